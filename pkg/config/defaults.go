@@ -24,6 +24,10 @@ import (
 	tjname "github.com/crossplane/terrajet/pkg/types/name"
 )
 
+// DataSourceKindPrefix is prepended to the kinds of the data sources, e.g.
+// the kind of "aws_ami" data source is "ObservedAMI".
+const DataSourceKindPrefix = "Observed"
+
 // Commonly used resource configurations.
 var (
 	// NameAsIdentifier uses "name" field in the arguments as the identifier of
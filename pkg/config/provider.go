@@ -8,6 +8,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
 
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
 	conversiontfjson "github.com/crossplane/terrajet/pkg/types/conversion/tfjson"
 )
 
@@ -92,13 +93,46 @@ type Provider struct {
 	// Defaults to []string{".+"} which would include all resources.
 	IncludeList []string
 
+	// DataSourceIncludeList is a list of regex for the Terraform data sources
+	// to be generated as read-only resources. Data sources in SkipList are
+	// skipped as well. Defaults to an empty list, i.e. no data source is
+	// generated.
+	DataSourceIncludeList []string
+
+	// GenerateProviderConfig enables the generation of the spec of the
+	// ProviderConfig from the schema of the Terraform provider block, see
+	// ProviderConfig. A hand-written ProviderConfigSpec type has to be removed
+	// from the base API package once it's enabled.
+	GenerateProviderConfig bool
+
+	// ProviderConfig is the configuration of the Terraform provider block.
+	// If GenerateProviderConfig is true, the spec of the ProviderConfig is
+	// generated from its schema with secret references for its sensitive
+	// arguments. Populated by NewProviderWithSchema.
+	ProviderConfig *Resource
+
+	// ErrorPatterns are the patterns that classify the errors of the Cloud
+	// API of the provider, e.g. its throttling errors, in addition to the
+	// default patterns. The category of the error of a failed async operation
+	// is reported as the reason of its condition and determines when the
+	// operation is retried.
+	ErrorPatterns []tferrors.Pattern
+
 	// Resources is a map holding resource configurations where key is Terraform
 	// resource name.
 	Resources map[string]*Resource
 
+	// DataSources is a map holding data source configurations where key is
+	// Terraform data source name.
+	DataSources map[string]*Resource
+
 	// resourceConfigurators is a map holding resource configurators where key
 	// is Terraform resource name.
 	resourceConfigurators map[string]ResourceConfiguratorChain
+
+	// dataSourceConfigurators is a map holding data source configurators
+	// where key is Terraform data source name.
+	dataSourceConfigurators map[string]ResourceConfiguratorChain
 }
 
 // A ProviderOption configures a Provider.
@@ -132,6 +166,22 @@ func WithSkipList(l []string) ProviderOption {
 	}
 }
 
+// WithDataSourceIncludeList configures DataSourceIncludeList for this
+// Provider.
+func WithDataSourceIncludeList(l []string) ProviderOption {
+	return func(p *Provider) {
+		p.DataSourceIncludeList = l
+	}
+}
+
+// WithGenerateProviderConfig configures GenerateProviderConfig for this
+// Provider.
+func WithGenerateProviderConfig(b bool) ProviderOption {
+	return func(p *Provider) {
+		p.GenerateProviderConfig = b
+	}
+}
+
 // WithBasePackages configures BasePackages for this Provider.
 func WithBasePackages(b BasePackages) ProviderOption {
 	return func(p *Provider) {
@@ -139,6 +189,19 @@ func WithBasePackages(b BasePackages) ProviderOption {
 	}
 }
 
+// WithErrorPatterns configures ErrorPatterns for this Provider.
+func WithErrorPatterns(p ...tferrors.Pattern) ProviderOption {
+	return func(pr *Provider) {
+		pr.ErrorPatterns = p
+	}
+}
+
+// ErrorClassifier returns the Classifier that sorts the errors of the
+// operations with the ErrorPatterns of the provider before the default ones.
+func (p *Provider) ErrorClassifier() *tferrors.Classifier {
+	return tferrors.NewClassifier(p.ErrorPatterns...)
+}
+
 // WithDefaultResourceFn configures DefaultResourceFn for this Provider
 func WithDefaultResourceFn(f DefaultResourceFn) ProviderOption {
 	return func(p *Provider) {
@@ -157,12 +220,42 @@ func NewProviderWithSchema(schema []byte, prefix string, modulePath string, opts
 	if len(ps.Schemas) != 1 {
 		panic(fmt.Sprintf("there should exactly be 1 provider schema but there are %d", len(ps.Schemas)))
 	}
-	var rs map[string]*tfjson.Schema
+	var rs, ds map[string]*tfjson.Schema
+	var cs *tfjson.Schema
 	for _, v := range ps.Schemas {
 		rs = v.ResourceSchemas
+		ds = v.DataSourceSchemas
+		cs = v.ConfigSchema
 		break
 	}
-	return NewProvider(conversiontfjson.GetV2ResourceMap(rs), prefix, modulePath, opts...)
+	p := NewProvider(conversiontfjson.GetV2ResourceMap(rs), prefix, modulePath, opts...)
+	for name, r := range p.Resources {
+		r.SingleNestedAttributes = conversiontfjson.GetSingleNestedAttributes(rs[name])
+	}
+	p.addDataSources(conversiontfjson.GetV2ResourceMap(ds))
+	for name, r := range p.DataSources {
+		r.SingleNestedAttributes = conversiontfjson.GetSingleNestedAttributes(ds[name])
+	}
+	if cs != nil {
+		p.ProviderConfig = newProviderConfig(prefix, conversiontfjson.GetV2ResourceMap(map[string]*tfjson.Schema{prefix: cs})[prefix])
+		p.ProviderConfig.SingleNestedAttributes = conversiontfjson.GetSingleNestedAttributes(cs)
+	}
+	return p
+}
+
+// newProviderConfig returns the configuration of the Terraform provider
+// block with the given name and schema. Its kind is used as the prefix of
+// the names of the generated types.
+func newProviderConfig(name string, terraformSchema *schema.Resource) *Resource {
+	return &Resource{
+		Name:              name,
+		TerraformResource: terraformSchema,
+		Kind:              "ProviderConfig",
+		Version:           "v1alpha1",
+		ExternalName:      IdentifierFromProvider,
+		References:        map[string]Reference{},
+		Sensitive:         NopSensitive,
+	}
 }
 
 // NewProvider builds and returns a new Provider.
@@ -180,8 +273,10 @@ func NewProvider(resourceMap map[string]*schema.Resource, prefix string, moduleP
 			// Include all Resources
 			".+",
 		},
-		Resources:             map[string]*Resource{},
-		resourceConfigurators: map[string]ResourceConfiguratorChain{},
+		Resources:               map[string]*Resource{},
+		DataSources:             map[string]*Resource{},
+		resourceConfigurators:   map[string]ResourceConfiguratorChain{},
+		dataSourceConfigurators: map[string]ResourceConfiguratorChain{},
 	}
 
 	for _, o := range opts {
@@ -208,6 +303,33 @@ func NewProvider(resourceMap map[string]*schema.Resource, prefix string, moduleP
 	return p
 }
 
+// addDataSources adds the configurations of the given data sources that are
+// in DataSourceIncludeList and not in SkipList. A data source is configured
+// the same way as a resource except that its kind is prefixed with
+// DataSourceKindPrefix so that it doesn't collide with the resource of the
+// same name, and its identifier is never set from the external name.
+func (p *Provider) addDataSources(dataSourceMap map[string]*schema.Resource) {
+	for name, terraformResource := range dataSourceMap {
+		if len(terraformResource.Schema) == 0 {
+			fmt.Printf("Skipping data source %s because it has no schema\n", name)
+			continue
+		}
+		if matches(name, p.SkipList) {
+			fmt.Printf("Skipping data source %s because it is in SkipList\n", name)
+			continue
+		}
+		if !matches(name, p.DataSourceIncludeList) {
+			continue
+		}
+		r := p.DefaultResourceFn(name, terraformResource)
+		r.Kind = DataSourceKindPrefix + r.Kind
+		r.DataSource = true
+		r.ExternalName = IdentifierFromProvider
+		r.ManagementPolicy = ManagementPolicyObserveOnly
+		p.DataSources[name] = r
+	}
+}
+
 // AddResourceConfigurator adds resource specific configurators.
 func (p *Provider) AddResourceConfigurator(resource string, c ResourceConfiguratorFn) { //nolint:interfacer
 	// Note(turkenh): nolint reasoning - easier to provide a function without
@@ -223,7 +345,13 @@ func (p *Provider) SetResourceConfigurator(resource string, c ResourceConfigurat
 	p.resourceConfigurators[resource] = ResourceConfiguratorChain{c}
 }
 
-// ConfigureResources configures resources with provided ResourceConfigurator's
+// AddDataSourceConfigurator adds data source specific configurators.
+func (p *Provider) AddDataSourceConfigurator(dataSource string, c ResourceConfiguratorFn) { //nolint:interfacer
+	p.dataSourceConfigurators[dataSource] = append(p.dataSourceConfigurators[dataSource], c)
+}
+
+// ConfigureResources configures resources and data sources with provided
+// ResourceConfigurator's
 func (p *Provider) ConfigureResources() {
 	for name, c := range p.resourceConfigurators {
 		// if not skipped & included & configured via the default configurator
@@ -231,6 +359,11 @@ func (p *Provider) ConfigureResources() {
 			c.Configure(r)
 		}
 	}
+	for name, c := range p.dataSourceConfigurators {
+		if r, ok := p.DataSources[name]; ok {
+			c.Configure(r)
+		}
+	}
 }
 
 func matches(name string, regexList []string) bool {
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const testSchema = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/hashicorp/test": {
+      "provider": {
+        "version": 0,
+        "block": {
+          "attributes": {
+            "region": {"type": "string", "optional": true},
+            "token": {"type": "string", "optional": true, "sensitive": true}
+          }
+        }
+      },
+      "resource_schemas": {
+        "test_thing": {
+          "version": 0,
+          "block": {
+            "attributes": {
+              "id": {"type": "string", "optional": true, "computed": true},
+              "name": {"type": "string", "required": true}
+            }
+          }
+        }
+      },
+      "data_source_schemas": {
+        "test_thing": {
+          "version": 0,
+          "block": {
+            "attributes": {
+              "id": {"type": "string", "optional": true, "computed": true},
+              "name": {"type": "string", "required": true},
+              "size": {"type": "number", "computed": true}
+            }
+          }
+        },
+        "test_other": {
+          "version": 0,
+          "block": {
+            "attributes": {
+              "id": {"type": "string", "optional": true, "computed": true}
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestNewProviderWithSchema(t *testing.T) {
+	type want struct {
+		resources       map[string]string
+		dataSources     map[string]string
+		providerConfigs []string
+	}
+	cases := map[string]struct {
+		reason string
+		opts   []ProviderOption
+		want
+	}{
+		"NoDataSources": {
+			reason: "No data source should be configured by default",
+			want: want{
+				resources:       map[string]string{"test_thing": "Thing"},
+				dataSources:     map[string]string{},
+				providerConfigs: []string{"region", "token"},
+			},
+		},
+		"IncludedDataSources": {
+			reason: "The included data sources should be configured with prefixed kinds",
+			opts:   []ProviderOption{WithDataSourceIncludeList([]string{"test_thing$"})},
+			want: want{
+				resources:       map[string]string{"test_thing": "Thing"},
+				dataSources:     map[string]string{"test_thing": "ObservedThing"},
+				providerConfigs: []string{"region", "token"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewProviderWithSchema([]byte(testSchema), "test", "github.com/crossplane-contrib/provider-jet-test", tc.opts...)
+			kinds := func(m map[string]*Resource) map[string]string {
+				r := map[string]string{}
+				for n, c := range m {
+					r[n] = c.Kind
+				}
+				return r
+			}
+			if diff := cmp.Diff(tc.want.resources, kinds(p.Resources)); diff != "" {
+				t.Errorf("\n%s\nNewProviderWithSchema(...): -want resources, +got resources:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.dataSources, kinds(p.DataSources)); diff != "" {
+				t.Errorf("\n%s\nNewProviderWithSchema(...): -want data sources, +got data sources:\n%s", tc.reason, diff)
+			}
+			for n, r := range p.DataSources {
+				if !r.DataSource {
+					t.Errorf("\n%s\nNewProviderWithSchema(...): data source %s is not marked as a data source", tc.reason, n)
+				}
+			}
+			var args []string
+			for k := range p.ProviderConfig.TerraformResource.Schema {
+				args = append(args, k)
+			}
+			if diff := cmp.Diff(tc.want.providerConfigs, args, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("\n%s\nNewProviderWithSchema(...): -want provider arguments, +got provider arguments:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package config
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -203,6 +204,86 @@ type OperationTimeouts struct {
 	Delete time.Duration
 }
 
+// ReplacementPolicy controls what happens when a change in the desired
+// configuration requires the external resource to be destroyed and created
+// again, i.e. a ForceNew field of the Terraform resource is changed.
+type ReplacementPolicy string
+
+const (
+	// ReplacementPolicyDeny blocks the update and reports it in the status
+	// of the resource. This is the default.
+	ReplacementPolicyDeny ReplacementPolicy = "Deny"
+	// ReplacementPolicyAllow lets the update replace the resource.
+	ReplacementPolicyAllow ReplacementPolicy = "Allow"
+	// ReplacementPolicyRequireApproval lets the update replace the resource
+	// only if the replacement is approved with an annotation on the
+	// resource.
+	ReplacementPolicyRequireApproval ReplacementPolicy = "RequireApproval"
+)
+
+// ManagementPolicy controls which operations the controller is allowed to run
+// on the external resource.
+type ManagementPolicy string
+
+const (
+	// ManagementPolicyFull lets the controller create, update and delete the
+	// external resource. This is the default.
+	ManagementPolicyFull ManagementPolicy = "Full"
+	// ManagementPolicyObserveOnly lets the controller only refresh the
+	// external resource identified by the external name and report its
+	// attributes in the status. The external resource is never created,
+	// updated or deleted.
+	ManagementPolicyObserveOnly ManagementPolicy = "ObserveOnly"
+	// ManagementPolicyImport is ManagementPolicyObserveOnly where the
+	// observed values are also written to the unset fields of the spec, so
+	// that the resource can be adopted by switching to ManagementPolicyFull
+	// without any change to the external resource.
+	ManagementPolicyImport ManagementPolicy = "Import"
+)
+
+// PreviousVersion is an API version that a resource had before it was
+// promoted to its current version. The objects of a previous version are
+// still served and they're converted from and to the current version, which
+// is the hub and the storage version of the resource.
+type PreviousVersion struct {
+	// Name of the version, e.g. v1alpha1.
+	Name string
+
+	// FieldMoves are the fields whose paths in this version are different
+	// from the ones in the current version. They're applied in order while
+	// converting to the current version and in reverse order while
+	// converting from it.
+	FieldMoves []FieldMove
+}
+
+// FieldMove is a field whose path in a previous version is different from
+// the one in the current version. The paths are Terraform field paths
+// concatenated with dots, e.g. "rule.port" is the "port" of every item in
+// the "rule" block. Both paths need to be in the same number of blocks and
+// lists.
+type FieldMove struct {
+	// From is the path of the field in the previous version.
+	From string
+	// To is the path of the field in the current version.
+	To string
+}
+
+// RenameField returns a FieldMove of a field at the given path that's renamed
+// to the given name in the current version.
+func RenameField(path, name string) FieldMove {
+	to := name
+	if i := strings.LastIndex(path, "."); i != -1 {
+		to = path[:i+1] + name
+	}
+	return FieldMove{From: path, To: to}
+}
+
+// MoveField returns a FieldMove of a field that's moved from the given path to
+// another one in the current version.
+func MoveField(from, to string) FieldMove {
+	return FieldMove{From: from, To: to}
+}
+
 // NewInitializerFn returns the Initializer with a client.
 type NewInitializerFn func(client client.Client) managed.Initializer
 
@@ -288,9 +369,31 @@ type Resource struct {
 
 	InitializerFns []NewInitializerFn
 
-	// OperationTimeouts allows configuring resource operation timeouts.
+	// OperationTimeouts allows configuring resource operation timeouts. They
+	// also determine the deadlines of the async operations of the resource.
 	OperationTimeouts OperationTimeouts
 
+	// ReplacementPolicy controls whether an update is allowed to destroy
+	// and re-create the resource. Defaults to ReplacementPolicyDeny.
+	ReplacementPolicy ReplacementPolicy
+
+	// ManagementPolicy controls which operations the controller is allowed
+	// to run on the external resource. It can be overridden per resource
+	// with an annotation. Defaults to ManagementPolicyFull.
+	ManagementPolicy ManagementPolicy
+
+	// PreviousVersions are the API versions that the resource had before it
+	// was promoted to Version. Their types are generated along with
+	// conversion functions that are served by a conversion webhook, so that
+	// the manifests of the previous versions keep working.
+	PreviousVersions []PreviousVersion
+
+	// DataSource is true if this is the configuration of a Terraform data
+	// source. The data sources are only read on every poll with their
+	// forProvider arguments, the controller never creates, updates or deletes
+	// anything, regardless of the ManagementPolicy.
+	DataSource bool
+
 	// ExternalName allows you to specify a custom ExternalName.
 	ExternalName ExternalName
 
@@ -302,4 +405,12 @@ type Resource struct {
 
 	// LateInitializer configuration to control late-initialization behaviour
 	LateInitializer LateInitializer
+
+	// SingleNestedAttributes are the dot-separated paths of the attributes
+	// whose values are objects in Terraform but lists with at most one item
+	// in the CRD, i.e. the nested attributes and blocks with single nesting
+	// mode of protocol version 6 providers. They're converted while the
+	// Terraform workspace files are written and read. Populated by
+	// NewProviderWithSchema.
+	SingleNestedAttributes []string
 }
@@ -109,3 +109,40 @@ func TestSetExternalTagsWithPaved(t *testing.T) {
 		})
 	}
 }
+
+func TestRenameField(t *testing.T) {
+	type args struct {
+		path string
+		name string
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want FieldMove
+	}{
+		"TopLevel": {
+			reason: "A top-level field should be renamed at the top level",
+			args: args{
+				path: "cidr",
+				name: "cidr_block",
+			},
+			want: FieldMove{From: "cidr", To: "cidr_block"},
+		},
+		"Nested": {
+			reason: "A nested field should be renamed under the same parent",
+			args: args{
+				path: "rule.cidr",
+				name: "cidr_block",
+			},
+			want: FieldMove{From: "rule.cidr", To: "rule.cidr_block"},
+		},
+	}
+	for n, tc := range cases {
+		t.Run(n, func(t *testing.T) {
+			got := RenameField(tc.args.path, tc.args.name)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nRenameField(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
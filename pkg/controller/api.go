@@ -29,7 +29,8 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/crossplane/terrajet/pkg/resource"
-	"github.com/crossplane/terrajet/pkg/terraform"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+	"github.com/crossplane/terrajet/pkg/workspace"
 )
 
 const (
@@ -59,46 +60,72 @@ func (a *APISecretClient) GetSecretValue(ctx context.Context, sel xpv1.SecretKey
 	return d[sel.Key], err
 }
 
+// APICallbacksOption lets you configure APICallbacks.
+type APICallbacksOption func(*APICallbacks)
+
+// WithErrorClassifier configures the Classifier that assigns the categories
+// of the errors of the failed operations, which are reported as the reasons
+// of their conditions. The default patterns are used if it's not configured.
+func WithErrorClassifier(c *tferrors.Classifier) APICallbacksOption {
+	return func(ac *APICallbacks) {
+		ac.classifier = c
+	}
+}
+
 // NewAPICallbacks returns a new APICallbacks.
-func NewAPICallbacks(m ctrl.Manager, of xpresource.ManagedKind) *APICallbacks {
+func NewAPICallbacks(m ctrl.Manager, of xpresource.ManagedKind, opts ...APICallbacksOption) *APICallbacks {
 	nt := func() resource.Terraformed {
 		return xpresource.MustCreateObject(schema.GroupVersionKind(of), m.GetScheme()).(resource.Terraformed)
 	}
-	return &APICallbacks{
+	ac := &APICallbacks{
 		kube:           m.GetClient(),
 		newTerraformed: nt,
 	}
+	for _, f := range opts {
+		f(ac)
+	}
+	return ac
 }
 
 // APICallbacks providers callbacks that work on API resources.
 type APICallbacks struct {
 	kube           client.Client
 	newTerraformed func() resource.Terraformed
+	classifier     *tferrors.Classifier
+}
+
+// classify annotates the given error with its category if a Classifier is
+// configured.
+func (ac *APICallbacks) classify(err error) error {
+	if err == nil || ac.classifier == nil {
+		return err
+	}
+	return tferrors.WithCategory(err, ac.classifier.Classify(err))
 }
 
 // Apply makes sure the error is saved in async operation condition.
-func (ac *APICallbacks) Apply(name string) terraform.CallbackFn {
+func (ac *APICallbacks) Apply(name string) workspace.CallbackFn {
 	return func(err error, ctx context.Context) error {
 		nn := types.NamespacedName{Name: name}
 		tr := ac.newTerraformed()
 		if kErr := ac.kube.Get(ctx, nn, tr); kErr != nil {
 			return errors.Wrap(kErr, errGet)
 		}
-		tr.SetConditions(resource.LastAsyncOperationCondition(err))
+		tr.SetConditions(resource.LastAsyncOperationCondition(ac.classify(err)))
 		tr.SetConditions(resource.AsyncOperationFinishedCondition())
 		return errors.Wrap(ac.kube.Status().Update(ctx, tr), errStatusUpdate)
 	}
 }
 
 // Destroy makes sure the error is saved in async operation condition.
-func (ac *APICallbacks) Destroy(name string) terraform.CallbackFn {
+func (ac *APICallbacks) Destroy(name string) workspace.CallbackFn {
 	return func(err error, ctx context.Context) error {
 		nn := types.NamespacedName{Name: name}
 		tr := ac.newTerraformed()
 		if kErr := ac.kube.Get(ctx, nn, tr); kErr != nil {
 			return errors.Wrap(kErr, errGet)
 		}
-		tr.SetConditions(resource.LastAsyncOperationCondition(err))
+		tr.SetConditions(resource.LastAsyncOperationCondition(ac.classify(err)))
 		tr.SetConditions(resource.AsyncOperationFinishedCondition())
 		return errors.Wrap(ac.kube.Status().Update(ctx, tr), errStatusUpdate)
 	}
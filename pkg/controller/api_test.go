@@ -25,6 +25,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrl "sigs.k8s.io/controller-runtime/pkg/manager"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
@@ -36,9 +37,10 @@ import (
 
 func TestAPICallbacks_Apply(t *testing.T) {
 	type args struct {
-		mgr ctrl.Manager
-		mg  xpresource.ManagedKind
-		err error
+		mgr  ctrl.Manager
+		mg   xpresource.ManagedKind
+		err  error
+		opts []APICallbacksOption
 	}
 	type want struct {
 		err error
@@ -68,6 +70,27 @@ func TestAPICallbacks_Apply(t *testing.T) {
 				err: tjerrors.NewApplyFailed(nil),
 			},
 		},
+		"ApplyOperationClassified": {
+			reason: "It should report the category of the error assigned by the provider patterns as the reason of the condition",
+			args: args{
+				mg: xpresource.ManagedKind(xpfake.GVK(&fake.Terraformed{})),
+				mgr: &xpfake.Manager{
+					Client: &test.MockClient{
+						MockGet: test.NewMockGetFn(nil),
+						MockStatusUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+							got := obj.(resource.Terraformed).GetCondition(resource.TypeLastAsyncOperation)
+							if diff := cmp.Diff(xpv1.ConditionReason(tjerrors.CategoryThrottled), got.Reason); diff != "" {
+								t.Errorf("\nApply(...): -want reason, +got reason:\n%s", diff)
+							}
+							return nil
+						},
+					},
+					Scheme: xpfake.SchemeWith(&fake.Terraformed{}),
+				},
+				err:  tjerrors.NewApplyFailed([]byte(`{"@level":"error","@message":"Error: CoolAPIBusy","diagnostic":{"severity":"error","summary":"CoolAPIBusy","detail":"try again later"},"type":"diagnostic"}`)),
+				opts: []APICallbacksOption{WithErrorClassifier(tjerrors.NewClassifier(tjerrors.NewPattern(tjerrors.CategoryThrottled, "CoolAPIBusy")))},
+			},
+		},
 		"ApplyOperationSucceeded": {
 			reason: "It should update the condition with success if the apply operation does not report error",
 			args: args{
@@ -107,7 +130,7 @@ func TestAPICallbacks_Apply(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := NewAPICallbacks(tc.args.mgr, tc.args.mg)
+			e := NewAPICallbacks(tc.args.mgr, tc.args.mg, tc.args.opts...)
 			err := e.Apply("name")(tc.args.err, context.TODO())
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nApply(...): -want error, +got error:\n%s", tc.reason, diff)
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+	"github.com/crossplane/terrajet/pkg/workspace"
+)
+
+// The types of the async operations.
+const (
+	opApply   = "apply"
+	opDestroy = "destroy"
+)
+
+// asyncFailure is the last failure of an async operation of a resource.
+type asyncFailure struct {
+	// op is the type of the failed operation, i.e. apply or destroy.
+	op string
+	// generation is the generation of the resource when the operation
+	// started.
+	generation int64
+	// time is when the operation failed.
+	time time.Time
+}
+
+// asyncFailures records the last failures of the async operations of the
+// resources of a controller. The time of a failure cannot be read from the
+// LastAsyncOperation condition since its LastTransitionTime is not updated
+// when the same failure is set again. A nil asyncFailures records nothing.
+type asyncFailures struct {
+	mu       sync.Mutex
+	failures map[types.UID]asyncFailure
+}
+
+func newAsyncFailures() *asyncFailures {
+	return &asyncFailures{failures: map[types.UID]asyncFailure{}}
+}
+
+// track returns a CallbackFn that records the result of the given async
+// operation of the given resource before calling the given callback.
+func (f *asyncFailures) track(mg xpresource.Managed, op string, callback workspace.CallbackFn) workspace.CallbackFn {
+	if f == nil {
+		return callback
+	}
+	uid, gen := mg.GetUID(), mg.GetGeneration()
+	return func(err error, ctx context.Context) error {
+		f.mu.Lock()
+		if err != nil {
+			f.failures[uid] = asyncFailure{op: op, generation: gen, time: time.Now()}
+		} else {
+			delete(f.failures, uid)
+		}
+		f.mu.Unlock()
+		return callback(err, ctx)
+	}
+}
+
+// get returns the last failure of the async operations of the resource with
+// the given UID, if any.
+func (f *asyncFailures) get(uid types.UID) (asyncFailure, bool) {
+	if f == nil {
+		return asyncFailure{}, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	af, ok := f.failures[uid]
+	return af, ok
+}
+
+// checkBackoff returns an error if the last async operation of the given type
+// failed recently with an error whose category calls for waiting before the
+// operation is retried, e.g. because the Cloud API throttled it. The category
+// is read from the reason of the LastAsyncOperation condition. The backoff is
+// reset once the spec of the resource changes, i.e. its generation, so that a
+// fixed configuration is applied right away. The managed reconciler requeues
+// the resource with a backoff on the error.
+//
+// The backoff applies only to the async resources. The errors of the sync
+// operations are returned to the managed reconciler as they are, which
+// requeues the resource with its own exponential backoff.
+func checkBackoff(mg xpresource.Managed, failures *asyncFailures, op string) error {
+	af, ok := failures.get(mg.GetUID())
+	if !ok || af.op != op || af.generation != mg.GetGeneration() {
+		return nil
+	}
+	c := mg.GetCondition(resource.TypeLastAsyncOperation)
+	if c.Status != corev1.ConditionFalse {
+		return nil
+	}
+	retry := af.time.Add(tferrors.Category(c.Reason).Backoff())
+	if d := time.Until(retry); d > 0 {
+		return errors.Errorf(errFmtBackoff, c.Reason, d.Round(time.Second))
+	}
+	return nil
+}
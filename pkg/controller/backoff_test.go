@@ -0,0 +1,174 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/fake"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+const backoffUID = "some-uid"
+
+func failedResource(r xpv1.ConditionReason, generation int64) xpresource.Managed {
+	return &fake.Terraformed{Managed: xpfake.Managed{
+		ObjectMeta: metav1.ObjectMeta{UID: backoffUID, Generation: generation},
+		ConditionedStatus: xpv1.ConditionedStatus{Conditions: []xpv1.Condition{{
+			Type:   resource.TypeLastAsyncOperation,
+			Status: corev1.ConditionFalse,
+			// The transition time of a repeated failure is not updated.
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+			Reason:             r,
+		}}},
+	}}
+}
+
+func TestCheckBackoff(t *testing.T) {
+	type args struct {
+		mg      xpresource.Managed
+		failure *asyncFailure
+		op      string
+	}
+	type want struct {
+		backoff bool
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NoFailure": {
+			reason: "The operation should not wait if the last one didn't fail.",
+			args: args{
+				mg: &fake.Terraformed{},
+				op: opApply,
+			},
+		},
+		"Throttled": {
+			reason: "The operation should wait if the last one was throttled recently.",
+			args: args{
+				mg:      failedResource(xpv1.ConditionReason(tferrors.CategoryThrottled), 1),
+				failure: &asyncFailure{op: opApply, generation: 1, time: time.Now()},
+				op:      opApply,
+			},
+			want: want{
+				backoff: true,
+			},
+		},
+		"ThrottledLongAgo": {
+			reason: "The operation should not wait if the backoff of the last failure elapsed.",
+			args: args{
+				mg:      failedResource(xpv1.ConditionReason(tferrors.CategoryThrottled), 1),
+				failure: &asyncFailure{op: opApply, generation: 1, time: time.Now().Add(-time.Hour)},
+				op:      opApply,
+			},
+		},
+		"SpecChanged": {
+			reason: "The operation should not wait if the spec of the resource changed since the last failure.",
+			args: args{
+				mg:      failedResource(xpv1.ConditionReason(tferrors.CategoryValidation), 2),
+				failure: &asyncFailure{op: opApply, generation: 1, time: time.Now()},
+				op:      opApply,
+			},
+		},
+		"OtherOperation": {
+			reason: "A destroy operation should not wait for the backoff of a failed apply operation.",
+			args: args{
+				mg:      failedResource(xpv1.ConditionReason(tferrors.CategoryValidation), 1),
+				failure: &asyncFailure{op: opApply, generation: 1, time: time.Now()},
+				op:      opDestroy,
+			},
+		},
+		"UnknownFailure": {
+			reason: "The operation should be retried right away if the category of the last failure is unknown.",
+			args: args{
+				mg:      failedResource(resource.ReasonApplyFailure, 1),
+				failure: &asyncFailure{op: opApply, generation: 1, time: time.Now()},
+				op:      opApply,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := newAsyncFailures()
+			if tc.args.failure != nil {
+				f.failures[backoffUID] = *tc.args.failure
+			}
+			err := checkBackoff(tc.args.mg, f, tc.args.op)
+			if diff := cmp.Diff(tc.want.backoff, err != nil); diff != "" {
+				t.Errorf("\n%s\ncheckBackoff(...): -want backoff, +got backoff:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAsyncFailuresTrack(t *testing.T) {
+	type want struct {
+		backoff bool
+	}
+	cases := map[string]struct {
+		reason  string
+		results []error
+		want
+	}{
+		"RepeatedFailure": {
+			reason:  "A repeated failure should be backed off from the time it was recorded.",
+			results: []error{errBoom, errBoom},
+			want: want{
+				backoff: true,
+			},
+		},
+		"Success": {
+			reason:  "A successful operation should reset the backoff.",
+			results: []error{errBoom, nil},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			f := newAsyncFailures()
+			mg := failedResource(xpv1.ConditionReason(tferrors.CategoryThrottled), 1)
+			for _, r := range tc.results {
+				called := false
+				cb := f.track(mg, opApply, func(err error, _ context.Context) error {
+					called = true
+					return nil
+				})
+				if err := cb(r, context.TODO()); err != nil {
+					t.Fatalf("\n%s\ntrack(...): unexpected error: %v", tc.reason, err)
+				}
+				if !called {
+					t.Errorf("\n%s\ntrack(...): callback was not called", tc.reason)
+				}
+			}
+			err := checkBackoff(mg, f, opApply)
+			if diff := cmp.Diff(tc.want.backoff, err != nil); diff != "" {
+				t.Errorf("\n%s\ncheckBackoff(...): -want backoff, +got backoff:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
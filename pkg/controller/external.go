@@ -20,28 +20,45 @@ import (
 	"context"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crossplane/terrajet/pkg/config"
 	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/resource/json"
 	"github.com/crossplane/terrajet/pkg/terraform"
+	"github.com/crossplane/terrajet/pkg/tracing"
 )
 
 const (
 	errUnexpectedObject  = "the custom resource is not a Terraformed resource"
 	errGetTerraformSetup = "cannot get terraform setup"
 	errGetWorkspace      = "cannot get a terraform workspace for resource"
-	errRefresh           = "cannot run refresh"
-	errPlan              = "cannot run plan"
+	errObserve           = "cannot observe the external resource"
 	errStartAsyncApply   = "cannot start async apply"
 	errStartAsyncDestroy = "cannot start async destroy"
 	errApply             = "cannot apply"
 	errDestroy           = "cannot destroy"
 	errStatusUpdate      = "cannot update status of custom resource"
+
+	errFmtObservedNotFound = "external resource does not exist and it's not created since the management policy of the resource is %s"
+	errFmtNotManaged       = "cannot %s the external resource since the management policy of the resource is %s"
+	errFmtBackoff          = "the last async operation failed with a %s error, it will be retried in %s"
+
+	errReplacementDenied      = "update requires the resource to be replaced which is denied by its replacement policy"
+	errReplacementNotApproved = "update requires the resource to be replaced which has to be approved by setting the annotation " + resource.AnnotationKeyApproveReplacement + " to \"true\""
+
+	reasonDrifted     event.Reason = "Drifted"
+	reasonInterrupted event.Reason = "AsyncOperationInterrupted"
+
+	reasonInvalidAsyncTimeout event.Reason = "InvalidAsyncTimeout"
 )
 
 // Option allows you to configure Connector.
@@ -56,6 +73,25 @@ func WithCallbackProvider(ac CallbackProvider) Option {
 	}
 }
 
+// WithEventRecorder configures the event recorder that the controller uses
+// to record events about the managed resources, such as the attributes that
+// drifted from the desired configuration.
+func WithEventRecorder(r event.Recorder) Option {
+	return func(c *Connector) {
+		c.eventRecorder = r
+	}
+}
+
+// WithTracing configures the TracerProvider that records the spans of the
+// connections and the external operations of the resources, which are tagged
+// with the given GroupVersionKind since the typed objects don't carry it.
+func WithTracing(tp trace.TracerProvider, gvk schema.GroupVersionKind) Option {
+	return func(c *Connector) {
+		c.tracer = tracing.Tracer(tp)
+		c.gvk = gvk
+	}
+}
+
 // NewConnector returns a new Connector object.
 func NewConnector(kube client.Client, ws Store, sf terraform.SetupFn, cfg *config.Resource, opts ...Option) *Connector {
 	c := &Connector{
@@ -63,6 +99,9 @@ func NewConnector(kube client.Client, ws Store, sf terraform.SetupFn, cfg *confi
 		getTerraformSetup: sf,
 		store:             ws,
 		config:            cfg,
+		eventRecorder:     event.NewNopRecorder(),
+		tracer:            tracing.Tracer(nil),
+		failures:          newAsyncFailures(),
 	}
 	for _, f := range opts {
 		f(c)
@@ -78,17 +117,33 @@ type Connector struct {
 	getTerraformSetup terraform.SetupFn
 	config            *config.Resource
 	callback          CallbackProvider
+	eventRecorder     event.Recorder
+	tracer            trace.Tracer
+	gvk               schema.GroupVersionKind
+	failures          *asyncFailures
 }
 
 // Connect makes sure the underlying client is ready to issue requests to the
 // provider API.
 func (c *Connector) Connect(ctx context.Context, mg xpresource.Managed) (managed.ExternalClient, error) {
+	// The attributes are carried by the context so that the workspace store
+	// can tag the spans of the workspace with them.
+	attrs := tracing.ResourceAttributes(c.gvk, mg.GetName())
+	ctx, span := c.tracer.Start(tracing.WithResourceAttributes(ctx, attrs), "Connect", trace.WithAttributes(attrs...))
+	ec, err := c.connect(ctx, mg, attrs)
+	tracing.End(span, err)
+	return ec, err
+}
+
+func (c *Connector) connect(ctx context.Context, mg xpresource.Managed, attrs []attribute.KeyValue) (managed.ExternalClient, error) {
 	tr, ok := mg.(resource.Terraformed)
 	if !ok {
 		return nil, errors.New(errUnexpectedObject)
 	}
 
-	ts, err := c.getTerraformSetup(ctx, c.kube, mg)
+	sctx, span := c.tracer.Start(ctx, "GetTerraformSetup")
+	ts, err := c.getTerraformSetup(sctx, c.kube, mg)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetTerraformSetup)
 	}
@@ -97,21 +152,55 @@ func (c *Connector) Connect(ctx context.Context, mg xpresource.Managed) (managed
 	if err != nil {
 		return nil, errors.Wrap(err, errGetWorkspace)
 	}
+	// The workspace falls back to the configured timeouts if the timeout
+	// annotation is invalid, which is reported here.
+	if _, err := terraform.NewAsyncTimeouts(tr, c.config.OperationTimeouts); err != nil {
+		c.eventRecorder.Event(mg, event.Warning(reasonInvalidAsyncTimeout, err))
+	}
 
 	return &external{
-		workspace: tf,
-		config:    c.config,
-		callback:  c.callback,
+		workspace:      tf,
+		config:         c.config,
+		callback:       c.callback,
+		failures:       c.failures,
+		eventRecorder:  c.eventRecorder,
+		tracer:         c.tracer,
+		spanAttributes: attrs,
 	}, nil
 }
 
 type external struct {
-	workspace Workspace
-	config    *config.Resource
-	callback  CallbackProvider
+	workspace     Workspace
+	config        *config.Resource
+	callback      CallbackProvider
+	failures      *asyncFailures
+	eventRecorder event.Recorder
+
+	tracer         trace.Tracer
+	spanAttributes []attribute.KeyValue
+
+	// requiresReplace is set by Observe if the plan shows that the resource
+	// has to be replaced to be up-to-date.
+	requiresReplace bool
+}
+
+// startSpan starts the span of the external operation with the given name.
+func (e *external) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	t := e.tracer
+	if t == nil {
+		t = tracing.Tracer(nil)
+	}
+	return t.Start(ctx, name, trace.WithAttributes(e.spanAttributes...))
+}
+
+func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.ExternalObservation, error) {
+	ctx, span := e.startSpan(ctx, "Observe")
+	o, err := e.observe(ctx, mg)
+	tracing.End(span, err)
+	return o, err
 }
 
-func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.ExternalObservation, error) { //nolint:gocyclo
+func (e *external) observe(ctx context.Context, mg xpresource.Managed) (managed.ExternalObservation, error) { //nolint:gocyclo
 	// We skip the gocyclo check because most of the operations are straight-forward
 	// and serial.
 	// TODO(muvaf): Look for ways to reduce the cyclomatic complexity without
@@ -120,17 +209,37 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 	if !ok {
 		return managed.ExternalObservation{}, errors.New(errUnexpectedObject)
 	}
-	res, err := e.workspace.Refresh(ctx)
+	policy := e.managementPolicy(tr)
+	// The external resources that are only observed are left untouched when
+	// their managed resources are deleted.
+	if policy != config.ManagementPolicyFull && meta.WasDeleted(tr) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	// A single Terraform invocation both refreshes the state and computes the
+	// plan that tells whether the external resource is up-to-date.
+	res, err := e.workspace.Observe(ctx)
 	if err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, errRefresh)
+		return managed.ExternalObservation{}, errors.Wrap(err, errObserve)
+	}
+	// The last async operation was interrupted by a restart of the provider
+	// and will not be able to report its result.
+	if res.InterruptedOperation != nil {
+		mg.SetConditions(resource.LastAsyncOperationCondition(res.InterruptedOperation), resource.AsyncOperationFinishedCondition())
+		e.eventRecorder.Event(mg, event.Warning(reasonInterrupted, res.InterruptedOperation))
 	}
 	switch {
 	case res.IsApplying, res.IsDestroying:
-		mg.SetConditions(resource.AsyncOperationOngoingCondition())
+		c := resource.AsyncOperationOngoingCondition()
+		if res.IsWaiting {
+			c = resource.AsyncOperationWaitingCondition()
+		}
+		mg.SetConditions(c)
 		return managed.ExternalObservation{
 			ResourceExists:   true,
 			ResourceUpToDate: true,
 		}, nil
+	case !res.Exists && policy != config.ManagementPolicyFull:
+		return managed.ExternalObservation{}, errors.Errorf(errFmtObservedNotFound, policy)
 	case !res.Exists:
 		return managed.ExternalObservation{
 			ResourceExists: false,
@@ -162,6 +271,10 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 		return managed.ExternalObservation{}, errors.Wrap(err, "cannot get connection details")
 	}
 
+	if policy != config.ManagementPolicyFull {
+		return e.observeOnly(tr, policy, res.State.GetAttributes(), conn, annotationsUpdated)
+	}
+
 	lateInitedParams, err := tr.LateInitialize(res.State.GetAttributes())
 	if err != nil {
 		return managed.ExternalObservation{}, errors.Wrap(err, "cannot late initialize parameters")
@@ -197,7 +310,7 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 			ConnectionDetails: conn,
 		}, nil
 	// with the least priority wrt critical annotation updates and status updates
-	// we allow a late-initialization before the Workspace.Plan call
+	// we allow a late-initialization before the plan result is consumed
 	case lateInitedParams:
 		return managed.ExternalObservation{
 			ResourceExists:          true,
@@ -205,20 +318,93 @@ func (e *external) Observe(ctx context.Context, mg xpresource.Managed) (managed.
 			ConnectionDetails:       conn,
 			ResourceLateInitialized: true,
 		}, nil
-	// now we do a Workspace.Refresh
+	// now we consume the plan computed during the observation
 	default:
-		plan, err := e.workspace.Plan(ctx)
+		e.requiresReplace = res.RequiresReplace
+		diff := res.Diff.String()
+		// The event is recorded only when the resource drifts or its diff
+		// changes so that a resource that stays drifted doesn't flood the
+		// events.
+		c := resource.UpToDateCondition(res.UpToDate, diff)
+		// A blocked replacement is reported in place of the drift so that
+		// the condition doesn't flip between the two on every observation.
+		if r, msg := e.replacementBlocked(tr); msg != "" {
+			c = resource.ReplacementBlockedCondition(r, msg+": "+diff)
+		}
+		if diff != "" && !tr.GetCondition(resource.TypeUpToDate).Equal(c) {
+			e.eventRecorder.Event(tr, event.Normal(reasonDrifted, diff))
+		}
+		tr.SetConditions(c)
 		return managed.ExternalObservation{
 			ResourceExists:    true,
-			ResourceUpToDate:  plan.UpToDate,
+			ResourceUpToDate:  res.UpToDate,
 			ConnectionDetails: conn,
-		}, errors.Wrap(err, errPlan)
+			Diff:              diff,
+		}, nil
 	}
 }
 
+// observeOnly completes the observation of a resource whose external
+// resource is only observed. The observed values are written to the spec if
+// the resource is being imported. The plan is skipped since the resource is
+// never updated.
+func (e *external) observeOnly(tr resource.Terraformed, policy config.ManagementPolicy, attr []byte, conn managed.ConnectionDetails, annotationsUpdated bool) (managed.ExternalObservation, error) {
+	obs := managed.ExternalObservation{
+		ResourceExists:    true,
+		ResourceUpToDate:  true,
+		ConnectionDetails: conn,
+	}
+	// The same priorities as the managed resources apply, see Observe.
+	switch {
+	case annotationsUpdated:
+		obs.ResourceLateInitialized = true
+	case !tr.GetCondition(xpv1.TypeReady).Equal(xpv1.Available()):
+		tr.SetConditions(xpv1.Available())
+	case policy == config.ManagementPolicyImport:
+		lateInited, err := tr.LateInitialize(attr)
+		if err != nil {
+			return managed.ExternalObservation{}, errors.Wrap(err, "cannot late initialize parameters")
+		}
+		obs.ResourceLateInitialized = lateInited
+	}
+	return obs, nil
+}
+
+// managementPolicy returns the management policy of the given resource. The
+// data sources are always only observed.
+func (e *external) managementPolicy(mg xpresource.Managed) config.ManagementPolicy {
+	if e.config.DataSource {
+		return config.ManagementPolicyObserveOnly
+	}
+	return resource.GetManagementPolicy(mg, e.config.ManagementPolicy)
+}
+
+// checkManagementPolicy returns an error if the management policy of the
+// given resource doesn't allow the given operation. The controller doesn't
+// run these operations for such resources, so this is a safeguard.
+func (e *external) checkManagementPolicy(mg xpresource.Managed, op string) error {
+	if policy := e.managementPolicy(mg); policy != config.ManagementPolicyFull {
+		return errors.Errorf(errFmtNotManaged, op, policy)
+	}
+	return nil
+}
+
 func (e *external) Create(ctx context.Context, mg xpresource.Managed) (managed.ExternalCreation, error) {
+	ctx, span := e.startSpan(ctx, "Create")
+	c, err := e.create(ctx, mg)
+	tracing.End(span, err)
+	return c, err
+}
+
+func (e *external) create(ctx context.Context, mg xpresource.Managed) (managed.ExternalCreation, error) {
+	if err := e.checkManagementPolicy(mg, "create"); err != nil {
+		return managed.ExternalCreation{}, err
+	}
 	if e.config.UseAsync {
-		return managed.ExternalCreation{}, errors.Wrap(e.workspace.ApplyAsync(e.callback.Apply(mg.GetName())), errStartAsyncApply)
+		if err := checkBackoff(mg, e.failures, opApply); err != nil {
+			return managed.ExternalCreation{}, err
+		}
+		return managed.ExternalCreation{}, errors.Wrap(e.workspace.ApplyAsync(e.failures.track(mg, opApply, e.callback.Apply(mg.GetName()))), errStartAsyncApply)
 	}
 	tr, ok := mg.(resource.Terraformed)
 	if !ok {
@@ -244,8 +430,24 @@ func (e *external) Create(ctx context.Context, mg xpresource.Managed) (managed.E
 }
 
 func (e *external) Update(ctx context.Context, mg xpresource.Managed) (managed.ExternalUpdate, error) {
+	ctx, span := e.startSpan(ctx, "Update")
+	u, err := e.update(ctx, mg)
+	tracing.End(span, err)
+	return u, err
+}
+
+func (e *external) update(ctx context.Context, mg xpresource.Managed) (managed.ExternalUpdate, error) {
+	if err := e.checkManagementPolicy(mg, "update"); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	if err := e.checkReplacement(mg); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
 	if e.config.UseAsync {
-		return managed.ExternalUpdate{}, errors.Wrap(e.workspace.ApplyAsync(e.callback.Apply(mg.GetName())), errStartAsyncApply)
+		if err := checkBackoff(mg, e.failures, opApply); err != nil {
+			return managed.ExternalUpdate{}, err
+		}
+		return managed.ExternalUpdate{}, errors.Wrap(e.workspace.ApplyAsync(e.failures.track(mg, opApply, e.callback.Apply(mg.GetName()))), errStartAsyncApply)
 	}
 	tr, ok := mg.(resource.Terraformed)
 	if !ok {
@@ -262,9 +464,45 @@ func (e *external) Update(ctx context.Context, mg xpresource.Managed) (managed.E
 	return managed.ExternalUpdate{}, errors.Wrap(tr.SetObservation(attr), "cannot set observation")
 }
 
+// checkReplacement returns an error if the update requires the resource to be
+// replaced but its replacement policy does not allow that. The blocking
+// condition is set by Observe.
+func (e *external) checkReplacement(mg xpresource.Managed) error {
+	if _, msg := e.replacementBlocked(mg); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// replacementBlocked returns the reason and the message of the blocked
+// replacement if the planned update requires the resource to be replaced but
+// its replacement policy does not allow that. The message is empty otherwise.
+func (e *external) replacementBlocked(mg xpresource.Managed) (xpv1.ConditionReason, string) {
+	if !e.requiresReplace || resource.IsReplacementAllowed(mg, e.config.ReplacementPolicy) {
+		return "", ""
+	}
+	if e.config.ReplacementPolicy == config.ReplacementPolicyRequireApproval {
+		return resource.ReasonReplacementNotApproved, errReplacementNotApproved
+	}
+	return resource.ReasonReplacementDenied, errReplacementDenied
+}
+
 func (e *external) Delete(ctx context.Context, mg xpresource.Managed) error {
+	ctx, span := e.startSpan(ctx, "Delete")
+	err := e.delete(ctx, mg)
+	tracing.End(span, err)
+	return err
+}
+
+func (e *external) delete(ctx context.Context, mg xpresource.Managed) error {
+	if err := e.checkManagementPolicy(mg, "delete"); err != nil {
+		return err
+	}
 	if e.config.UseAsync {
-		return errors.Wrap(e.workspace.DestroyAsync(e.callback.Destroy(mg.GetName())), errStartAsyncDestroy)
+		if err := checkBackoff(mg, e.failures, opDestroy); err != nil {
+			return err
+		}
+		return errors.Wrap(e.workspace.DestroyAsync(e.failures.track(mg, opDestroy, e.callback.Destroy(mg.GetName()))), errStartAsyncDestroy)
 	}
 	return errors.Wrap(e.workspace.Destroy(ctx), errDestroy)
 }
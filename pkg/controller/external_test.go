@@ -19,8 +19,10 @@ package controller
 import (
 	"context"
 	"testing"
+	"time"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	xpmeta "github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
@@ -28,7 +30,11 @@ import (
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crossplane/terrajet/pkg/config"
@@ -36,6 +42,10 @@ import (
 	"github.com/crossplane/terrajet/pkg/resource/fake"
 	"github.com/crossplane/terrajet/pkg/resource/json"
 	"github.com/crossplane/terrajet/pkg/terraform"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+	"github.com/crossplane/terrajet/pkg/tracing"
+	"github.com/crossplane/terrajet/pkg/workspace"
+	workspacefake "github.com/crossplane/terrajet/pkg/workspace/fake"
 )
 
 var (
@@ -54,23 +64,24 @@ var (
 )
 
 type WorkspaceFns struct {
-	ApplyAsyncFn   func(callback terraform.CallbackFn) error
-	ApplyFn        func(ctx context.Context) (terraform.ApplyResult, error)
-	DestroyAsyncFn func(callback terraform.CallbackFn) error
+	ApplyAsyncFn   func(callback workspace.CallbackFn) error
+	ApplyFn        func(ctx context.Context) (workspace.ApplyResult, error)
+	DestroyAsyncFn func(callback workspace.CallbackFn) error
 	DestroyFn      func(ctx context.Context) error
-	RefreshFn      func(ctx context.Context) (terraform.RefreshResult, error)
-	PlanFn         func(ctx context.Context) (terraform.PlanResult, error)
+	RefreshFn      func(ctx context.Context) (workspace.RefreshResult, error)
+	PlanFn         func(ctx context.Context) (workspace.PlanResult, error)
+	ObserveFn      func(ctx context.Context) (workspace.ObserveResult, error)
 }
 
-func (c WorkspaceFns) ApplyAsync(callback terraform.CallbackFn) error {
+func (c WorkspaceFns) ApplyAsync(callback workspace.CallbackFn) error {
 	return c.ApplyAsyncFn(callback)
 }
 
-func (c WorkspaceFns) Apply(ctx context.Context) (terraform.ApplyResult, error) {
+func (c WorkspaceFns) Apply(ctx context.Context) (workspace.ApplyResult, error) {
 	return c.ApplyFn(ctx)
 }
 
-func (c WorkspaceFns) DestroyAsync(callback terraform.CallbackFn) error {
+func (c WorkspaceFns) DestroyAsync(callback workspace.CallbackFn) error {
 	return c.DestroyAsyncFn(callback)
 }
 
@@ -78,32 +89,36 @@ func (c WorkspaceFns) Destroy(ctx context.Context) error {
 	return c.DestroyFn(ctx)
 }
 
-func (c WorkspaceFns) Refresh(ctx context.Context) (terraform.RefreshResult, error) {
+func (c WorkspaceFns) Refresh(ctx context.Context) (workspace.RefreshResult, error) {
 	return c.RefreshFn(ctx)
 }
 
-func (c WorkspaceFns) Plan(ctx context.Context) (terraform.PlanResult, error) {
+func (c WorkspaceFns) Plan(ctx context.Context) (workspace.PlanResult, error) {
 	return c.PlanFn(ctx)
 }
 
+func (c WorkspaceFns) Observe(ctx context.Context) (workspace.ObserveResult, error) {
+	return c.ObserveFn(ctx)
+}
+
 type StoreFns struct {
-	WorkspaceFn func(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (*terraform.Workspace, error)
+	WorkspaceFn func(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error)
 }
 
-func (s StoreFns) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (*terraform.Workspace, error) {
+func (s StoreFns) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error) {
 	return s.WorkspaceFn(ctx, c, tr, ts, cfg)
 }
 
 type CallbackFns struct {
-	ApplyFn   func(string) terraform.CallbackFn
-	DestroyFn func(string) terraform.CallbackFn
+	ApplyFn   func(string) workspace.CallbackFn
+	DestroyFn func(string) workspace.CallbackFn
 }
 
-func (c CallbackFns) Apply(name string) terraform.CallbackFn {
+func (c CallbackFns) Apply(name string) workspace.CallbackFn {
 	return c.ApplyFn(name)
 }
 
-func (c CallbackFns) Destroy(name string) terraform.CallbackFn {
+func (c CallbackFns) Destroy(name string) workspace.CallbackFn {
 	return c.DestroyFn(name)
 }
 
@@ -149,7 +164,7 @@ func TestConnect(t *testing.T) {
 					return terraform.Setup{}, nil
 				},
 				store: StoreFns{
-					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (*terraform.Workspace, error) {
+					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (Workspace, error) {
 						return nil, errBoom
 					},
 				},
@@ -165,12 +180,22 @@ func TestConnect(t *testing.T) {
 					return terraform.Setup{}, nil
 				},
 				store: StoreFns{
-					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (*terraform.Workspace, error) {
+					WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (Workspace, error) {
 						return nil, nil
 					},
 				},
 			},
 		},
+		"InMemoryStore": {
+			reason: "Any store that returns a Workspace should be usable.",
+			args: args{
+				obj: &fake.Terraformed{},
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, nil
+				},
+				store: workspacefake.NewStore(),
+			},
+		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
@@ -183,14 +208,108 @@ func TestConnect(t *testing.T) {
 	}
 }
 
+func TestConnectInvalidAsyncTimeout(t *testing.T) {
+	obj := &fake.Terraformed{
+		Managed: xpfake.Managed{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					resource.AnnotationKeyAsyncTimeout: "0s",
+				},
+			},
+		},
+	}
+	store := StoreFns{
+		WorkspaceFn: func(_ context.Context, _ resource.SecretClient, _ resource.Terraformed, _ terraform.Setup, _ *config.Resource) (Workspace, error) {
+			return nil, nil
+		},
+	}
+	setupFn := func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+		return terraform.Setup{}, nil
+	}
+	r := &eventRecorder{}
+	c := NewConnector(nil, store, setupFn, &config.Resource{}, WithEventRecorder(r))
+	if _, err := c.Connect(context.TODO(), obj); err != nil {
+		t.Fatalf("Connect(...): an invalid timeout annotation should not fail the connection: %v", err)
+	}
+	if diff := cmp.Diff([]event.Reason{reasonInvalidAsyncTimeout}, r.reasons); diff != "" {
+		t.Errorf("Connect(...): -want events, +got events:\n%s", diff)
+	}
+}
+
+func TestConnectTracing(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "cool.group", Version: "v1alpha1", Kind: "CoolResource"}
+	type span struct {
+		Name       string
+		Status     codes.Code
+		Attributes []attribute.KeyValue
+	}
+	type args struct {
+		setupFn terraform.SetupFn
+	}
+	type want struct {
+		spans []span
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Success": {
+			reason: "The spans of the connection should be tagged with the resource.",
+			args: args{
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, nil
+				},
+			},
+			want: want{
+				spans: []span{
+					{Name: "GetTerraformSetup"},
+					{Name: "Connect", Attributes: tracing.ResourceAttributes(gvk, "cool-resource")},
+				},
+			},
+		},
+		"SetupFailed": {
+			reason: "The failure of the Terraform setup should be recorded.",
+			args: args{
+				setupFn: func(_ context.Context, _ client.Client, _ xpresource.Managed) (terraform.Setup, error) {
+					return terraform.Setup{}, errBoom
+				},
+			},
+			want: want{
+				spans: []span{
+					{Name: "GetTerraformSetup", Status: codes.Error},
+					{Name: "Connect", Status: codes.Error, Attributes: tracing.ResourceAttributes(gvk, "cool-resource")},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tp, exp := tracing.NewInMemoryTracerProvider()
+			c := NewConnector(nil, workspacefake.NewStore(), tc.args.setupFn, &config.Resource{}, WithTracing(tp, gvk))
+			obj := &fake.Terraformed{Managed: xpfake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "cool-resource"}}}
+			_, _ = c.Connect(context.TODO(), obj)
+			var got []span
+			for _, s := range exp.GetSpans() {
+				got = append(got, span{Name: s.Name, Status: s.Status.Code, Attributes: s.Attributes})
+			}
+			if diff := cmp.Diff(tc.want.spans, got, cmp.AllowUnexported(attribute.Value{})); diff != "" {
+				t.Errorf("\n%s\nConnect(...): -want spans, +got spans:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestObserve(t *testing.T) {
 	type args struct {
 		w   Workspace
+		cfg *config.Resource
 		obj xpresource.Managed
 	}
 	type want struct {
-		obs managed.ExternalObservation
-		err error
+		obs       managed.ExternalObservation
+		condition *xpv1.Condition
+		err       error
 	}
 	cases := map[string]struct {
 		reason string
@@ -205,39 +324,84 @@ func TestObserve(t *testing.T) {
 				err: errors.New(errUnexpectedObject),
 			},
 		},
-		"RefreshFailed": {
-			reason: "It should return error if we cannot refresh",
+		"ObserveFailed": {
+			reason: "It should return error if we cannot observe",
 			args: args{
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
-						return terraform.RefreshResult{}, errBoom
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{}, errBoom
 					},
 				},
 			},
 			want: want{
-				err: errors.Wrap(errBoom, errRefresh),
+				err: errors.Wrap(errBoom, errObserve),
 			},
 		},
-		"RefreshNotFound": {
+		"NotFound": {
 			reason: "It should not report error in case resource is not found",
 			args: args{
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
-						return terraform.RefreshResult{Exists: false}, nil
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{}, nil
 					},
 				},
 			},
 		},
-		"RefreshInProgress": {
+		"InterruptedOperation": {
+			reason: "An async operation interrupted by a restart of the provider should be reported in the condition",
+			args: args{
+				obj: &fake.Terraformed{},
+				w: WorkspaceFns{
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								InterruptedOperation: tferrors.NewOperationInterrupted("apply", time.Time{}),
+							},
+						}, nil
+					},
+				},
+			},
+			want: want{
+				condition: func() *xpv1.Condition {
+					c := resource.LastAsyncOperationCondition(tferrors.NewOperationInterrupted("apply", time.Time{}))
+					return &c
+				}(),
+			},
+		},
+		"InProgress": {
 			reason: "It should report exists and up-to-date if an operation is ongoing",
 			args: args{
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
-						return terraform.RefreshResult{
-							IsApplying: true,
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								IsApplying: true,
+							},
+						}, nil
+					},
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: true,
+				},
+			},
+		},
+		"Waiting": {
+			reason: "It should report that the operation is waiting for its turn",
+			args: args{
+				obj: &fake.Terraformed{},
+				w: WorkspaceFns{
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								IsApplying: true,
+								IsWaiting:  true,
+							},
 						}, nil
 					},
 				},
@@ -247,6 +411,10 @@ func TestObserve(t *testing.T) {
 					ResourceExists:   true,
 					ResourceUpToDate: true,
 				},
+				condition: func() *xpv1.Condition {
+					c := resource.AsyncOperationWaitingCondition()
+					return &c
+				}(),
 			},
 		},
 		"TransitionToReady": {
@@ -254,10 +422,12 @@ func TestObserve(t *testing.T) {
 			args: args{
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
-						return terraform.RefreshResult{
-							Exists: true,
-							State:  exampleState,
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								Exists: true,
+								State:  exampleState,
+							},
 						}, nil
 					},
 				},
@@ -271,56 +441,172 @@ func TestObserve(t *testing.T) {
 				},
 			},
 		},
-		"PlanFailed": {
-			reason: "Failure of plan should be reported",
+		"Success": {
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ConditionedStatus: xpv1.ConditionedStatus{
+							Conditions: []xpv1.Condition{xpv1.Available()},
+						},
+					},
+				},
+				w: WorkspaceFns{
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								Exists: true,
+								State:  exampleState,
+							},
+							UpToDate: true,
+						}, nil
+					},
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:          true,
+					ResourceUpToDate:        true,
+					ResourceLateInitialized: true,
+				},
+			},
+		},
+		"ObserveOnlyDeleted": {
+			reason: "The external resource that's only observed should be left untouched when the resource is deleted",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations:       map[string]string{resource.AnnotationKeyManagementPolicy: string(config.ManagementPolicyObserveOnly)},
+							DeletionTimestamp: &metav1.Time{Time: time.Now()},
+						},
+					},
+				},
+				w: WorkspaceFns{
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{}, errBoom
+					},
+				},
+			},
+		},
+		"ObserveOnlyNotFound": {
+			reason: "The external resource that's only observed should not be created if it doesn't exist",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{resource.AnnotationKeyManagementPolicy: string(config.ManagementPolicyObserveOnly)},
+						},
+					},
+				},
+				w: WorkspaceFns{
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{}, nil
+					},
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtObservedNotFound, config.ManagementPolicyObserveOnly),
+			},
+		},
+		"ObserveOnly": {
+			reason: "The external resource that's only observed should be reported as up-to-date without a plan",
 			args: args{
 				obj: &fake.Terraformed{
 					Managed: xpfake.Managed{
 						ObjectMeta: metav1.ObjectMeta{
 							Annotations: map[string]string{
-								xpmeta.AnnotationKeyExternalName: "some-id",
+								resource.AnnotationKeyManagementPolicy: string(config.ManagementPolicyObserveOnly),
+								xpmeta.AnnotationKeyExternalName:       "some-id",
 							},
 						},
-						ConditionedStatus: xpv1.ConditionedStatus{
-							Conditions: []xpv1.Condition{xpv1.Available()},
-						},
 					},
 				},
 				w: WorkspaceFns{
-					RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
-						return terraform.RefreshResult{
-							Exists: true,
-							State:  exampleState,
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								Exists: true,
+								State:  exampleState,
+							},
 						}, nil
 					},
-					PlanFn: func(_ context.Context) (terraform.PlanResult, error) {
-						return terraform.PlanResult{}, errBoom
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: true,
+				},
+				condition: func() *xpv1.Condition {
+					c := xpv1.Available()
+					return &c
+				}(),
+			},
+		},
+		"DataSource": {
+			reason: "A data source should only be observed regardless of its management policy",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								resource.AnnotationKeyManagementPolicy: string(config.ManagementPolicyFull),
+								xpmeta.AnnotationKeyExternalName:       "some-id",
+							},
+						},
+					},
+				},
+				cfg: config.DefaultResource("terrajet_resource", nil, func(r *config.Resource) {
+					r.DataSource = true
+				}),
+				w: WorkspaceFns{
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								Exists: true,
+								State:  exampleState,
+							},
+						}, nil
 					},
 				},
 			},
 			want: want{
-				err: errors.Wrap(errBoom, errPlan),
+				obs: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: true,
+				},
+				condition: func() *xpv1.Condition {
+					c := xpv1.Available()
+					return &c
+				}(),
 			},
 		},
-		"Success": {
+		"Import": {
+			reason: "The observed values of the external resource that's being imported should be written to the spec",
 			args: args{
 				obj: &fake.Terraformed{
 					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								resource.AnnotationKeyManagementPolicy: string(config.ManagementPolicyImport),
+								xpmeta.AnnotationKeyExternalName:       "some-id",
+							},
+						},
 						ConditionedStatus: xpv1.ConditionedStatus{
 							Conditions: []xpv1.Condition{xpv1.Available()},
 						},
 					},
+					LateInitializer: fake.LateInitializer{Result: true},
 				},
 				w: WorkspaceFns{
-					RefreshFn: func(_ context.Context) (terraform.RefreshResult, error) {
-						return terraform.RefreshResult{
-							Exists: true,
-							State:  exampleState,
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								Exists: true,
+								State:  exampleState,
+							},
 						}, nil
 					},
-					PlanFn: func(_ context.Context) (terraform.PlanResult, error) {
-						return terraform.PlanResult{UpToDate: true}, nil
-					},
 				},
 			},
 			want: want{
@@ -329,20 +615,176 @@ func TestObserve(t *testing.T) {
 					ResourceUpToDate:        true,
 					ResourceLateInitialized: true,
 				},
+				condition: func() *xpv1.Condition {
+					c := xpv1.Available()
+					return &c
+				}(),
+			},
+		},
+		"Drifted": {
+			reason: "The attributes that are not up-to-date should be reported in the condition",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								xpmeta.AnnotationKeyExternalName: "some-id",
+							},
+						},
+						ConditionedStatus: xpv1.ConditionedStatus{
+							Conditions: []xpv1.Condition{xpv1.Available()},
+						},
+					},
+				},
+				w: WorkspaceFns{
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								Exists: true,
+								State:  exampleState,
+							},
+							Diff: workspace.PlanDiff{
+								{Path: "param", Before: "paramval", After: "newval"},
+							},
+						}, nil
+					},
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: false,
+					Diff:             `param: "paramval" => "newval"`,
+				},
+				condition: func() *xpv1.Condition {
+					c := resource.UpToDateCondition(false, `param: "paramval" => "newval"`)
+					return &c
+				}(),
+			},
+		},
+		"ReplacementBlocked": {
+			reason: "A drift that requires a replacement denied by the policy should be reported as a blocked replacement",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								xpmeta.AnnotationKeyExternalName: "some-id",
+							},
+						},
+						ConditionedStatus: xpv1.ConditionedStatus{
+							Conditions: []xpv1.Condition{xpv1.Available()},
+						},
+					},
+				},
+				w: WorkspaceFns{
+					ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+						return workspace.ObserveResult{
+							RefreshResult: workspace.RefreshResult{
+								Exists: true,
+								State:  exampleState,
+							},
+							RequiresReplace: true,
+							Diff: workspace.PlanDiff{
+								{Path: "param", Before: "paramval", After: "newval"},
+							},
+						}, nil
+					},
+				},
+			},
+			want: want{
+				obs: managed.ExternalObservation{
+					ResourceExists:   true,
+					ResourceUpToDate: false,
+					Diff:             `param: "paramval" => "newval"`,
+				},
+				condition: func() *xpv1.Condition {
+					c := resource.ReplacementBlockedCondition(resource.ReasonReplacementDenied, errReplacementDenied+`: param: "paramval" => "newval"`)
+					return &c
+				}(),
 			},
 		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := &external{workspace: tc.w, config: config.DefaultResource("terrajet_resource", nil)}
-			_, err := e.Observe(context.TODO(), tc.args.obj)
+			cfg := tc.args.cfg
+			if cfg == nil {
+				cfg = config.DefaultResource("terrajet_resource", nil)
+			}
+			e := &external{workspace: tc.w, config: cfg, eventRecorder: event.NewNopRecorder()}
+			obs, err := e.Observe(context.TODO(), tc.args.obj)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nObserve(...): -want error, +got error:\n%s", tc.reason, diff)
 			}
+			if tc.want.condition != nil {
+				if diff := cmp.Diff(*tc.want.condition, tc.args.obj.GetCondition(tc.want.condition.Type), test.EquateConditions()); diff != "" {
+					t.Errorf("\n%s\nObserve(...): -want condition, +got condition:\n%s", tc.reason, diff)
+				}
+				if diff := cmp.Diff(tc.want.obs, obs); diff != "" {
+					t.Errorf("\n%s\nObserve(...): -want observation, +got observation:\n%s", tc.reason, diff)
+				}
+			}
 		})
 	}
 }
 
+// eventRecorder records the reasons of the events.
+type eventRecorder struct {
+	reasons []event.Reason
+}
+
+func (r *eventRecorder) Event(_ runtime.Object, e event.Event) {
+	r.reasons = append(r.reasons, e.Reason)
+}
+
+func (r *eventRecorder) WithAnnotations(_ ...string) event.Recorder {
+	return r
+}
+
+func TestObserveDriftedEvent(t *testing.T) {
+	diff := workspace.PlanDiff{{Path: "param", Before: "paramval", After: "newval"}}
+	w := WorkspaceFns{
+		ObserveFn: func(_ context.Context) (workspace.ObserveResult, error) {
+			return workspace.ObserveResult{
+				RefreshResult: workspace.RefreshResult{
+					Exists: true,
+					State:  exampleState,
+				},
+				Diff: diff,
+			}, nil
+		},
+	}
+	obj := &fake.Terraformed{
+		Managed: xpfake.Managed{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					xpmeta.AnnotationKeyExternalName: "some-id",
+				},
+			},
+			ConditionedStatus: xpv1.ConditionedStatus{
+				Conditions: []xpv1.Condition{xpv1.Available()},
+			},
+		},
+	}
+	r := &eventRecorder{}
+	e := &external{workspace: w, config: config.DefaultResource("terrajet_resource", nil), eventRecorder: r}
+	for i := 0; i < 2; i++ {
+		if _, err := e.Observe(context.TODO(), obj); err != nil {
+			t.Fatalf("Observe(...): %v", err)
+		}
+	}
+	if diff := cmp.Diff([]event.Reason{reasonDrifted}, r.reasons); diff != "" {
+		t.Errorf("Observe(...): a resource that stays drifted should be reported once: -want events, +got events:\n%s", diff)
+	}
+	diff = workspace.PlanDiff{{Path: "param", Before: "paramval", After: "othervalue"}}
+	if _, err := e.Observe(context.TODO(), obj); err != nil {
+		t.Fatalf("Observe(...): %v", err)
+	}
+	if diff := cmp.Diff([]event.Reason{reasonDrifted, reasonDrifted}, r.reasons); diff != "" {
+		t.Errorf("Observe(...): a changed diff should be reported: -want events, +got events:\n%s", diff)
+	}
+}
+
 func TestCreate(t *testing.T) {
 	type args struct {
 		w   Workspace
@@ -374,13 +816,13 @@ func TestCreate(t *testing.T) {
 					UseAsync: true,
 				},
 				c: CallbackFns{
-					ApplyFn: func(s string) terraform.CallbackFn {
+					ApplyFn: func(s string) workspace.CallbackFn {
 						return nil
 					},
 				},
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					ApplyAsyncFn: func(_ terraform.CallbackFn) error {
+					ApplyAsyncFn: func(_ workspace.CallbackFn) error {
 						return errBoom
 					},
 				},
@@ -395,8 +837,8 @@ func TestCreate(t *testing.T) {
 				cfg: &config.Resource{},
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					ApplyFn: func(_ context.Context) (terraform.ApplyResult, error) {
-						return terraform.ApplyResult{}, errBoom
+					ApplyFn: func(_ context.Context) (workspace.ApplyResult, error) {
+						return workspace.ApplyResult{}, errBoom
 					},
 				},
 			},
@@ -418,10 +860,11 @@ func TestCreate(t *testing.T) {
 
 func TestUpdate(t *testing.T) {
 	type args struct {
-		w   Workspace
-		cfg *config.Resource
-		c   CallbackProvider
-		obj xpresource.Managed
+		w       Workspace
+		cfg     *config.Resource
+		c       CallbackProvider
+		obj     xpresource.Managed
+		replace bool
 	}
 	type want struct {
 		err error
@@ -447,13 +890,13 @@ func TestUpdate(t *testing.T) {
 					UseAsync: true,
 				},
 				c: CallbackFns{
-					ApplyFn: func(s string) terraform.CallbackFn {
+					ApplyFn: func(s string) workspace.CallbackFn {
 						return nil
 					},
 				},
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					ApplyAsyncFn: func(_ terraform.CallbackFn) error {
+					ApplyAsyncFn: func(_ workspace.CallbackFn) error {
 						return errBoom
 					},
 				},
@@ -468,8 +911,58 @@ func TestUpdate(t *testing.T) {
 				cfg: &config.Resource{},
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					ApplyFn: func(_ context.Context) (terraform.ApplyResult, error) {
-						return terraform.ApplyResult{}, errBoom
+					ApplyFn: func(_ context.Context) (workspace.ApplyResult, error) {
+						return workspace.ApplyResult{}, errBoom
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errApply),
+			},
+		},
+		"ReplacementDenied": {
+			reason: "It should not apply if the resource has to be replaced and the policy denies it",
+			args: args{
+				cfg:     &config.Resource{},
+				obj:     &fake.Terraformed{},
+				replace: true,
+			},
+			want: want{
+				err: errors.New(errReplacementDenied),
+			},
+		},
+		"ReplacementNotApproved": {
+			reason: "It should not apply if the resource has to be replaced and the replacement is not approved",
+			args: args{
+				cfg: &config.Resource{
+					ReplacementPolicy: config.ReplacementPolicyRequireApproval,
+				},
+				obj:     &fake.Terraformed{},
+				replace: true,
+			},
+			want: want{
+				err: errors.New(errReplacementNotApproved),
+			},
+		},
+		"ReplacementApproved": {
+			reason: "It should apply if the resource has to be replaced and the replacement is approved",
+			args: args{
+				cfg: &config.Resource{
+					ReplacementPolicy: config.ReplacementPolicyRequireApproval,
+				},
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								resource.AnnotationKeyApproveReplacement: "true",
+							},
+						},
+					},
+				},
+				replace: true,
+				w: WorkspaceFns{
+					ApplyFn: func(_ context.Context) (workspace.ApplyResult, error) {
+						return workspace.ApplyResult{}, errBoom
 					},
 				},
 			},
@@ -480,7 +973,7 @@ func TestUpdate(t *testing.T) {
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := &external{workspace: tc.w, callback: tc.c, config: tc.cfg}
+			e := &external{workspace: tc.w, callback: tc.c, config: tc.cfg, requiresReplace: tc.args.replace}
 			_, err := e.Update(context.TODO(), tc.args.obj)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\nCreate(...): -want error, +got error:\n%s", tc.reason, diff)
@@ -511,13 +1004,13 @@ func TestDelete(t *testing.T) {
 					UseAsync: true,
 				},
 				c: CallbackFns{
-					DestroyFn: func(_ string) terraform.CallbackFn {
+					DestroyFn: func(_ string) workspace.CallbackFn {
 						return nil
 					},
 				},
 				obj: &fake.Terraformed{},
 				w: WorkspaceFns{
-					DestroyAsyncFn: func(_ terraform.CallbackFn) error {
+					DestroyAsyncFn: func(_ workspace.CallbackFn) error {
 						return errBoom
 					},
 				},
@@ -526,6 +1019,42 @@ func TestDelete(t *testing.T) {
 				err: errors.Wrap(errBoom, errStartAsyncDestroy),
 			},
 		},
+		"ObserveOnly": {
+			reason: "It should not destroy the external resource that's only observed",
+			args: args{
+				obj: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{resource.AnnotationKeyManagementPolicy: string(config.ManagementPolicyObserveOnly)},
+						},
+					},
+				},
+				cfg: &config.Resource{},
+				w: WorkspaceFns{
+					DestroyFn: func(_ context.Context) error {
+						return nil
+					},
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtNotManaged, "delete", config.ManagementPolicyObserveOnly),
+			},
+		},
+		"DataSource": {
+			reason: "It should never destroy anything for a data source",
+			args: args{
+				obj: &fake.Terraformed{},
+				cfg: &config.Resource{DataSource: true},
+				w: WorkspaceFns{
+					DestroyFn: func(_ context.Context) error {
+						return nil
+					},
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtNotManaged, "delete", config.ManagementPolicyObserveOnly),
+			},
+		},
 		"SyncDestroyFailed": {
 			reason: "It should return error if it cannot destroy in sync mode",
 			args: args{
@@ -22,30 +22,36 @@ import (
 	"github.com/crossplane/terrajet/pkg/config"
 	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/terraform"
+	"github.com/crossplane/terrajet/pkg/workspace"
 )
 
-// TODO(muvaf): It's a bit weird that the functions return the struct of a
-// specific implementation of this interface. Maybe a different package for the
-// returned result types?
-
 // Workspace is the set of methods that are needed for the controller to work.
-type Workspace interface {
-	ApplyAsync(terraform.CallbackFn) error
-	Apply(context.Context) (terraform.ApplyResult, error)
-	DestroyAsync(terraform.CallbackFn) error
-	Destroy(context.Context) error
-	Refresh(context.Context) (terraform.RefreshResult, error)
-	Plan(context.Context) (terraform.PlanResult, error)
-}
+type Workspace = workspace.Workspace
 
 // Store is where we can get access to the Terraform workspace of given resource.
 type Store interface {
-	Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (*terraform.Workspace, error)
+	Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error)
+}
+
+// StoreFn is a function that satisfies the Store interface.
+type StoreFn func(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error)
+
+// Workspace returns the workspace of the given resource.
+func (fn StoreFn) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error) {
+	return fn(ctx, c, tr, ts, cfg)
+}
+
+// CleanableStore is a Store that can remove the workspaces of the deleted
+// resources. It can also implement terraform.StoreDetacher to keep the
+// workspaces of the resources whose external resources are orphaned.
+type CleanableStore interface {
+	Store
+	terraform.StoreCleaner
 }
 
 // CallbackProvider provides functions that can be called with the result of
 // async operations.
 type CallbackProvider interface {
-	Apply(name string) terraform.CallbackFn
-	Destroy(name string) terraform.CallbackFn
+	Apply(name string) workspace.CallbackFn
+	Destroy(name string) workspace.CallbackFn
 }
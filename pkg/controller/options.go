@@ -18,6 +18,7 @@ package controller
 
 import (
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/crossplane/terrajet/pkg/config"
@@ -35,8 +36,15 @@ type Options struct {
 
 	// WorkspaceStore will be used to pick/initialize the workspace the specific CR
 	// instance should use.
+	//
+	// Deprecated: Use Store, which takes precedence if it's set.
 	WorkspaceStore *terraform.WorkspaceStore
 
+	// Store will be used to pick/initialize the workspace the specific CR
+	// instance should use and to remove it once the CR is deleted. See
+	// NewTerraformStore and NewInProcessStore.
+	Store CleanableStore
+
 	// SetupFn contains the provider-specific initialization logic, such as
 	// preparing the auth token for Terraform CLI.
 	SetupFn terraform.SetupFn
@@ -45,4 +53,24 @@ type Options struct {
 	// resource. Setting this enables External Secret Stores for the controller
 	// by adding connection.DetailsManager as a ConnectionPublisher.
 	SecretStoreConfigGVK *schema.GroupVersionKind
+
+	// StartWebhooks registers the conversion webhooks of the resources that
+	// have previous API versions with the webhook server of the manager,
+	// which needs to be configured with a TLS certificate.
+	StartWebhooks bool
+
+	// TracerProvider records the spans of the connections and the external
+	// operations of the resources. The spans are not recorded if it's nil.
+	// The same TracerProvider should be given to the workspace store to trace
+	// the Terraform CLI calls, see terraform.WithTracerProvider.
+	TracerProvider trace.TracerProvider
+}
+
+// GetStore returns the Store of the options, or the WorkspaceStore if the
+// Store is not set.
+func (o Options) GetStore() CleanableStore {
+	if o.Store != nil {
+		return o.Store
+	}
+	return NewTerraformStore(o.WorkspaceStore)
 }
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+const (
+	errNoProviderConfig    = "no providerConfigRef provided"
+	errGetProviderConfig   = "cannot get referenced ProviderConfig"
+	errTrackUsage          = "cannot track ProviderConfig usage"
+	errGetConfiguration    = "cannot get provider configuration"
+	errSensitiveParameters = "cannot get sensitive provider configuration"
+)
+
+// ProviderConfig is a ProviderConfig whose spec is generated from the schema
+// of the Terraform provider block.
+type ProviderConfig interface {
+	client.Object
+
+	// GetConfiguration returns the configuration of the Terraform provider
+	// without its sensitive arguments.
+	GetConfiguration() (map[string]interface{}, error)
+	// GetSensitiveConfigurationMapping returns the field paths of the secret
+	// references of the sensitive arguments keyed by their Terraform paths.
+	GetSensitiveConfigurationMapping() map[string]string
+}
+
+// NewProviderConfigSetupFn returns a SetupFn that configures the Terraform
+// provider with the configuration in the spec of the ProviderConfig that the
+// managed resource references. The sensitive arguments are read from the
+// referenced secrets. The given ProviderConfig is only used as the type of
+// the fetched ones. If usage is not nil, the usage of the ProviderConfig is
+// tracked with ProviderConfigUsages of its type.
+func NewProviderConfigSetupFn(version string, requirement terraform.ProviderRequirement, pc ProviderConfig, usage xpresource.ProviderConfigUsage) terraform.SetupFn {
+	return func(ctx context.Context, kube client.Client, mg xpresource.Managed) (terraform.Setup, error) {
+		ref := mg.GetProviderConfigReference()
+		if ref == nil {
+			return terraform.Setup{}, errors.New(errNoProviderConfig)
+		}
+		if usage != nil {
+			if err := xpresource.NewProviderConfigUsageTracker(kube, usage).Track(ctx, mg); err != nil {
+				return terraform.Setup{}, errors.Wrap(err, errTrackUsage)
+			}
+		}
+		obj := pc.DeepCopyObject().(ProviderConfig)
+		if err := kube.Get(ctx, types.NamespacedName{Name: ref.Name}, obj); err != nil {
+			return terraform.Setup{}, errors.Wrap(err, errGetProviderConfig)
+		}
+		cfg, err := obj.GetConfiguration()
+		if err != nil {
+			return terraform.Setup{}, errors.Wrap(err, errGetConfiguration)
+		}
+		if err := resource.GetSensitiveParameters(ctx, &APISecretClient{kube: kube}, obj, cfg, obj.GetSensitiveConfigurationMapping()); err != nil {
+			return terraform.Setup{}, errors.Wrap(err, errSensitiveParameters)
+		}
+		return terraform.Setup{
+			Version:       version,
+			Requirement:   requirement,
+			Configuration: cfg,
+		}, nil
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+// providerConfig is a ProviderConfig of a Terraform provider with a region
+// and a sensitive token argument.
+type providerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              providerConfigSpec `json:"spec"`
+}
+
+type providerConfigSpec struct {
+	Region         string                  `json:"region"`
+	TokenSecretRef *xpv1.SecretKeySelector `json:"tokenSecretRef,omitempty"`
+}
+
+func (pc *providerConfig) DeepCopyObject() runtime.Object {
+	c := *pc
+	return &c
+}
+
+func (pc *providerConfig) GetConfiguration() (map[string]interface{}, error) {
+	return map[string]interface{}{"region": pc.Spec.Region}, nil
+}
+
+func (pc *providerConfig) GetSensitiveConfigurationMapping() map[string]string {
+	return map[string]string{"token": "spec.tokenSecretRef"}
+}
+
+func TestNewProviderConfigSetupFn(t *testing.T) {
+	requirement := terraform.ProviderRequirement{Source: "hashicorp/test", Version: "1.2.3"}
+	getFn := func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+		switch o := obj.(type) {
+		case *providerConfig:
+			o.Spec = providerConfigSpec{
+				Region: "us-east-1",
+				TokenSecretRef: &xpv1.SecretKeySelector{
+					SecretReference: xpv1.SecretReference{Name: "creds", Namespace: "crossplane-system"},
+					Key:             "token",
+				},
+			}
+		case *v1.Secret:
+			o.Data = map[string][]byte{"token": []byte("s3cr3t")}
+		}
+		return nil
+	}
+	type args struct {
+		kube client.Client
+		mg   xpresource.Managed
+	}
+	type want struct {
+		setup terraform.Setup
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NoProviderConfigRef": {
+			reason: "It should return an error if the managed resource doesn't reference a ProviderConfig",
+			args: args{
+				mg: &xpfake.Managed{},
+			},
+			want: want{
+				err: errors.New(errNoProviderConfig),
+			},
+		},
+		"GetProviderConfigFailed": {
+			reason: "It should return an error if the ProviderConfig cannot be fetched",
+			args: args{
+				kube: &test.MockClient{
+					MockGet: test.NewMockGetFn(errBoom),
+				},
+				mg: &xpfake.Managed{
+					ProviderConfigReferencer: xpfake.ProviderConfigReferencer{Ref: &xpv1.Reference{Name: "default"}},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errGetProviderConfig),
+			},
+		},
+		"Success": {
+			reason: "The configuration should include the sensitive arguments read from the referenced secrets",
+			args: args{
+				kube: &test.MockClient{
+					MockGet: getFn,
+				},
+				mg: &xpfake.Managed{
+					ProviderConfigReferencer: xpfake.ProviderConfigReferencer{Ref: &xpv1.Reference{Name: "default"}},
+				},
+			},
+			want: want{
+				setup: terraform.Setup{
+					Version:     "1.1.0",
+					Requirement: requirement,
+					Configuration: terraform.ProviderConfiguration{
+						"region": "us-east-1",
+						"token":  "s3cr3t",
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fn := NewProviderConfigSetupFn("1.1.0", requirement, &providerConfig{}, nil)
+			got, err := fn(context.TODO(), tc.args.kube, tc.args.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nSetupFn(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.setup, got); diff != "" {
+				t.Errorf("\n%s\nSetupFn(...): -want setup, +got setup:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/terraform"
+)
+
+// NewTerraformStore returns a CleanableStore whose workspaces run the
+// Terraform CLI.
+func NewTerraformStore(ws *terraform.WorkspaceStore) CleanableStore {
+	return &terraformStore{WorkspaceStore: ws}
+}
+
+type terraformStore struct {
+	*terraform.WorkspaceStore
+}
+
+func (s *terraformStore) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error) {
+	w, err := s.WorkspaceStore.Workspace(ctx, c, tr, ts, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewInProcessStore returns a CleanableStore whose workspaces call the
+// provider served in-process.
+func NewInProcessStore(ws *terraform.InProcessWorkspaceStore) CleanableStore {
+	return &inProcessStore{InProcessWorkspaceStore: ws}
+}
+
+type inProcessStore struct {
+	*terraform.InProcessWorkspaceStore
+}
+
+func (s *inProcessStore) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts terraform.Setup, cfg *config.Resource) (Workspace, error) {
+	w, err := s.InProcessWorkspaceStore.Workspace(ctx, c, tr, ts, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
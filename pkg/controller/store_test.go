@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/terrajet/pkg/terraform"
+	workspacefake "github.com/crossplane/terrajet/pkg/workspace/fake"
+)
+
+func TestStoreDetacher(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		store  CleanableStore
+		want   bool
+	}{
+		"Terraform": {
+			reason: "The workspaces of the Terraform store should be detachable.",
+			store:  NewTerraformStore(terraform.NewWorkspaceStore(nil)),
+			want:   true,
+		},
+		"InProcess": {
+			reason: "The workspaces of the in-process store should be detachable.",
+			store:  NewInProcessStore(terraform.NewInProcessWorkspaceStore(nil, nil)),
+			want:   true,
+		},
+		"Fake": {
+			reason: "The fake store should not be detachable.",
+			store:  workspacefake.NewStore(),
+			want:   false,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, got := tc.store.(terraform.StoreDetacher); got != tc.want {
+				t.Errorf("\n%s\nStoreDetacher: want %t, got %t", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOptionsGetStore(t *testing.T) {
+	ws := terraform.NewWorkspaceStore(nil)
+	fs := workspacefake.NewStore()
+	cases := map[string]struct {
+		reason string
+		o      Options
+		want   CleanableStore
+	}{
+		"Store": {
+			reason: "The Store should be used if it's set.",
+			o:      Options{Store: fs, WorkspaceStore: ws},
+			want:   fs,
+		},
+		"WorkspaceStore": {
+			reason: "The WorkspaceStore should be used if the Store is not set.",
+			o:      Options{WorkspaceStore: ws},
+			want:   &terraformStore{WorkspaceStore: ws},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// The stores are compared by identity.
+			if diff := cmp.Diff(tc.want, tc.o.GetStore(), cmp.Comparer(func(a, b *terraform.WorkspaceStore) bool { return a == b }), cmp.Comparer(func(a, b *workspacefake.Store) bool { return a == b })); diff != "" {
+				t.Errorf("\n%s\nGetStore(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conversion contains the functions that convert the managed
+// resources between their API versions.
+package conversion
+
+import (
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	errToUnstructured   = "cannot convert the source object to unstructured"
+	errFromUnstructured = "cannot convert unstructured to the destination object"
+	fmtErrExpand        = "cannot expand wildcards of field path %q"
+	fmtErrWildcards     = "field paths %q and %q must have the same number of wildcards"
+	fmtErrMove          = "cannot move field %q to %q"
+)
+
+const wildcard = "*"
+
+// FieldMove moves the value at a field path of the source object to another
+// field path in the converted object. The paths may contain wildcards for the
+// items of lists, e.g. spec.forProvider.rule[*].port, in which case both
+// paths need to have the same number of wildcards, which are matched in
+// order.
+type FieldMove struct {
+	From string
+	To   string
+}
+
+// Reverse returns the moves that undo the given ones.
+func Reverse(moves []FieldMove) []FieldMove {
+	result := make([]FieldMove, len(moves))
+	for i, m := range moves {
+		result[len(moves)-1-i] = FieldMove{From: m.To, To: m.From}
+	}
+	return result
+}
+
+// Convert converts src into dst, which are different API versions of the same
+// kind. All fields of src are copied to the same paths in dst except the ones
+// that are moved. The fields that do not exist in dst are dropped. The kind
+// and API version of dst are preserved.
+func Convert(src, dst runtime.Object, moves []FieldMove) error {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(src)
+	if err != nil {
+		return errors.Wrap(err, errToUnstructured)
+	}
+	p := fieldpath.Pave(u)
+	for _, m := range moves {
+		if err := move(p, m); err != nil {
+			return errors.Wrapf(err, fmtErrMove, m.From, m.To)
+		}
+	}
+	gvk := dst.GetObjectKind().GroupVersionKind()
+	u = p.UnstructuredContent()
+	delete(u, "apiVersion")
+	delete(u, "kind")
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u, dst); err != nil {
+		return errors.Wrap(err, errFromUnstructured)
+	}
+	dst.GetObjectKind().SetGroupVersionKind(gvk)
+	return nil
+}
+
+func move(p *fieldpath.Paved, m FieldMove) error {
+	fromPattern, err := fieldpath.Parse(m.From)
+	if err != nil {
+		return err
+	}
+	toPattern, err := fieldpath.Parse(m.To)
+	if err != nil {
+		return err
+	}
+	if countWildcards(fromPattern) != countWildcards(toPattern) {
+		return errors.Errorf(fmtErrWildcards, m.From, m.To)
+	}
+	paths, err := p.ExpandWildcards(m.From)
+	if err != nil {
+		return errors.Wrapf(err, fmtErrExpand, m.From)
+	}
+	values := make([]interface{}, len(paths))
+	targets := make([]string, len(paths))
+	for i, path := range paths {
+		from, err := fieldpath.Parse(path)
+		if err != nil {
+			return err
+		}
+		if values[i], err = p.GetValue(path); err != nil {
+			return err
+		}
+		targets[i] = fillWildcards(toPattern, fromPattern, from).String()
+	}
+	// All values are removed before any of them is set so that a move does
+	// not overwrite a value that it moves afterwards.
+	for _, path := range paths {
+		if err := deleteField(p, path); err != nil {
+			return err
+		}
+	}
+	for i, t := range targets {
+		if err := p.SetValue(t, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func countWildcards(s fieldpath.Segments) int {
+	n := 0
+	for _, seg := range s {
+		if seg.Type == fieldpath.SegmentField && seg.Field == wildcard {
+			n++
+		}
+	}
+	return n
+}
+
+// fillWildcards replaces the wildcards in the target pattern with the
+// segments of the expanded path at the positions of the wildcards in the
+// source pattern.
+func fillWildcards(target, pattern, expanded fieldpath.Segments) fieldpath.Segments {
+	var fills fieldpath.Segments
+	for i, seg := range pattern {
+		if seg.Type == fieldpath.SegmentField && seg.Field == wildcard {
+			fills = append(fills, expanded[i])
+		}
+	}
+	result := make(fieldpath.Segments, len(target))
+	for i, seg := range target {
+		result[i] = seg
+		if seg.Type == fieldpath.SegmentField && seg.Field == wildcard {
+			result[i], fills = fills[0], fills[1:]
+		}
+	}
+	return result
+}
+
+func deleteField(p *fieldpath.Paved, path string) error {
+	s, err := fieldpath.Parse(path)
+	if err != nil {
+		return err
+	}
+	last := s[len(s)-1]
+	parent := interface{}(p.UnstructuredContent())
+	if len(s) > 1 {
+		if parent, err = p.GetValue(s[:len(s)-1].String()); err != nil {
+			return err
+		}
+	}
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		delete(v, last.Field)
+		return nil
+	case []interface{}:
+		// The item is set to nil rather than removed so that the indices of
+		// the other items stay the same while they're being moved.
+		v[last.Index] = nil
+		return nil
+	default:
+		return errors.Errorf("cannot delete field %q of a non-object value", path)
+	}
+}
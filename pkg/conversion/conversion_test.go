@@ -0,0 +1,171 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+type oldThing struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              oldThingSpec `json:"spec"`
+}
+
+type oldThingSpec struct {
+	Name  string    `json:"name,omitempty"`
+	Port  int64     `json:"port,omitempty"`
+	Rules []oldRule `json:"rules,omitempty"`
+}
+
+type oldRule struct {
+	Cidr string `json:"cidr,omitempty"`
+}
+
+func (t *oldThing) DeepCopyObject() runtime.Object {
+	c := *t
+	return &c
+}
+
+type newThing struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              newThingSpec `json:"spec"`
+}
+
+type newThingSpec struct {
+	DisplayName string    `json:"displayName,omitempty"`
+	Network     *network  `json:"network,omitempty"`
+	Rules       []newRule `json:"rules,omitempty"`
+}
+
+type network struct {
+	Port int64 `json:"port,omitempty"`
+}
+
+type newRule struct {
+	CidrBlock string `json:"cidrBlock,omitempty"`
+}
+
+func (t *newThing) DeepCopyObject() runtime.Object {
+	c := *t
+	return &c
+}
+
+var moves = []FieldMove{
+	{From: "spec.name", To: "spec.displayName"},
+	{From: "spec.port", To: "spec.network.port"},
+	{From: "spec.rules[*].cidr", To: "spec.rules[*].cidrBlock"},
+}
+
+func TestConvert(t *testing.T) {
+	old := &oldThing{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "example.org/v1alpha1", Kind: "Thing"},
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Labels: map[string]string{"key": "value"}},
+		Spec: oldThingSpec{
+			Name:  "thing",
+			Port:  8080,
+			Rules: []oldRule{{Cidr: "10.0.0.0/16"}, {Cidr: "10.1.0.0/16"}},
+		},
+	}
+	converted := &newThing{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "example.org/v1beta1", Kind: "Thing"},
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Labels: map[string]string{"key": "value"}},
+		Spec: newThingSpec{
+			DisplayName: "thing",
+			Network:     &network{Port: 8080},
+			Rules:       []newRule{{CidrBlock: "10.0.0.0/16"}, {CidrBlock: "10.1.0.0/16"}},
+		},
+	}
+	type args struct {
+		src   runtime.Object
+		dst   runtime.Object
+		moves []FieldMove
+	}
+	type want struct {
+		dst runtime.Object
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"ToNewVersion": {
+			reason: "The fields should be moved to their paths in the new version and the kind of the destination should be kept",
+			args: args{
+				src:   old,
+				dst:   &newThing{TypeMeta: converted.TypeMeta},
+				moves: moves,
+			},
+			want: want{
+				dst: converted,
+			},
+		},
+		"FromNewVersion": {
+			reason: "The reversed moves should convert the new version back to the old one",
+			args: args{
+				src:   converted,
+				dst:   &oldThing{TypeMeta: old.TypeMeta},
+				moves: Reverse(moves),
+			},
+			want: want{
+				dst: old,
+			},
+		},
+		"MissingField": {
+			reason: "The moves of the fields that are not set should be skipped",
+			args: args{
+				src:   &oldThing{Spec: oldThingSpec{Name: "thing"}},
+				dst:   &newThing{},
+				moves: moves,
+			},
+			want: want{
+				dst: &newThing{Spec: newThingSpec{DisplayName: "thing"}},
+			},
+		},
+		"WildcardMismatch": {
+			reason: "It should return an error if the paths of a move have different number of wildcards",
+			args: args{
+				src:   old,
+				dst:   &newThing{},
+				moves: []FieldMove{{From: "spec.rules[*].cidr", To: "spec.cidr"}},
+			},
+			want: want{
+				dst: &newThing{},
+				err: errors.Wrapf(errors.Errorf(fmtErrWildcards, "spec.rules[*].cidr", "spec.cidr"), fmtErrMove, "spec.rules[*].cidr", "spec.cidr"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := Convert(tc.args.src, tc.args.dst, tc.args.moves)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nConvert(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.dst, tc.args.dst); diff != "" {
+				t.Errorf("\n%s\nConvert(...): -want dst, +got dst:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
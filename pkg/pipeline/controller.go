@@ -64,6 +64,8 @@ func (cg *ControllerGenerator) Generate(cfg *config.Resource, typesPkgPath strin
 		"UseAsync":               cfg.UseAsync,
 		"ResourceType":           cfg.Name,
 		"Initializers":           cfg.InitializerFns,
+		"DataSource":             cfg.DataSource,
+		"ConversionWebhook":      len(cfg.PreviousVersions) > 0,
 	}
 
 	filePath := filepath.Join(cg.ControllerGroupDir, strings.ToLower(cfg.Kind), "zz_controller.go")
@@ -0,0 +1,219 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/muvaf/typewriter/pkg/wrapper"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/conversion"
+	"github.com/crossplane/terrajet/pkg/pipeline/templates"
+	"github.com/crossplane/terrajet/pkg/types/name"
+)
+
+// conversionInput is a resource of a previous version that is converted from
+// and to its hub version.
+type conversionInput struct {
+	*config.Resource
+	Moves []conversion.FieldMove
+}
+
+// NewConversionGenerator returns a new ConversionGenerator.
+func NewConversionGenerator(pkg *types.Package, rootDir, group, version string) *ConversionGenerator {
+	return &ConversionGenerator{
+		LocalDirectoryPath: filepath.Join(rootDir, "apis", strings.ToLower(strings.Split(group, ".")[0]), version),
+		LicenseHeaderPath:  filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		pkg:                pkg,
+	}
+}
+
+// ConversionGenerator generates the methods that mark the hub versions of the
+// resources and the ones that convert the previous versions from and to
+// their hub versions.
+type ConversionGenerator struct {
+	LocalDirectoryPath string
+	LicenseHeaderPath  string
+
+	pkg *types.Package
+}
+
+// Generate writes the conversion methods of the given hub and previous
+// version resources.
+func (cg *ConversionGenerator) Generate(hubs []*config.Resource, spokes []*conversionInput, apiVersion string) error {
+	file := wrapper.NewFile(cg.pkg.Path(), cg.pkg.Name(), templates.ConversionTemplate,
+		wrapper.WithGenStatement(GenStatement),
+		wrapper.WithHeaderPath(cg.LicenseHeaderPath),
+	)
+	hubKinds := make([]string, len(hubs))
+	for i, h := range hubs {
+		hubKinds[i] = h.Kind
+	}
+	spokeVars := make([]map[string]interface{}, len(spokes))
+	for i, s := range spokes {
+		spokeVars[i] = map[string]interface{}{
+			"CRD": map[string]string{
+				"Kind": s.Kind,
+			},
+			"Moves": s.Moves,
+		}
+	}
+	vars := map[string]interface{}{
+		"APIVersion": apiVersion,
+		"Hubs":       hubKinds,
+		"Spokes":     spokeVars,
+	}
+	filePath := filepath.Join(cg.LocalDirectoryPath, "zz_generated_conversion.go")
+	return errors.Wrap(file.Write(filePath, vars, os.ModePerm), "cannot write conversion methods file")
+}
+
+// newPreviousVersion returns the configuration of the given previous version
+// of the resource. Its Terraform schema is the one of the resource with the
+// field moves reverted.
+func newPreviousVersion(r *config.Resource, v config.PreviousVersion) (*config.Resource, error) {
+	pr := *r
+	pr.Version = v.Name
+	pr.PreviousVersions = nil
+	pr.Sensitive = config.Sensitive{
+		AdditionalConnectionDetailsFn: r.Sensitive.AdditionalConnectionDetailsFn,
+	}
+	pr.TerraformResource = copyResource(r.TerraformResource)
+	for i := len(v.FieldMoves) - 1; i >= 0; i-- {
+		m := v.FieldMoves[i]
+		toParent, toName, err := parentResource(pr.TerraformResource, m.To)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot find field %q", m.To)
+		}
+		fromParent, fromName, err := parentResource(pr.TerraformResource, m.From)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot find the parent of field %q", m.From)
+		}
+		s, ok := toParent.Schema[toName]
+		if !ok {
+			return nil, errors.Errorf("cannot find field %q", m.To)
+		}
+		delete(toParent.Schema, toName)
+		fromParent.Schema[fromName] = s
+	}
+	return &pr, nil
+}
+
+// fieldMoves returns the field moves of the given previous version with the
+// Terraform paths converted to the field paths in the CRDs of the hub and the
+// previous version.
+func fieldMoves(hub, previous *config.Resource, v config.PreviousVersion) ([]conversion.FieldMove, error) {
+	var result []conversion.FieldMove
+	for _, m := range v.FieldMoves {
+		from, err := crdFieldPaths(previous.TerraformResource, m.From)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get the field paths of %q in version %s", m.From, previous.Version)
+		}
+		to, err := crdFieldPaths(hub.TerraformResource, m.To)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get the field paths of %q in version %s", m.To, hub.Version)
+		}
+		if len(from) != len(to) {
+			return nil, errors.Errorf("cannot move field %q to %q with a different sensitivity", m.From, m.To)
+		}
+		for i := range from {
+			if strings.Count(from[i], "[*]") != strings.Count(to[i], "[*]") {
+				return nil, errors.Errorf("cannot move field %q to %q in a different number of blocks", m.From, m.To)
+			}
+			result = append(result, conversion.FieldMove{From: from[i], To: to[i]})
+		}
+	}
+	return result, nil
+}
+
+// crdFieldPaths returns the field paths of the given Terraform field in the
+// CRD. A field can be in both the spec and the status. The sensitive fields
+// are secret references in the spec and they are not in the status.
+func crdFieldPaths(r *schema.Resource, tfPath string) ([]string, error) {
+	parts := strings.Split(tfPath, ".")
+	var segments fieldpath.Segments
+	var s *schema.Schema
+	for i, p := range parts {
+		s = r.Schema[p]
+		if s == nil {
+			return nil, errors.Errorf("cannot find field %q", strings.Join(parts[:i+1], "."))
+		}
+		n := name.NewFromSnake(p).LowerCamelComputed
+		if i == len(parts)-1 {
+			if s.Sensitive {
+				n += "SecretRef"
+			}
+			segments = append(segments, fieldpath.Field(n))
+			break
+		}
+		e, ok := s.Elem.(*schema.Resource)
+		if !ok {
+			return nil, errors.Errorf("field %q is not a block", strings.Join(parts[:i+1], "."))
+		}
+		segments = append(segments, fieldpath.Field(n), fieldpath.Field("*"))
+		r = e
+	}
+	path := segments.String()
+	switch {
+	case s.Sensitive && s.Computed && !s.Optional:
+		// Sensitive observations are published in the connection secret.
+		return nil, nil
+	case s.Sensitive:
+		return []string{"spec.forProvider." + path}, nil
+	default:
+		return []string{"spec.forProvider." + path, "status.atProvider." + path}, nil
+	}
+}
+
+// parentResource returns the schema of the block that contains the field at
+// the given Terraform path and the name of the field in the block.
+func parentResource(r *schema.Resource, tfPath string) (*schema.Resource, string, error) {
+	parts := strings.Split(tfPath, ".")
+	for i, p := range parts[:len(parts)-1] {
+		s := r.Schema[p]
+		if s == nil {
+			return nil, "", errors.Errorf("cannot find field %q", strings.Join(parts[:i+1], "."))
+		}
+		e, ok := s.Elem.(*schema.Resource)
+		if !ok {
+			return nil, "", errors.Errorf("field %q is not a block", strings.Join(parts[:i+1], "."))
+		}
+		r = e
+	}
+	return r, parts[len(parts)-1], nil
+}
+
+// copyResource returns a deep copy of the schemas of the given resource and
+// its blocks.
+func copyResource(r *schema.Resource) *schema.Resource {
+	c := *r
+	c.Schema = make(map[string]*schema.Schema, len(r.Schema))
+	for k, s := range r.Schema {
+		sc := *s
+		if e, ok := s.Elem.(*schema.Resource); ok {
+			sc.Elem = copyResource(e)
+		}
+		c.Schema[k] = &sc
+	}
+	return &c
+}
@@ -100,6 +100,13 @@ func (cg *CRDGenerator) Generate(cfg *config.Resource) (string, error) {
 		"Provider": map[string]string{
 			"ShortName": cg.ProviderShortName,
 		},
+		"DataSource": cfg.DataSource,
+		// The current version of a resource with previous versions is the
+		// storage version of its CRD.
+		"StorageVersion": len(cfg.PreviousVersions) > 0,
+		"Terraform": map[string]string{
+			"Name": cfg.Name,
+		},
 		"XPCommonAPIsPackageAlias": file.Imports.UsePackage(tjtypes.PackagePathXPCommonAPIs),
 	}
 	filePath := filepath.Join(cg.LocalDirectoryPath, fmt.Sprintf("zz_%s_types.go", strings.ToLower(cfg.Kind)))
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	twtypes "github.com/muvaf/typewriter/pkg/types"
+	"github.com/muvaf/typewriter/pkg/wrapper"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/pipeline/templates"
+	tjtypes "github.com/crossplane/terrajet/pkg/types"
+)
+
+const (
+	// sensitivePathPrefix is the prefix of the field paths of the secret
+	// references that the types builder produces for a resource.
+	sensitivePathPrefix = "spec.forProvider."
+	// configurationPathPrefix is the prefix of the same field paths in the
+	// ProviderConfig.
+	configurationPathPrefix = "spec.configuration."
+)
+
+// NewProviderConfigGenerator returns a new ProviderConfigGenerator that
+// generates the ProviderConfig spec in the given API package, e.g.
+// "apis/v1alpha1", and the default SetupFn in the clients package.
+func NewProviderConfigGenerator(rootDir, modulePath, apiPackage string) *ProviderConfigGenerator {
+	return &ProviderConfigGenerator{
+		APIDirectoryPath:     filepath.Join(rootDir, apiPackage),
+		ClientsDirectoryPath: filepath.Join(rootDir, "internal", "clients"),
+		LicenseHeaderPath:    filepath.Join(rootDir, "hack", "boilerplate.go.txt"),
+		ModulePath:           modulePath,
+		pkg:                  types.NewPackage(filepath.Join(modulePath, apiPackage), filepath.Base(apiPackage)),
+	}
+}
+
+// ProviderConfigGenerator generates the spec of the ProviderConfig from the
+// schema of the Terraform provider block and a SetupFn that configures the
+// provider with it.
+type ProviderConfigGenerator struct {
+	APIDirectoryPath     string
+	ClientsDirectoryPath string
+	LicenseHeaderPath    string
+	ModulePath           string
+
+	pkg *types.Package
+}
+
+// Generate writes the ProviderConfig spec and the default SetupFn.
+func (pg *ProviderConfigGenerator) Generate(cfg *config.Resource) error {
+	file := wrapper.NewFile(pg.pkg.Path(), pg.pkg.Name(), templates.ProviderConfigTypesTemplate,
+		wrapper.WithGenStatement(GenStatement),
+		wrapper.WithHeaderPath(pg.LicenseHeaderPath),
+	)
+	gen, err := tjtypes.NewBuilder(pg.pkg).Build(cfg)
+	if err != nil {
+		return errors.Wrap(err, "cannot build types for the provider configuration")
+	}
+	// The provider configuration has no computed attributes, so there is no
+	// use of the top-level observation type.
+	genTypes := make([]*types.Named, 0, len(gen.Types))
+	for _, t := range gen.Types {
+		if t != gen.AtProviderType {
+			genTypes = append(genTypes, t)
+		}
+	}
+	// See the note in CRDGenerator.Generate about the scope.
+	pkg := types.NewPackage(pg.pkg.Path(), pg.pkg.Name())
+	typePrinter := twtypes.NewPrinter(file.Imports, pkg.Scope(), twtypes.WithComments(gen.Comments))
+	typesStr, err := typePrinter.Print(genTypes)
+	if err != nil {
+		return errors.Wrap(err, "cannot print the type list")
+	}
+	sensitive := map[string]string{}
+	for tfPath, xpPath := range cfg.Sensitive.GetFieldPaths() {
+		sensitive[tfPath] = configurationPathPrefix + strings.TrimPrefix(xpPath, sensitivePathPrefix)
+	}
+	vars := map[string]interface{}{
+		"APIVersion":             pg.pkg.Name(),
+		"Types":                  typesStr,
+		"ConfigurationType":      gen.ForProviderType.Obj().Name(),
+		"SensitiveFields":        sensitive,
+		"SingleNestedAttributes": cfg.SingleNestedAttributes,
+	}
+	if err := file.Write(filepath.Join(pg.APIDirectoryPath, "zz_providerconfig_types.go"), vars, os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot write provider config types file")
+	}
+
+	setupFile := wrapper.NewFile(filepath.Join(pg.ModulePath, "internal", "clients"), "clients", templates.SetupFnTemplate,
+		wrapper.WithGenStatement(GenStatement),
+		wrapper.WithHeaderPath(pg.LicenseHeaderPath),
+	)
+	vars = map[string]interface{}{
+		"APIPackageAlias": setupFile.Imports.UsePackage(pg.pkg.Path()),
+	}
+	return errors.Wrap(setupFile.Write(filepath.Join(pg.ClientsDirectoryPath, "zz_setup.go"), vars, os.ModePerm), "cannot write setup function file")
+}
@@ -33,6 +33,12 @@ type terraformedInput struct {
 	ParametersTypeName string
 }
 
+// previousVersion is a previous version of a hub resource.
+type previousVersion struct {
+	hub     *config.Resource
+	version config.PreviousVersion
+}
+
 // Run runs the Terrajet code generation pipelines.
 func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
 	// Note(turkenh): nolint reasoning - this is the main function of the code
@@ -43,6 +49,7 @@ func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
 	// An example entry in the tree would be:
 	// ec2.awsjet.crossplane.io -> v1alpha1 -> aws_vpc
 	resourcesGroups := map[string]map[string]map[string]*config.Resource{}
+	previousVersions := map[*config.Resource]previousVersion{}
 	for name, resource := range pc.Resources {
 		group := pc.RootGroup
 		if resource.ShortGroup != "" {
@@ -55,6 +62,36 @@ func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
 			resourcesGroups[group][resource.Version] = map[string]*config.Resource{}
 		}
 		resourcesGroups[group][resource.Version][name] = resource
+
+		// The previous versions are generated in their own version packages
+		// with the schema that they had before the fields were moved.
+		for _, v := range resource.PreviousVersions {
+			pr, err := newPreviousVersion(resource, v)
+			if err != nil {
+				panic(errors.Wrapf(err, "cannot build version %s of resource %s", v.Name, name))
+			}
+			if len(resourcesGroups[group][v.Name]) == 0 {
+				resourcesGroups[group][v.Name] = map[string]*config.Resource{}
+			}
+			resourcesGroups[group][v.Name][name] = pr
+			previousVersions[pr] = previousVersion{hub: resource, version: v}
+		}
+	}
+	// Data sources are generated in the same groups as the resources. They
+	// are keyed with a prefix since a data source usually has the same name
+	// as a resource.
+	for name, resource := range pc.DataSources {
+		group := pc.RootGroup
+		if resource.ShortGroup != "" {
+			group = strings.ToLower(resource.ShortGroup) + "." + pc.RootGroup
+		}
+		if len(resourcesGroups[group]) == 0 {
+			resourcesGroups[group] = map[string]map[string]*config.Resource{}
+		}
+		if len(resourcesGroups[group][resource.Version]) == 0 {
+			resourcesGroups[group][resource.Version] = map[string]*config.Resource{}
+		}
+		resourcesGroups[group][resource.Version]["data."+name] = resource
 	}
 
 	// Add ProviderConfig API package to the list of API version packages.
@@ -71,6 +108,8 @@ func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
 	for group, versions := range resourcesGroups {
 		for version, resources := range versions {
 			var tfResources []*terraformedInput
+			var hubs []*config.Resource
+			var spokes []*conversionInput
 			versionGen := NewVersionGenerator(rootDir, pc.ModulePath, group, version)
 			crdGen := NewCRDGenerator(versionGen.Package(), rootDir, pc.ShortName, group, version)
 			tfGen := NewTerraformedGenerator(versionGen.Package(), rootDir, group, version)
@@ -81,6 +120,19 @@ func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
 				if err != nil {
 					panic(errors.Wrapf(err, "cannot generate crd for resource %s", name))
 				}
+				// The previous versions are only converted from and to their
+				// hub versions, which are the ones that are reconciled.
+				if pv, ok := previousVersions[resources[name]]; ok {
+					moves, err := fieldMoves(pv.hub, resources[name], pv.version)
+					if err != nil {
+						panic(errors.Wrapf(err, "cannot generate conversion for resource %s", name))
+					}
+					spokes = append(spokes, &conversionInput{Resource: resources[name], Moves: moves})
+					continue
+				}
+				if len(resources[name].PreviousVersions) > 0 {
+					hubs = append(hubs, resources[name])
+				}
 				tfResources = append(tfResources, &terraformedInput{
 					Resource:           resources[name],
 					ParametersTypeName: paramTypeName,
@@ -93,8 +145,16 @@ func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
 				count++
 			}
 
-			if err := tfGen.Generate(tfResources, version); err != nil {
-				panic(errors.Wrapf(err, "cannot generate terraformed for resource %s", group))
+			if len(tfResources) > 0 {
+				if err := tfGen.Generate(tfResources, version); err != nil {
+					panic(errors.Wrapf(err, "cannot generate terraformed for resource %s", group))
+				}
+			}
+
+			if len(hubs) > 0 || len(spokes) > 0 {
+				if err := NewConversionGenerator(versionGen.Package(), rootDir, group, version).Generate(hubs, spokes, version); err != nil {
+					panic(errors.Wrapf(err, "cannot generate conversion methods for group %s", group))
+				}
 			}
 
 			if err := versionGen.Generate(); err != nil {
@@ -104,6 +164,15 @@ func Run(pc *config.Provider, rootDir string) { // nolint:gocyclo
 		}
 	}
 
+	if pc.GenerateProviderConfig {
+		if pc.ProviderConfig == nil || len(pc.BasePackages.APIVersion) == 0 {
+			panic(errors.New("provider schema and the base API package are required to generate the ProviderConfig"))
+		}
+		if err := NewProviderConfigGenerator(rootDir, pc.ModulePath, pc.BasePackages.APIVersion[0]).Generate(pc.ProviderConfig); err != nil {
+			panic(errors.Wrap(err, "cannot generate provider config"))
+		}
+	}
+
 	if err := NewRegisterGenerator(rootDir, pc.ModulePath).Generate(apiVersionPkgList); err != nil {
 		panic(errors.Wrap(err, "cannot generate register file"))
 	}
@@ -42,3 +42,18 @@ var RegisterTemplate string
 // SetupTemplate is populated with controller setup calls.
 //go:embed setup.go.tmpl
 var SetupTemplate string
+
+// ProviderConfigTypesTemplate is populated with the ProviderConfig spec
+// generated from the provider schema.
+//go:embed providerconfig_types.go.tmpl
+var ProviderConfigTypesTemplate string
+
+// SetupFnTemplate is populated with the default SetupFn that configures the
+// provider with the generated ProviderConfig spec.
+//go:embed setupfn.go.tmpl
+var SetupFnTemplate string
+
+// ConversionTemplate is populated with the methods that convert the previous
+// versions of the resources from and to their hub versions.
+//go:embed conversion.go.tmpl
+var ConversionTemplate string
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane/terrajet/pkg/config"
+)
+
+const (
+	// AnnotationKeyAsyncTimeout is the key of the annotation that overrides
+	// the deadline of the async operations of the resource. Its value should
+	// be a duration string, e.g. "2h".
+	AnnotationKeyAsyncTimeout = "terrajet.crossplane.io/async-timeout"
+
+	// AnnotationKeyApproveReplacement is the key of the annotation that
+	// approves the replacement of resources whose replacement policy is
+	// config.ReplacementPolicyRequireApproval. Its value should be "true".
+	AnnotationKeyApproveReplacement = "terrajet.crossplane.io/approve-replacement"
+
+	// AnnotationKeyManagementPolicy is the key of the annotation that
+	// overrides the management policy of the resource. Its value should be
+	// one of the config.ManagementPolicy values, e.g. "ObserveOnly".
+	AnnotationKeyManagementPolicy = "terrajet.crossplane.io/management-policy"
+)
+
+// GetManagementPolicy returns the management policy of the given resource,
+// which is the policy in its annotation if it's valid, or the given policy
+// of its kind otherwise.
+func GetManagementPolicy(o metav1.Object, policy config.ManagementPolicy) config.ManagementPolicy {
+	switch p := config.ManagementPolicy(o.GetAnnotations()[AnnotationKeyManagementPolicy]); p {
+	case config.ManagementPolicyFull, config.ManagementPolicyObserveOnly, config.ManagementPolicyImport:
+		return p
+	}
+	if policy == "" {
+		return config.ManagementPolicyFull
+	}
+	return policy
+}
+
+// IsReplacementAllowed returns whether the given resource is allowed to be
+// destroyed and re-created to apply a change in its configuration.
+func IsReplacementAllowed(o metav1.Object, policy config.ReplacementPolicy) bool {
+	switch policy {
+	case config.ReplacementPolicyAllow:
+		return true
+	case config.ReplacementPolicyRequireApproval:
+		return o.GetAnnotations()[AnnotationKeyApproveReplacement] == "true"
+	default:
+		return false
+	}
+}
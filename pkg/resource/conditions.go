@@ -28,12 +28,21 @@ import (
 const (
 	TypeLastAsyncOperation = "LastAsyncOperation"
 	TypeAsyncOperation     = "AsyncOperation"
+	TypeUpToDate           = "UpToDate"
 
 	ReasonApplyFailure   xpv1.ConditionReason = "ApplyFailure"
 	ReasonDestroyFailure xpv1.ConditionReason = "DestroyFailure"
+	ReasonInterrupted    xpv1.ConditionReason = "Interrupted"
+	ReasonProviderExited xpv1.ConditionReason = "ProviderExited"
 	ReasonSuccess        xpv1.ConditionReason = "Success"
 	ReasonOngoing        xpv1.ConditionReason = "Ongoing"
+	ReasonWaiting        xpv1.ConditionReason = "Waiting"
 	ReasonFinished       xpv1.ConditionReason = "Finished"
+	ReasonUpToDate       xpv1.ConditionReason = "UpToDate"
+	ReasonDrifted        xpv1.ConditionReason = "Drifted"
+
+	ReasonReplacementDenied      xpv1.ConditionReason = "ReplacementDenied"
+	ReasonReplacementNotApproved xpv1.ConditionReason = "ReplacementNotApproved"
 )
 
 // LastAsyncOperationCondition returns the condition depending on the content
@@ -48,19 +57,23 @@ func LastAsyncOperationCondition(err error) xpv1.Condition {
 			Reason:             ReasonSuccess,
 		}
 	case tferrors.IsApplyFailed(err):
+		return operationFailedCondition(ReasonApplyFailure, err)
+	case tferrors.IsDestroyFailed(err):
+		return operationFailedCondition(ReasonDestroyFailure, err)
+	case tferrors.IsOperationInterrupted(err):
 		return xpv1.Condition{
 			Type:               TypeLastAsyncOperation,
 			Status:             corev1.ConditionFalse,
 			LastTransitionTime: metav1.Now(),
-			Reason:             ReasonApplyFailure,
+			Reason:             ReasonInterrupted,
 			Message:            err.Error(),
 		}
-	case tferrors.IsDestroyFailed(err):
+	case tferrors.IsProviderRestarted(err):
 		return xpv1.Condition{
 			Type:               TypeLastAsyncOperation,
 			Status:             corev1.ConditionFalse,
 			LastTransitionTime: metav1.Now(),
-			Reason:             ReasonDestroyFailure,
+			Reason:             ReasonProviderExited,
 			Message:            err.Error(),
 		}
 	default:
@@ -74,6 +87,22 @@ func LastAsyncOperationCondition(err error) xpv1.Condition {
 	}
 }
 
+// operationFailedCondition returns the condition TypeLastAsyncOperation of a
+// failed operation. The reason is the category of the error if it's known,
+// see tferrors.CategoryOf, and the given reason otherwise.
+func operationFailedCondition(r xpv1.ConditionReason, err error) xpv1.Condition {
+	if c := tferrors.CategoryOf(err); c != tferrors.CategoryUnknown {
+		r = xpv1.ConditionReason(c)
+	}
+	return xpv1.Condition{
+		Type:               TypeLastAsyncOperation,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             r,
+		Message:            err.Error(),
+	}
+}
+
 // AsyncOperationFinishedCondition returns the condition TypeAsyncOperation Finished
 // if the operation was finished
 func AsyncOperationFinishedCondition() xpv1.Condition {
@@ -95,3 +124,48 @@ func AsyncOperationOngoingCondition() xpv1.Condition {
 		Reason:             ReasonOngoing,
 	}
 }
+
+// AsyncOperationWaitingCondition returns the condition TypeAsyncOperation
+// Waiting if the operation is waiting for its turn to start
+func AsyncOperationWaitingCondition() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeAsyncOperation,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonWaiting,
+	}
+}
+
+// UpToDateCondition returns the condition TypeUpToDate depending on the result
+// of the last plan. The given diff is a summary of the attributes that will be
+// changed by the next apply.
+func UpToDateCondition(upToDate bool, diff string) xpv1.Condition {
+	if upToDate {
+		return xpv1.Condition{
+			Type:               TypeUpToDate,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonUpToDate,
+		}
+	}
+	return xpv1.Condition{
+		Type:               TypeUpToDate,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDrifted,
+		Message:            diff,
+	}
+}
+
+// ReplacementBlockedCondition returns the condition TypeUpToDate with the given
+// reason when the resource cannot be updated because the update requires it to
+// be replaced.
+func ReplacementBlockedCondition(r xpv1.ConditionReason, msg string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeUpToDate,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             r,
+		Message:            msg,
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"strings"
+)
+
+const wildcard = "*"
+
+// UnwrapSingleNested converts the lists with at most one item at the given
+// paths of the given Terraform attributes to the objects that Terraform
+// expects for the single nested attributes. Paths are dot-separated attribute
+// names where "*" matches any key of a map, see
+// config.Resource.SingleNestedAttributes.
+func UnwrapSingleNested(attr map[string]interface{}, paths []string) {
+	// Children are converted before their parents so that they can still be
+	// reached through the lists.
+	for i := len(paths) - 1; i >= 0; i-- {
+		convertNested(attr, strings.Split(paths[i], "."), func(v interface{}) interface{} {
+			l, ok := v.([]interface{})
+			switch {
+			case !ok || len(l) > 1:
+				return v
+			case len(l) == 0:
+				return nil
+			default:
+				return l[0]
+			}
+		})
+	}
+}
+
+// WrapSingleNested converts the objects at the given paths of the given
+// Terraform attributes to lists with a single item, which is how the single
+// nested attributes are represented in the CRDs. It's the reverse of
+// UnwrapSingleNested.
+func WrapSingleNested(attr map[string]interface{}, paths []string) {
+	// Parents are converted before their children so that the children can
+	// be reached through the lists.
+	for _, p := range paths {
+		convertNested(attr, strings.Split(p, "."), func(v interface{}) interface{} {
+			if m, ok := v.(map[string]interface{}); ok {
+				return []interface{}{m}
+			}
+			return v
+		})
+	}
+}
+
+// convertNested replaces the values at the given path segments with the
+// result of the given function. Lists are traversed implicitly.
+func convertNested(v interface{}, segments []string, fn func(interface{}) interface{}) {
+	if len(segments) == 0 {
+		return
+	}
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			convertNested(e, segments, fn)
+		}
+	case map[string]interface{}:
+		switch {
+		case segments[0] == wildcard:
+			for _, e := range t {
+				convertNested(e, segments[1:], fn)
+			}
+		case len(segments) == 1:
+			if e, ok := t[segments[0]]; ok {
+				t[segments[0]] = fn(e)
+			}
+		default:
+			convertNested(t[segments[0]], segments[1:], fn)
+		}
+	}
+}
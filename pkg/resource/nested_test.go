@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnwrapSingleNested(t *testing.T) {
+	type args struct {
+		attr  map[string]interface{}
+		paths []string
+	}
+	type want struct {
+		attr map[string]interface{}
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoPaths": {
+			reason: "Attributes should not be changed if there are no single nested attributes.",
+			args: args{
+				attr: map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "c"}}},
+			},
+			want: want{
+				attr: map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "c"}}},
+			},
+		},
+		"Nested": {
+			reason: "Single nested attributes and their single nested children should be unwrapped.",
+			args: args{
+				attr: map[string]interface{}{
+					"a": []interface{}{map[string]interface{}{
+						"b": []interface{}{map[string]interface{}{"c": "d"}},
+					}},
+					"e": []interface{}{},
+				},
+				paths: []string{"a", "a.b", "e"},
+			},
+			want: want{
+				attr: map[string]interface{}{
+					"a": map[string]interface{}{
+						"b": map[string]interface{}{"c": "d"},
+					},
+					"e": nil,
+				},
+			},
+		},
+		"InListAndMap": {
+			reason: "Single nested attributes in lists and maps of objects should be unwrapped.",
+			args: args{
+				attr: map[string]interface{}{
+					"l": []interface{}{
+						map[string]interface{}{"s": []interface{}{map[string]interface{}{"c": "d"}}},
+						map[string]interface{}{"s": []interface{}{map[string]interface{}{"c": "e"}}},
+					},
+					"m": map[string]interface{}{
+						"key": map[string]interface{}{"s": []interface{}{map[string]interface{}{"c": "f"}}},
+					},
+				},
+				paths: []string{"l.s", "m.*.s"},
+			},
+			want: want{
+				attr: map[string]interface{}{
+					"l": []interface{}{
+						map[string]interface{}{"s": map[string]interface{}{"c": "d"}},
+						map[string]interface{}{"s": map[string]interface{}{"c": "e"}},
+					},
+					"m": map[string]interface{}{
+						"key": map[string]interface{}{"s": map[string]interface{}{"c": "f"}},
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			UnwrapSingleNested(tc.args.attr, tc.args.paths)
+			if diff := cmp.Diff(tc.want.attr, tc.args.attr); diff != "" {
+				t.Errorf("\n%s\nUnwrapSingleNested(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWrapSingleNested(t *testing.T) {
+	type args struct {
+		attr  map[string]interface{}
+		paths []string
+	}
+	type want struct {
+		attr map[string]interface{}
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Nested": {
+			reason: "Single nested attributes and their single nested children should be wrapped.",
+			args: args{
+				attr: map[string]interface{}{
+					"a": map[string]interface{}{
+						"b": map[string]interface{}{"c": "d"},
+					},
+					"e": nil,
+				},
+				paths: []string{"a", "a.b", "e"},
+			},
+			want: want{
+				attr: map[string]interface{}{
+					"a": []interface{}{map[string]interface{}{
+						"b": []interface{}{map[string]interface{}{"c": "d"}},
+					}},
+					"e": nil,
+				},
+			},
+		},
+		"Missing": {
+			reason: "Missing attributes should be ignored.",
+			args: args{
+				attr:  map[string]interface{}{"a": "b"},
+				paths: []string{"c", "c.d"},
+			},
+			want: want{
+				attr: map[string]interface{}{"a": "b"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			WrapSingleNested(tc.args.attr, tc.args.paths)
+			if diff := cmp.Diff(tc.want.attr, tc.args.attr); diff != "" {
+				t.Errorf("\n%s\nWrapSingleNested(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
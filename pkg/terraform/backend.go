@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	fileLock = ".terraform.lock.hcl"
+
+	// LabelKeyWorkspaceOwnerName is the label on the objects created by the
+	// WorkspaceBackends that holds the name of the resource the workspace
+	// belongs to.
+	LabelKeyWorkspaceOwnerName = "terrajet.crossplane.io/workspace-owner-name"
+	// LabelKeyWorkspaceOwnerUID is the label on the objects created by the
+	// WorkspaceBackends that holds the UID of the resource the workspace
+	// belongs to.
+	LabelKeyWorkspaceOwnerUID = "terrajet.crossplane.io/workspace-owner-uid"
+
+	fmtSecretName = "terrajet-workspace-%s"
+)
+
+// backendFiles are the files of a workspace that cannot be reproduced from
+// the resource and are kept in the WorkspaceBackend.
+var backendFiles = []string{fileState, fileLock}
+
+// WorkspaceBackend stores the files of the workspaces that cannot be
+// reproduced from the resources, i.e. the Terraform state and the dependency
+// lock file, outside of the local disk so that they survive the provider
+// being moved to another node.
+type WorkspaceBackend interface {
+	// Load returns the stored files of the workspace of the given object
+	// keyed by their names. It returns no files if nothing has been stored
+	// for the object yet.
+	Load(ctx context.Context, o metav1.Object) (map[string][]byte, error)
+	// Store replaces the stored files of the workspace of the given object.
+	Store(ctx context.Context, o metav1.Object, files map[string][]byte) error
+	// Delete removes the stored files of the workspace of the given object.
+	// It doesn't return an error if nothing has been stored.
+	Delete(ctx context.Context, o metav1.Object) error
+}
+
+// NewSecretBackend returns a new SecretBackend.
+func NewSecretBackend(kube client.Client, namespace string) *SecretBackend {
+	return &SecretBackend{
+		kube:      kube,
+		applier:   xpresource.NewAPIPatchingApplicator(kube),
+		namespace: namespace,
+	}
+}
+
+// SecretBackend is a WorkspaceBackend that stores the files of every
+// workspace in a Kubernetes Secret in the given namespace. Please note that
+// the size of a Secret is limited to 1MiB.
+type SecretBackend struct {
+	kube      client.Client
+	applier   xpresource.Applicator
+	namespace string
+}
+
+// Load returns the data of the Secret of the workspace.
+func (sb *SecretBackend) Load(ctx context.Context, o metav1.Object) (map[string][]byte, error) {
+	s := &corev1.Secret{}
+	err := sb.kube.Get(ctx, types.NamespacedName{Namespace: sb.namespace, Name: secretName(o)}, s)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return s.Data, errors.Wrap(err, "cannot get workspace secret")
+}
+
+// Store creates or updates the Secret of the workspace with the given files.
+func (sb *SecretBackend) Store(ctx context.Context, o metav1.Object, files map[string][]byte) error {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(o),
+			Namespace: sb.namespace,
+			Labels: map[string]string{
+				LabelKeyWorkspaceOwnerName: o.GetName(),
+				LabelKeyWorkspaceOwnerUID:  string(o.GetUID()),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: files,
+	}
+	return errors.Wrap(sb.applier.Apply(ctx, s), "cannot apply workspace secret")
+}
+
+// Delete deletes the Secret of the workspace.
+func (sb *SecretBackend) Delete(ctx context.Context, o metav1.Object) error {
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(o),
+			Namespace: sb.namespace,
+		},
+	}
+	return errors.Wrap(xpresource.IgnoreNotFound(sb.kube.Delete(ctx, s)), "cannot delete workspace secret")
+}
+
+func secretName(o metav1.Object) string {
+	return fmt.Sprintf(fmtSecretName, o.GetUID())
+}
+
+// NewFsBackend returns a new FsBackend.
+func NewFsBackend(fs afero.Fs, root string) *FsBackend {
+	return &FsBackend{
+		fs:   afero.Afero{Fs: fs},
+		root: root,
+	}
+}
+
+// FsBackend is a WorkspaceBackend that stores the files of every workspace
+// in a directory named after the UID of its object under the given root
+// directory. The root is usually where an object storage bucket is mounted.
+type FsBackend struct {
+	fs   afero.Afero
+	root string
+}
+
+// Load reads the files in the directory of the workspace.
+func (fb *FsBackend) Load(_ context.Context, o metav1.Object) (map[string][]byte, error) {
+	return readBackendFiles(fb.fs, filepath.Join(fb.root, string(o.GetUID())))
+}
+
+// Store writes the given files to the directory of the workspace.
+func (fb *FsBackend) Store(_ context.Context, o metav1.Object, files map[string][]byte) error {
+	dir := filepath.Join(fb.root, string(o.GetUID()))
+	if err := fb.fs.MkdirAll(dir, os.ModePerm); err != nil {
+		return errors.Wrap(err, "cannot create workspace directory")
+	}
+	for f, b := range files {
+		if err := fb.fs.WriteFile(filepath.Join(dir, f), b, 0600); err != nil {
+			return errors.Wrapf(err, "cannot write %s", f)
+		}
+	}
+	return nil
+}
+
+// Delete removes the directory of the workspace.
+func (fb *FsBackend) Delete(_ context.Context, o metav1.Object) error {
+	return errors.Wrap(fb.fs.RemoveAll(filepath.Join(fb.root, string(o.GetUID()))), "cannot remove workspace directory")
+}
+
+// readBackendFiles returns the files in the given workspace directory that
+// are kept in the WorkspaceBackend.
+func readBackendFiles(fs afero.Afero, dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, f := range backendFiles {
+		b, err := fs.ReadFile(filepath.Join(dir, f))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s", f)
+		}
+		files[f] = b
+	}
+	return files, nil
+}
+
+func equalFiles(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for f, c := range a {
+		if d, ok := b[f]; !ok || !bytes.Equal(c, d) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var owner = &metav1.ObjectMeta{Name: "some-name", UID: "some-uid"}
+
+func TestSecretBackendLoad(t *testing.T) {
+	type args struct {
+		kube client.Client
+	}
+	type want struct {
+		files map[string][]byte
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NotFound": {
+			reason: "No files should be returned if the Secret doesn't exist.",
+			args: args{
+				kube: &test.MockClient{
+					MockGet: test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+				},
+			},
+		},
+		"Found": {
+			reason: "The data of the Secret of the workspace should be returned.",
+			args: args{
+				kube: &test.MockClient{
+					MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+						if key.Name != "terrajet-workspace-some-uid" || key.Namespace != "crossplane-system" {
+							return errors.Errorf("unexpected key %s", key)
+						}
+						obj.(*corev1.Secret).Data = map[string][]byte{fileState: []byte(tfstate)}
+						return nil
+					},
+				},
+			},
+			want: want{
+				files: map[string][]byte{fileState: []byte(tfstate)},
+			},
+		},
+		"Error": {
+			reason: "Errors other than not found should be returned.",
+			args: args{
+				kube: &test.MockClient{
+					MockGet: test.NewMockGetFn(errBoom),
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, "cannot get workspace secret"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			files, err := NewSecretBackend(tc.args.kube, "crossplane-system").Load(context.TODO(), owner)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nLoad(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.files, files); diff != "" {
+				t.Errorf("\n%s\nLoad(...): -want files, +got files:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSecretBackendDelete(t *testing.T) {
+	type args struct {
+		kube client.Client
+	}
+	type want struct {
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NotFound": {
+			reason: "It should not be an error if the Secret doesn't exist.",
+			args: args{
+				kube: &test.MockClient{
+					MockDelete: test.NewMockDeleteFn(kerrors.NewNotFound(schema.GroupResource{}, "")),
+				},
+			},
+		},
+		"Error": {
+			reason: "Errors other than not found should be returned.",
+			args: args{
+				kube: &test.MockClient{
+					MockDelete: test.NewMockDeleteFn(errBoom),
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, "cannot delete workspace secret"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := NewSecretBackend(tc.args.kube, "crossplane-system").Delete(context.TODO(), owner)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nDelete(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFsBackend(t *testing.T) {
+	files := map[string][]byte{
+		fileState: []byte(tfstate),
+		fileLock:  []byte("lock"),
+	}
+	fb := NewFsBackend(afero.NewMemMapFs(), "/backend")
+	if err := fb.Store(context.TODO(), owner, files); err != nil {
+		t.Fatalf("Store(...): %v", err)
+	}
+	got, err := fb.Load(context.TODO(), owner)
+	if err != nil {
+		t.Fatalf("Load(...): %v", err)
+	}
+	if diff := cmp.Diff(files, got); diff != "" {
+		t.Errorf("Load(...): -want files, +got files:\n%s", diff)
+	}
+	if err := fb.Delete(context.TODO(), owner); err != nil {
+		t.Fatalf("Delete(...): %v", err)
+	}
+	got, err = fb.Load(context.TODO(), owner)
+	if err != nil {
+		t.Fatalf("Load(...): %v", err)
+	}
+	if diff := cmp.Diff(map[string][]byte{}, got); diff != "" {
+		t.Errorf("Load(...) after Delete(...): -want files, +got files:\n%s", diff)
+	}
+}
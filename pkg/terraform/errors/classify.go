@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Category is the category of a Terraform error, which tells whether and
+// when the failed operation is worth retrying.
+type Category string
+
+// Error categories.
+const (
+	// CategoryThrottled is the category of the errors caused by the rate
+	// limits of the Cloud API.
+	CategoryThrottled Category = "Throttled"
+	// CategoryCredentials is the category of the errors caused by invalid
+	// credentials or missing permissions.
+	CategoryCredentials Category = "CredentialError"
+	// CategoryConflict is the category of the errors caused by a conflict
+	// with an existing external resource.
+	CategoryConflict Category = "Conflict"
+	// CategoryValidation is the category of the errors caused by an invalid
+	// configuration of the resource.
+	CategoryValidation Category = "ValidationError"
+	// CategoryTransient is the category of the errors caused by transient
+	// network failures.
+	CategoryTransient Category = "TransientNetworkError"
+	// CategoryUnknown is the category of the errors that don't match any
+	// pattern.
+	CategoryUnknown Category = "Unknown"
+)
+
+// Backoff returns how long a failed operation of the category should wait
+// before it's retried. The operations that failed due to transient or unknown
+// errors are retried right away. Only the async operations are backed off by
+// category, see config.Resource.UseAsync.
+func (c Category) Backoff() time.Duration {
+	switch c {
+	case CategoryThrottled, CategoryValidation:
+		return time.Minute
+	case CategoryCredentials:
+		return 2 * time.Minute
+	case CategoryConflict:
+		return 30 * time.Second
+	default:
+		return 0
+	}
+}
+
+// Pattern is a regular expression that matches the summary and detail of
+// the Terraform diagnostics of a Category.
+type Pattern struct {
+	Category Category
+	Regexp   *regexp.Regexp
+}
+
+// NewPattern returns a new Pattern of the given Category. It panics if the
+// given expression cannot be compiled.
+func NewPattern(c Category, expr string) Pattern {
+	return Pattern{Category: c, Regexp: regexp.MustCompile(expr)}
+}
+
+// defaultPatterns are the patterns of the errors that are common across the
+// Terraform providers. The first matching pattern wins, so the more specific
+// categories come first, e.g. an invalid token is a credential error rather
+// than a validation error.
+var defaultPatterns = []Pattern{
+	NewPattern(CategoryThrottled, `(?i)throttl|rate exceeded|rate ?limit|too many requests|request ?limit ?exceeded|slow ?down|status ?code:? ?429`),
+	NewPattern(CategoryCredentials, `(?i)unauthori[sz]ed|not authori[sz]ed|access ?denied|forbidden|permission denied|authentication failed|invalid (client )?token|expired ?token|no valid credential|status ?code:? ?40[13]`),
+	NewPattern(CategoryTransient, `(?i)connection (reset|refused)|i/o timeout|tls handshake timeout|no such host|temporary failure|unexpected eof|service unavailable|bad gateway|gateway timeout|status ?code:? ?50[234]`),
+	NewPattern(CategoryConflict, `(?i)already exists|conflict|resource ?in ?use|status ?code:? ?409`),
+	NewPattern(CategoryValidation, `(?i)invalid (value|parameter|argument|configuration|request)|validation ?(error|exception|failed)|unsupported argument|missing required argument|malformed|status ?code:? ?400`),
+}
+
+// Classifier sorts the Terraform errors into categories by matching their
+// diagnostics against patterns.
+type Classifier struct {
+	patterns []Pattern
+}
+
+// NewClassifier returns a new Classifier that matches the given patterns
+// before the default ones, so that the providers can classify the errors of
+// their Cloud APIs.
+func NewClassifier(patterns ...Pattern) *Classifier {
+	return &Classifier{patterns: append(append(make([]Pattern, 0, len(patterns)+len(defaultPatterns)), patterns...), defaultPatterns...)}
+}
+
+// Classify returns the Category of the given error. The summaries and
+// details of its Terraform diagnostics are matched, or its message if it
+// has none. A nil Classifier uses the default patterns.
+func (c *Classifier) Classify(err error) Category {
+	if err == nil {
+		return CategoryUnknown
+	}
+	patterns := defaultPatterns
+	if c != nil {
+		patterns = c.patterns
+	}
+	texts := []string{err.Error()}
+	var d interface{ Diagnostics() []LogDiagnostic }
+	if errors.As(err, &d) && len(d.Diagnostics()) > 0 {
+		texts = make([]string, 0, len(d.Diagnostics()))
+		for _, diag := range d.Diagnostics() {
+			texts = append(texts, diag.Summary+": "+diag.Detail)
+		}
+	}
+	for _, t := range texts {
+		for _, p := range patterns {
+			if p.Regexp.MatchString(t) {
+				return p.Category
+			}
+		}
+	}
+	return CategoryUnknown
+}
+
+type classified struct {
+	error
+	category Category
+}
+
+func (c *classified) Unwrap() error {
+	return c.error
+}
+
+// WithCategory returns the given error annotated with the given Category,
+// e.g. the one assigned by a Classifier with the patterns of a provider.
+func WithCategory(err error, c Category) error {
+	if err == nil {
+		return nil
+	}
+	return &classified{error: err, category: c}
+}
+
+// CategoryOf returns the Category that the given error is annotated with,
+// or the one assigned by the default patterns if it's not annotated.
+func CategoryOf(err error) Category {
+	c := &classified{}
+	if errors.As(err, &c) {
+		return c.category
+	}
+	return (*Classifier)(nil).Classify(err)
+}
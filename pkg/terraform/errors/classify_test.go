@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+func diagnosticLog(summary, detail string) []byte {
+	return []byte(fmt.Sprintf(`{"@level":"error","@message":"Error: %s","diagnostic":{"severity":"error","summary":%q,"detail":%q},"type":"diagnostic"}`, summary, summary, detail))
+}
+
+func TestClassify(t *testing.T) {
+	type args struct {
+		patterns []Pattern
+		err      error
+	}
+	type want struct {
+		c Category
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Throttled": {
+			reason: "Rate limit errors should be classified as throttled.",
+			args: args{
+				err: NewApplyFailed(diagnosticLog("error creating Cool Resource", "ThrottlingException: Rate exceeded")),
+			},
+			want: want{
+				c: CategoryThrottled,
+			},
+		},
+		"Credentials": {
+			reason: "Permission errors should be classified as credential errors.",
+			args: args{
+				err: NewApplyFailed(diagnosticLog("error creating Cool Resource", "AccessDenied: User is not authorized to perform the action")),
+			},
+			want: want{
+				c: CategoryCredentials,
+			},
+		},
+		"Conflict": {
+			reason: "Errors about existing resources should be classified as conflicts.",
+			args: args{
+				err: NewApplyFailed(diagnosticLog("error creating Cool Resource", "a resource with the ID \"some-id\" already exists")),
+			},
+			want: want{
+				c: CategoryConflict,
+			},
+		},
+		"Validation": {
+			reason: "Configuration errors should be classified as validation errors.",
+			args: args{
+				err: NewApplyFailed(errorLog),
+			},
+			want: want{
+				c: CategoryValidation,
+			},
+		},
+		"Transient": {
+			reason: "Network errors should be classified as transient.",
+			args: args{
+				err: NewDestroyFailed(diagnosticLog("error deleting Cool Resource", "dial tcp: lookup cool.api: no such host")),
+			},
+			want: want{
+				c: CategoryTransient,
+			},
+		},
+		"Unknown": {
+			reason: "Errors that don't match any pattern should be unknown.",
+			args: args{
+				err: NewApplyFailed(diagnosticLog("error creating Cool Resource", "something unexpected happened")),
+			},
+			want: want{
+				c: CategoryUnknown,
+			},
+		},
+		"NoDiagnostics": {
+			reason: "The message of the errors without diagnostics should be matched.",
+			args: args{
+				err: errors.New("429 Too Many Requests"),
+			},
+			want: want{
+				c: CategoryThrottled,
+			},
+		},
+		"ProviderPattern": {
+			reason: "The patterns of the provider should be matched before the default ones.",
+			args: args{
+				patterns: []Pattern{NewPattern(CategoryThrottled, "CoolAPIBusy")},
+				err:      NewApplyFailed(diagnosticLog("error creating Cool Resource", "CoolAPIBusy: invalid request")),
+			},
+			want: want{
+				c: CategoryThrottled,
+			},
+		},
+		"NilError": {
+			reason: "A nil error should be unknown.",
+			want: want{
+				c: CategoryUnknown,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := NewClassifier(tc.args.patterns...).Classify(tc.args.err)
+			if diff := cmp.Diff(tc.want.c, got); diff != "" {
+				t.Errorf("\n%s\nClassify(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	type args struct {
+		err error
+	}
+	type want struct {
+		c        Category
+		isFailed bool
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Annotated": {
+			reason: "The category the error is annotated with should be returned.",
+			args: args{
+				err: WithCategory(NewApplyFailed(errorLog), CategoryConflict),
+			},
+			want: want{
+				c:        CategoryConflict,
+				isFailed: true,
+			},
+		},
+		"NotAnnotated": {
+			reason: "The category should be assigned by the default patterns if the error is not annotated.",
+			args: args{
+				err: NewApplyFailed(errorLog),
+			},
+			want: want{
+				c:        CategoryValidation,
+				isFailed: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want.c, CategoryOf(tc.args.err)); diff != "" {
+				t.Errorf("\n%s\nCategoryOf(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.isFailed, IsApplyFailed(tc.args.err)); diff != "" {
+				t.Errorf("\n%s\nIsApplyFailed(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package errors
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
@@ -30,6 +31,8 @@ const (
 
 type tfError struct {
 	message string
+	// diagnostics are the error diagnostics reported by Terraform CLI.
+	diagnostics []LogDiagnostic
 }
 
 type applyFailed struct {
@@ -61,6 +64,11 @@ func (t *tfError) Error() string {
 	return t.message
 }
 
+// Diagnostics returns the error diagnostics reported by Terraform CLI.
+func (t *tfError) Diagnostics() []LogDiagnostic {
+	return t.diagnostics
+}
+
 func newTFError(message string, logs []byte) (string, *tfError) {
 	tfError := &tfError{
 		message: message,
@@ -79,6 +87,7 @@ func newTFError(message string, logs []byte) (string, *tfError) {
 		}
 		m := l.Message
 		if l.Diagnostic.Severity == levelError && l.Diagnostic.Summary != "" {
+			tfError.diagnostics = append(tfError.diagnostics, l.Diagnostic)
 			m = fmt.Sprintf("%s: %s", l.Diagnostic.Summary, l.Diagnostic.Detail)
 			if len(l.Diagnostic.Range.FileName) != 0 {
 				m = m + ": File name: " + l.Diagnostic.Range.FileName
@@ -182,3 +191,52 @@ func IsPlanFailed(err error) bool {
 	r := &planFailed{}
 	return errors.As(err, &r)
 }
+
+type operationInterrupted struct {
+	*tfError
+}
+
+// NewOperationInterrupted returns a new error reporting that the given
+// operation that started at the given time was interrupted before it could
+// complete, e.g. because the provider was restarted.
+func NewOperationInterrupted(op string, start time.Time) error {
+	return &operationInterrupted{tfError: &tfError{
+		message: fmt.Sprintf("%s operation that started at %s was interrupted before it could complete", op, start.String()),
+	}}
+}
+
+// IsOperationInterrupted returns whether error is due to an operation that
+// was interrupted before it could complete.
+func IsOperationInterrupted(err error) bool {
+	r := &operationInterrupted{}
+	return errors.As(err, &r)
+}
+
+type providerRestarted struct {
+	*tfError
+	cause error
+}
+
+func (p *providerRestarted) Unwrap() error {
+	return p.cause
+}
+
+// NewProviderRestarted returns a new error reporting that the given operation
+// failed because the native provider process it was attached to exited while
+// the operation was running. The diagnostics in the given logs of the
+// operation are reported, and the error it failed with is kept as the cause.
+func NewProviderRestarted(op string, logs []byte, cause error) error {
+	parseError, tfError := newTFError(fmt.Sprintf("%s operation failed because the native provider process exited while it was running, it will be retried", op), logs)
+	result := &providerRestarted{tfError: tfError, cause: cause}
+	if parseError == "" {
+		return result
+	}
+	return errors.WithMessage(result, parseError)
+}
+
+// IsProviderRestarted returns whether error is due to an operation that
+// failed because the native provider process exited while it was running.
+func IsProviderRestarted(err error) bool {
+	r := &providerRestarted{}
+	return errors.As(err, &r)
+}
@@ -18,6 +18,7 @@ package errors
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
@@ -333,3 +334,99 @@ func TestNewPlanFailed(t *testing.T) {
 		})
 	}
 }
+
+func TestIsOperationInterrupted(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := map[string]struct {
+		args args
+		want bool
+	}{
+		"NilError": {
+			args: args{},
+			want: false,
+		},
+		"NonInterruptedError": {
+			args: args{
+				err: errorBoom,
+			},
+			want: false,
+		},
+		"InterruptedError": {
+			args: args{
+				err: NewOperationInterrupted("apply", time.Time{}),
+			},
+			want: true,
+		},
+		"WrappedInterruptedError": {
+			args: args{
+				err: errors.Wrap(NewOperationInterrupted("apply", time.Time{}), "wrapped"),
+			},
+			want: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsOperationInterrupted(tt.args.err); got != tt.want {
+				t.Errorf("IsOperationInterrupted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsProviderRestarted(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := map[string]struct {
+		args args
+		want bool
+	}{
+		"NilError": {
+			args: args{},
+			want: false,
+		},
+		"NonRestartedError": {
+			args: args{
+				err: errorBoom,
+			},
+			want: false,
+		},
+		"RestartedError": {
+			args: args{
+				err: NewProviderRestarted("apply", nil, errorBoom),
+			},
+			want: true,
+		},
+		"RestartedErrorWithLogs": {
+			args: args{
+				err: NewProviderRestarted("apply", []byte(`{"@level": "error", "@message": "Error: connection reset", "diagnostic": {"severity": "error", "summary": "connection reset"}}`), errorBoom),
+			},
+			want: true,
+		},
+		"WrappedRestartedError": {
+			args: args{
+				err: errors.Wrap(NewProviderRestarted("apply", nil, errorBoom), "wrapped"),
+			},
+			want: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := IsProviderRestarted(tt.args.err); got != tt.want {
+				t.Errorf("IsProviderRestarted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderRestartedCause(t *testing.T) {
+	err := NewProviderRestarted("apply", []byte(`{"@level": "error", "@message": "Error: connection reset", "diagnostic": {"severity": "error", "summary": "connection reset", "detail": "read: connection reset by peer"}}`), errorBoom)
+	if !errors.Is(err, errorBoom) {
+		t.Errorf("NewProviderRestarted(...): the error should wrap its cause, got %v", err)
+	}
+	if got := CategoryOf(err); got != CategoryTransient {
+		t.Errorf("NewProviderRestarted(...): the diagnostics should be classified, want %s, got %s", CategoryTransient, got)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake contains fake implementations of the interfaces in the
+// terraform package to be used in tests.
+package fake
+
+import (
+	"context"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Backend is an in-memory WorkspaceBackend.
+type Backend struct {
+	// Files are the stored files of the workspaces keyed by the UIDs of
+	// their objects.
+	Files map[types.UID]map[string][]byte
+	// Err is returned by all calls if set.
+	Err error
+
+	mu sync.Mutex
+}
+
+// NewBackend returns a new empty Backend.
+func NewBackend() *Backend {
+	return &Backend{Files: map[types.UID]map[string][]byte{}}
+}
+
+// Load returns a copy of the stored files.
+func (b *Backend) Load(_ context.Context, o metav1.Object) (map[string][]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Err != nil {
+		return nil, b.Err
+	}
+	return copyFiles(b.Files[o.GetUID()]), nil
+}
+
+// Store replaces the stored files with a copy of the given files.
+func (b *Backend) Store(_ context.Context, o metav1.Object, files map[string][]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Err != nil {
+		return b.Err
+	}
+	if b.Files == nil {
+		b.Files = map[types.UID]map[string][]byte{}
+	}
+	b.Files[o.GetUID()] = copyFiles(files)
+	return nil
+}
+
+// Delete removes the stored files.
+func (b *Backend) Delete(_ context.Context, o metav1.Object) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.Err != nil {
+		return b.Err
+	}
+	delete(b.Files, o.GetUID())
+	return nil
+}
+
+func copyFiles(files map[string][]byte) map[string][]byte {
+	if files == nil {
+		return nil
+	}
+	c := make(map[string][]byte, len(files))
+	for f, d := range files {
+		c[f] = append([]byte(nil), d...)
+	}
+	return c
+}
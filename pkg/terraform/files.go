@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
@@ -71,6 +72,10 @@ func NewFileProducer(ctx context.Context, client resource.SecretClient, dir stri
 		return nil, errors.Wrap(err, "cannot get sensitive observation")
 	}
 	fp.observation = obs
+	// The single nested attributes are lists in the CRD but objects in
+	// Terraform.
+	resource.UnwrapSingleNested(fp.parameters, cfg.SingleNestedAttributes)
+	resource.UnwrapSingleNested(fp.observation, cfg.SingleNestedAttributes)
 
 	return fp, nil
 }
@@ -91,31 +96,14 @@ type FileProducer struct {
 // WriteTFState writes the Terraform state that should exist in the filesystem to
 // start any Terraform operation.
 func (fp *FileProducer) WriteTFState(ctx context.Context) error {
-	base := make(map[string]interface{})
-	// NOTE(muvaf): Since we try to produce the current state, observation
-	// takes precedence over parameters.
-	for k, v := range fp.parameters {
-		base[k] = v
-	}
-	for k, v := range fp.observation {
-		base[k] = v
-	}
-	id, err := fp.Config.ExternalName.GetIDFn(ctx, meta.GetExternalName(fp.Resource), fp.parameters, fp.Setup.Configuration)
+	base, privateRaw, err := fp.instanceState(ctx)
 	if err != nil {
-		return errors.Wrap(err, "cannot get id")
+		return err
 	}
-	base["id"] = id
 	attr, err := json.JSParser.Marshal(base)
 	if err != nil {
 		return errors.Wrap(err, "cannot marshal produced state attributes")
 	}
-	var privateRaw []byte
-	if pr, ok := fp.Resource.GetAnnotations()[resource.AnnotationKeyPrivateRawAttribute]; ok {
-		privateRaw = []byte(pr)
-	}
-	if privateRaw, err = insertTimeoutsMeta(privateRaw, timeouts(fp.Config.OperationTimeouts)); err != nil {
-		return errors.Wrap(err, "cannot insert timeouts metadata to private raw")
-	}
 	s := json.NewStateV4()
 	s.TerraformVersion = fp.Setup.Version
 	s.Lineage = string(fp.Resource.GetUID())
@@ -144,13 +132,46 @@ func (fp *FileProducer) WriteTFState(ctx context.Context) error {
 	return errors.Wrap(fp.fs.WriteFile(filepath.Join(fp.Dir, "terraform.tfstate"), rawState, 0600), "cannot write tfstate file")
 }
 
+// instanceState returns the attributes and the private data of the Terraform
+// state reproduced from the resource.
+func (fp *FileProducer) instanceState(ctx context.Context) (map[string]interface{}, []byte, error) {
+	base := make(map[string]interface{})
+	// NOTE(muvaf): Since we try to produce the current state, observation
+	// takes precedence over parameters.
+	for k, v := range fp.parameters {
+		base[k] = v
+	}
+	for k, v := range fp.observation {
+		base[k] = v
+	}
+	id, err := fp.Config.ExternalName.GetIDFn(ctx, meta.GetExternalName(fp.Resource), fp.parameters, fp.Setup.Configuration)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "cannot get id")
+	}
+	base["id"] = id
+	var privateRaw []byte
+	if pr, ok := fp.Resource.GetAnnotations()[resource.AnnotationKeyPrivateRawAttribute]; ok {
+		privateRaw = []byte(pr)
+	}
+	if privateRaw, err = insertTimeoutsMeta(privateRaw, timeouts(fp.Config.OperationTimeouts)); err != nil {
+		return nil, nil, errors.Wrap(err, "cannot insert timeouts metadata to private raw")
+	}
+	return base, privateRaw, nil
+}
+
 // WriteMainTF writes the content main configuration file that has the desired
 // state configuration for Terraform.
 func (fp *FileProducer) WriteMainTF() error {
-	// If the resource is in a deletion process, we need to remove the deletion
-	// protection.
+	if fp.Config.DataSource {
+		return fp.writeMainTF("data")
+	}
+	// If the resource is in a deletion process or it's allowed to be replaced,
+	// we need to remove the deletion protection. The external resources of
+	// the resources deleted with the Orphan policy are never destroyed.
+	deleted := meta.WasDeleted(fp.Resource)
+	orphaned := deleted && fp.Resource.GetDeletionPolicy() == xpv1.DeletionOrphan
 	fp.parameters["lifecycle"] = map[string]bool{
-		"prevent_destroy": !meta.WasDeleted(fp.Resource),
+		"prevent_destroy": orphaned || (!deleted && !resource.IsReplacementAllowed(fp.Resource, fp.Config.ReplacementPolicy)),
 	}
 
 	// Add operation timeouts if any timeout configured for the resource
@@ -158,6 +179,12 @@ func (fp *FileProducer) WriteMainTF() error {
 		fp.parameters["timeouts"] = tp
 	}
 
+	return fp.writeMainTF("resource")
+}
+
+// writeMainTF writes the main configuration file where the parameters are
+// configured in a block of the given kind, i.e. "resource" or "data".
+func (fp *FileProducer) writeMainTF(block string) error {
 	// Note(turkenh): To use third party providers, we need to configure
 	// provider name in required_providers.
 	providerSource := strings.Split(fp.Setup.Requirement.Source, "/")
@@ -173,7 +200,7 @@ func (fp *FileProducer) WriteMainTF() error {
 		"provider": map[string]interface{}{
 			providerSource[len(providerSource)-1]: fp.Setup.Configuration,
 		},
-		"resource": map[string]interface{}{
+		block: map[string]interface{}{
 			fp.Resource.GetTerraformResourceType(): map[string]interface{}{
 				fp.Resource.GetName(): fp.parameters,
 			},
@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
@@ -212,6 +213,58 @@ func TestWriteMainTF(t *testing.T) {
 				maintf: `{"provider":{"provider-test":null},"resource":{"":{"":{"lifecycle":{"prevent_destroy":true},"name":"some-id","param":"paramval"}}},"terraform":{"required_providers":{"provider-test":{"source":"hashicorp/provider-test","version":"1.2.3"}}}}`,
 			},
 		},
+		"Orphaned": {
+			reason: "The external resource of a resource deleted with the Orphan policy should be protected against destruction",
+			args: args{
+				tr: &fake.Terraformed{
+					Managed: xpfake.Managed{
+						ObjectMeta: metav1.ObjectMeta{
+							Annotations: map[string]string{
+								meta.AnnotationKeyExternalName: "some-id",
+							},
+							DeletionTimestamp: &metav1.Time{Time: time.Now()},
+						},
+						Orphanable: xpfake.Orphanable{Policy: xpv1.DeletionOrphan},
+					},
+					Parameterizable: fake.Parameterizable{Parameters: map[string]interface{}{
+						"param": "paramval",
+					}},
+				},
+				cfg: config.DefaultResource("terrajet_resource", nil),
+				s: Setup{
+					Requirement: ProviderRequirement{
+						Source:  "hashicorp/provider-test",
+						Version: "1.2.3",
+					},
+				},
+			},
+			want: want{
+				maintf: `{"provider":{"provider-test":null},"resource":{"":{"":{"lifecycle":{"prevent_destroy":true},"name":"some-id","param":"paramval"}}},"terraform":{"required_providers":{"provider-test":{"source":"hashicorp/provider-test","version":"1.2.3"}}}}`,
+			},
+		},
+		"DataSource": {
+			reason: "Data sources should be written as data blocks without a lifecycle",
+			args: args{
+				tr: &fake.Terraformed{
+					Parameterizable: fake.Parameterizable{Parameters: map[string]interface{}{
+						"param": "paramval",
+					}},
+				},
+				cfg: config.DefaultResource("terrajet_resource", nil, func(r *config.Resource) {
+					r.DataSource = true
+					r.ExternalName = config.IdentifierFromProvider
+				}),
+				s: Setup{
+					Requirement: ProviderRequirement{
+						Source:  "hashicorp/provider-test",
+						Version: "1.2.3",
+					},
+				},
+			},
+			want: want{
+				maintf: `{"data":{"":{"":{"param":"paramval"}}},"provider":{"provider-test":null},"terraform":{"required_providers":{"provider-test":{"source":"hashicorp/provider-test","version":"1.2.3"}}}}`,
+			},
+		},
 		"Custom Source": {
 			reason: "Custom source like my-company/namespace/provider-test resources should be able to write everything it has into maintf file",
 			args: args{
@@ -19,12 +19,21 @@ package terraform
 import (
 	"context"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
 )
 
 const (
 	errRemoveWorkspace = "cannot remove workspace from the store"
+	errDetachWorkspace = "cannot detach workspace from the external resource"
+
+	reasonDetached event.Reason = "Detached"
 )
 
 // StoreCleaner is the interface that the workspace finalizer needs to work with.
@@ -32,14 +41,38 @@ type StoreCleaner interface {
 	Remove(obj xpresource.Object) error
 }
 
+// StoreDetacher is implemented by the stores that can detach the workspace
+// of a resource from its external resource, i.e. drop the workspace without
+// destroying the external resource, once it's safe to do so.
+type StoreDetacher interface {
+	Detach(ctx context.Context, obj xpresource.Object) error
+}
+
 // TODO(muvaf): A FinalizerChain in crossplane-runtime?
 
+// WorkspaceFinalizerOption lets you configure a WorkspaceFinalizer.
+type WorkspaceFinalizerOption func(wf *WorkspaceFinalizer)
+
+// WithFinalizerEventRecorder configures the event recorder that the
+// WorkspaceFinalizer uses to record the last observed state of the external
+// resources it detaches.
+func WithFinalizerEventRecorder(r event.Recorder) WorkspaceFinalizerOption {
+	return func(wf *WorkspaceFinalizer) {
+		wf.eventRecorder = r
+	}
+}
+
 // NewWorkspaceFinalizer returns a new WorkspaceFinalizer.
-func NewWorkspaceFinalizer(ws StoreCleaner, af xpresource.Finalizer) *WorkspaceFinalizer {
-	return &WorkspaceFinalizer{
-		Finalizer: af,
-		Store:     ws,
+func NewWorkspaceFinalizer(ws StoreCleaner, af xpresource.Finalizer, opts ...WorkspaceFinalizerOption) *WorkspaceFinalizer {
+	wf := &WorkspaceFinalizer{
+		Finalizer:     af,
+		Store:         ws,
+		eventRecorder: event.NewNopRecorder(),
 	}
+	for _, o := range opts {
+		o(wf)
+	}
+	return wf
 }
 
 // WorkspaceFinalizer removes the workspace from the workspace store and only
@@ -47,6 +80,8 @@ func NewWorkspaceFinalizer(ws StoreCleaner, af xpresource.Finalizer) *WorkspaceF
 type WorkspaceFinalizer struct {
 	xpresource.Finalizer
 	Store StoreCleaner
+
+	eventRecorder event.Recorder
 }
 
 // AddFinalizer to the supplied Managed resource.
@@ -55,10 +90,41 @@ func (wf *WorkspaceFinalizer) AddFinalizer(ctx context.Context, obj xpresource.O
 }
 
 // RemoveFinalizer removes the workspace from workspace store before removing
-// the finalizer.
+// the finalizer. The workspace of a resource that's deleted with the Orphan
+// deletion policy is detached from its external resource instead, which is
+// left untouched.
 func (wf *WorkspaceFinalizer) RemoveFinalizer(ctx context.Context, obj xpresource.Object) error {
+	if mg, ok := obj.(xpresource.Managed); ok && meta.WasDeleted(mg) && mg.GetDeletionPolicy() == xpv1.DeletionOrphan {
+		if err := wf.detach(ctx, mg); err != nil {
+			return err
+		}
+		return wf.Finalizer.RemoveFinalizer(ctx, obj)
+	}
 	if err := wf.Store.Remove(obj); err != nil {
 		return errors.Wrap(err, errRemoveWorkspace)
 	}
 	return wf.Finalizer.RemoveFinalizer(ctx, obj)
 }
+
+// detach detaches the workspace of the given resource from its external
+// resource and records the last observed state of the external resource so
+// that it can be adopted later, e.g. by a resource in another cluster.
+func (wf *WorkspaceFinalizer) detach(ctx context.Context, mg xpresource.Managed) error {
+	if d, ok := wf.Store.(StoreDetacher); ok {
+		if err := d.Detach(ctx, mg); err != nil {
+			return errors.Wrap(err, errDetachWorkspace)
+		}
+	} else if err := wf.Store.Remove(mg); err != nil {
+		return errors.Wrap(err, errRemoveWorkspace)
+	}
+	msg := "Detached the external resource " + meta.GetExternalName(mg) + " without deleting it"
+	if tr, ok := mg.(resource.Terraformed); ok {
+		if obs, err := tr.GetObservation(); err == nil {
+			if raw, err := json.JSParser.Marshal(obs); err == nil {
+				msg += ", last observed state: " + string(raw)
+			}
+		}
+	}
+	wf.eventRecorder.Event(mg, event.Normal(reasonDetached, msg))
+	return nil
+}
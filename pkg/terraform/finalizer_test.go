@@ -19,14 +19,19 @@ package terraform
 import (
 	"context"
 	"testing"
+	"time"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/fake"
 )
 
 var (
@@ -46,6 +51,24 @@ func (sf *StoreFns) Remove(obj xpresource.Object) error {
 	return sf.RemoveFn(obj)
 }
 
+type DetachingStoreFns struct {
+	StoreFns
+	DetachFn func(ctx context.Context, obj xpresource.Object) error
+}
+
+func (sf *DetachingStoreFns) Detach(ctx context.Context, obj xpresource.Object) error {
+	return sf.DetachFn(ctx, obj)
+}
+
+func orphanedManaged() *fake.Terraformed {
+	return &fake.Terraformed{
+		Managed: xpfake.Managed{
+			ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &metav1.Time{Time: time.Now()}},
+			Orphanable: xpfake.Orphanable{Policy: xpv1.DeletionOrphan},
+		},
+	}
+}
+
 func TestAddFinalizer(t *testing.T) {
 	type args struct {
 		finalizer xpresource.Finalizer
@@ -150,6 +173,55 @@ func TestRemoveFinalizer(t *testing.T) {
 				err: errBoom,
 			},
 		},
+		"Orphaned": {
+			reason: "The workspace of a resource deleted with the Orphan policy should be detached instead of removed.",
+			args: args{
+				obj: orphanedManaged(),
+				store: &DetachingStoreFns{
+					StoreFns: StoreFns{
+						RemoveFn: func(_ xpresource.Object) error {
+							return errBoom
+						},
+					},
+					DetachFn: func(_ context.Context, _ xpresource.Object) error {
+						return nil
+					},
+				},
+				finalizer: xpresource.FinalizerFns{
+					RemoveFinalizerFn: func(_ context.Context, _ xpresource.Object) error {
+						return nil
+					},
+				},
+			},
+		},
+		"OrphanedDetachFails": {
+			reason: "The finalizer should not be removed if the workspace cannot be detached.",
+			args: args{
+				obj: orphanedManaged(),
+				store: &DetachingStoreFns{
+					DetachFn: func(_ context.Context, _ xpresource.Object) error {
+						return errBoom
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errDetachWorkspace),
+			},
+		},
+		"OrphanedWithoutDetacher": {
+			reason: "The workspace should be removed if the store cannot detach it.",
+			args: args{
+				obj: orphanedManaged(),
+				store: &StoreFns{
+					RemoveFn: func(_ xpresource.Object) error {
+						return errBoom
+					},
+				},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, errRemoveWorkspace),
+			},
+		},
 	}
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
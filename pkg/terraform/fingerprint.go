@@ -0,0 +1,88 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// PlanCacheStats counts the comparisons of the configuration and the state of
+// a workspace that are reused because they're the same as the ones of the
+// last comparison, i.e. the skipped plans and plan shows, and the ones that
+// are computed. It's safe for concurrent use.
+type PlanCacheStats struct {
+	hits   uint64
+	misses uint64
+}
+
+// Hits returns the number of reused comparisons.
+func (s *PlanCacheStats) Hits() uint64 {
+	if s == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&s.hits)
+}
+
+// Misses returns the number of computed comparisons.
+func (s *PlanCacheStats) Misses() uint64 {
+	if s == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&s.misses)
+}
+
+func (s *PlanCacheStats) hit() {
+	if s != nil {
+		atomic.AddUint64(&s.hits, 1)
+	}
+}
+
+func (s *PlanCacheStats) miss() {
+	if s != nil {
+		atomic.AddUint64(&s.misses, 1)
+	}
+}
+
+// fingerprint returns the hash of the main.tf.json file that the FileProducer
+// rendered and the attributes of the state in the workspace. The comparison of
+// the configuration with the state, which is refreshed by Observe before, is
+// the same for the same fingerprint.
+func (w *Workspace) fingerprint() (string, error) {
+	tf, err := w.fs.ReadFile(filepath.Join(w.dir, fileMainTF))
+	if err != nil {
+		return "", errors.Wrap(err, "cannot read main tf file")
+	}
+	s, err := w.readState()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, b := range [][]byte{tf, s.GetAttributes(), s.GetPrivateRaw()} {
+		// The length prefixes keep the boundaries of the parts.
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(len(b)))
+		_, _ = h.Write(l[:])
+		_, _ = h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
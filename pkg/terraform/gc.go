@@ -0,0 +1,256 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultCollectionInterval = 1 * time.Hour
+	defaultRetention          = 1 * time.Hour
+)
+
+// ResourceLister lists the UIDs of the managed resources whose workspaces
+// have to be kept.
+type ResourceLister interface {
+	ListUIDs(ctx context.Context) (map[types.UID]struct{}, error)
+}
+
+// ResourceListerFn is a function that implements the ResourceLister
+// interface.
+type ResourceListerFn func(ctx context.Context) (map[types.UID]struct{}, error)
+
+// ListUIDs calls the ResourceListerFn.
+func (fn ResourceListerFn) ListUIDs(ctx context.Context) (map[types.UID]struct{}, error) {
+	return fn(ctx)
+}
+
+// NewAPIResourceLister returns a new APIResourceLister.
+func NewAPIResourceLister(kube client.Client, gvks ...schema.GroupVersionKind) *APIResourceLister {
+	return &APIResourceLister{kube: kube, gvks: gvks}
+}
+
+// APIResourceLister lists the managed resources of the given kinds using only
+// their metadata.
+type APIResourceLister struct {
+	kube client.Client
+	gvks []schema.GroupVersionKind
+}
+
+// ListUIDs returns the UIDs of all managed resources of the configured kinds.
+func (l *APIResourceLister) ListUIDs(ctx context.Context) (map[types.UID]struct{}, error) {
+	uids := map[types.UID]struct{}{}
+	for _, gvk := range l.gvks {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := l.kube.List(ctx, list); err != nil {
+			return nil, errors.Wrapf(err, "cannot list %s", gvk.String())
+		}
+		for _, o := range list.Items {
+			uids[o.GetUID()] = struct{}{}
+		}
+	}
+	return uids, nil
+}
+
+// CollectorOption lets you configure the WorkspaceCollector.
+type CollectorOption func(*WorkspaceCollector)
+
+// WithCollectionInterval sets how often the orphaned workspaces are collected.
+func WithCollectionInterval(d time.Duration) CollectorOption {
+	return func(c *WorkspaceCollector) {
+		c.interval = d
+	}
+}
+
+// WithRetention sets how long a workspace directory is kept after it was last
+// used even if its resource doesn't exist anymore. It protects the
+// workspaces of the resources that are created after the resources are
+// listed.
+func WithRetention(d time.Duration) CollectorOption {
+	return func(c *WorkspaceCollector) {
+		c.retention = d
+	}
+}
+
+// WithDryRun makes the collector only log the workspaces it'd remove.
+func WithDryRun(dryRun bool) CollectorOption {
+	return func(c *WorkspaceCollector) {
+		c.dryRun = dryRun
+	}
+}
+
+// WithCollectorLogger sets the logger of the collector.
+func WithCollectorLogger(l logging.Logger) CollectorOption {
+	return func(c *WorkspaceCollector) {
+		c.logger = l
+	}
+}
+
+// NewWorkspaceCollector returns a new WorkspaceCollector.
+func NewWorkspaceCollector(ws *WorkspaceStore, l ResourceLister, opts ...CollectorOption) *WorkspaceCollector {
+	c := &WorkspaceCollector{
+		store:     ws,
+		lister:    l,
+		interval:  defaultCollectionInterval,
+		retention: defaultRetention,
+		logger:    logging.NewNopLogger(),
+	}
+	for _, f := range opts {
+		f(c)
+	}
+	return c
+}
+
+// WorkspaceCollector periodically removes the workspace directories whose
+// managed resources don't exist anymore, e.g. because the provider was
+// restarted before the workspace was removed or the finalizer of the
+// resource was removed manually. It can be added to a controller manager as
+// a Runnable.
+type WorkspaceCollector struct {
+	store     *WorkspaceStore
+	lister    ResourceLister
+	interval  time.Duration
+	retention time.Duration
+	dryRun    bool
+	logger    logging.Logger
+}
+
+// Start collects the orphaned workspaces periodically until the given
+// context is done.
+func (c *WorkspaceCollector) Start(ctx context.Context) error {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			if _, err := c.Collect(ctx); err != nil {
+				c.logger.Info("cannot collect orphaned workspaces", "error", err.Error())
+			}
+		}
+	}
+}
+
+// Collect removes the orphaned workspace directories once and returns them.
+// In dry-run mode, it returns the directories that would be removed. The
+// workspaces with a running operation are never removed.
+func (c *WorkspaceCollector) Collect(ctx context.Context) ([]string, error) {
+	// The resources are listed before the directories are read so that the
+	// workspaces of the resources created in the meantime are protected by
+	// the retention period.
+	uids, err := c.lister.ListUIDs(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list managed resources")
+	}
+	root := c.store.fs.GetTempDir("")
+	entries, err := c.store.fs.ReadDir(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read workspaces root directory")
+	}
+	candidates := map[types.UID]string{}
+	for _, e := range entries {
+		uid := types.UID(e.Name())
+		if _, ok := uids[uid]; ok || !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		stale, err := c.isStale(dir, e.ModTime())
+		if err != nil {
+			return nil, err
+		}
+		if stale {
+			candidates[uid] = dir
+		}
+	}
+	// The workspaces are released under the lock of the store but their
+	// directories are removed after it's released so that the disk I/O
+	// doesn't block the other workspaces.
+	removable := c.release(candidates)
+	if c.dryRun {
+		for _, dir := range removable {
+			c.logger.Info("would remove orphaned workspace", "workspace", dir)
+		}
+		return removable, nil
+	}
+	var collected []string
+	for _, dir := range removable {
+		if err := c.store.fs.RemoveAll(dir); err != nil {
+			return collected, errors.Wrapf(err, "cannot remove orphaned workspace %s", dir)
+		}
+		c.logger.Info("removed orphaned workspace", "workspace", dir)
+		collected = append(collected, dir)
+	}
+	return collected, nil
+}
+
+// isStale returns whether the given directory is a workspace that hasn't been
+// used within the retention period. The main configuration file is written
+// every time the workspace is used.
+func (c *WorkspaceCollector) isStale(dir string, modTime time.Time) (bool, error) {
+	// The root directory is shared with other programs, so only the
+	// directories that look like workspaces are considered.
+	if len(validation.IsDNS1123Subdomain(filepath.Base(dir))) != 0 {
+		return false, nil
+	}
+	fi, err := c.store.fs.Stat(filepath.Join(dir, fileMainTF))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "cannot stat %s", fileMainTF)
+	}
+	if fi.ModTime().After(modTime) {
+		modTime = fi.ModTime()
+	}
+	return time.Since(modTime) > c.retention, nil
+}
+
+// release removes the given workspaces from the store unless they have a
+// running operation and returns the directories of the removed ones. In
+// dry-run mode, the workspaces are kept in the store.
+func (c *WorkspaceCollector) release(candidates map[types.UID]string) []string {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	var dirs []string
+	for uid, dir := range candidates {
+		if w, ok := c.store.store[uid]; ok && w.LastOperation.IsRunning() {
+			c.logger.Debug("skipping orphaned workspace with a running operation", "workspace", dir)
+			continue
+		}
+		if !c.dryRun {
+			c.store.release(uid)
+		}
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestWorkspaceCollectorCollect(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	type workspace struct {
+		uid     types.UID
+		mainTF  bool
+		lastUse time.Time
+		running bool
+	}
+	type args struct {
+		workspaces []workspace
+		lister     ResourceLister
+		opts       []CollectorOption
+	}
+	type want struct {
+		collected []string
+		remaining []types.UID
+		err       error
+	}
+	listed := func(uids ...types.UID) ResourceLister {
+		return ResourceListerFn(func(_ context.Context) (map[types.UID]struct{}, error) {
+			m := map[types.UID]struct{}{}
+			for _, u := range uids {
+				m[u] = struct{}{}
+			}
+			return m, nil
+		})
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Orphaned": {
+			reason: "Workspaces of the resources that don't exist should be removed after the retention period.",
+			args: args{
+				workspaces: []workspace{
+					{uid: "existing", mainTF: true, lastUse: old},
+					{uid: "orphaned", mainTF: true, lastUse: old},
+					{uid: "recent", mainTF: true, lastUse: time.Now()},
+				},
+				lister: listed("existing"),
+			},
+			want: want{
+				collected: []string{"orphaned"},
+				remaining: []types.UID{"existing", "recent"},
+			},
+		},
+		"NotWorkspace": {
+			reason: "Directories that don't look like workspaces should not be removed.",
+			args: args{
+				workspaces: []workspace{
+					{uid: "not-workspace", lastUse: old},
+					{uid: "Not_A_UID", mainTF: true, lastUse: old},
+				},
+				lister: listed(),
+			},
+			want: want{
+				remaining: []types.UID{"Not_A_UID", "not-workspace"},
+			},
+		},
+		"RunningOperation": {
+			reason: "Workspaces with a running operation should never be removed.",
+			args: args{
+				workspaces: []workspace{
+					{uid: "running", mainTF: true, lastUse: old, running: true},
+				},
+				lister: listed(),
+			},
+			want: want{
+				remaining: []types.UID{"running"},
+			},
+		},
+		"DryRun": {
+			reason: "Nothing should be removed in dry-run mode.",
+			args: args{
+				workspaces: []workspace{
+					{uid: "orphaned", mainTF: true, lastUse: old},
+				},
+				lister: listed(),
+				opts:   []CollectorOption{WithDryRun(true)},
+			},
+			want: want{
+				collected: []string{"orphaned"},
+				remaining: []types.UID{"orphaned"},
+			},
+		},
+		"ListFailed": {
+			reason: "Nothing should be removed if the resources cannot be listed.",
+			args: args{
+				workspaces: []workspace{
+					{uid: "orphaned", mainTF: true, lastUse: old},
+				},
+				lister: ResourceListerFn(func(_ context.Context) (map[types.UID]struct{}, error) {
+					return nil, errBoom
+				}),
+			},
+			want: want{
+				remaining: []types.UID{"orphaned"},
+				err:       errors.Wrap(errBoom, "cannot list managed resources"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs))
+			root := ws.fs.GetTempDir("")
+			for _, w := range tc.args.workspaces {
+				dir := filepath.Join(root, string(w.uid))
+				if err := fs.MkdirAll(dir, os.ModePerm); err != nil {
+					t.Fatalf("MkdirAll(...): %v", err)
+				}
+				if w.mainTF {
+					if err := afero.WriteFile(fs, filepath.Join(dir, fileMainTF), []byte("{}"), os.ModePerm); err != nil {
+						t.Fatalf("WriteFile(...): %v", err)
+					}
+					if err := fs.Chtimes(filepath.Join(dir, fileMainTF), w.lastUse, w.lastUse); err != nil {
+						t.Fatalf("Chtimes(...): %v", err)
+					}
+				}
+				if err := fs.Chtimes(dir, w.lastUse, w.lastUse); err != nil {
+					t.Fatalf("Chtimes(...): %v", err)
+				}
+				if w.running {
+					o := &Operation{}
+					o.MarkStart(applyType)
+					ws.store[w.uid] = NewWorkspace(dir, WithLastOperation(o))
+				}
+			}
+			collected, err := NewWorkspaceCollector(ws, tc.args.lister, tc.args.opts...).Collect(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nCollect(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			var wantCollected []string
+			for _, c := range tc.want.collected {
+				wantCollected = append(wantCollected, filepath.Join(root, c))
+			}
+			if diff := cmp.Diff(wantCollected, collected); diff != "" {
+				t.Errorf("\n%s\nCollect(...): -want collected, +got collected:\n%s", tc.reason, diff)
+			}
+			var remaining []types.UID
+			entries, _ := afero.ReadDir(fs, root)
+			for _, e := range entries {
+				remaining = append(remaining, types.UID(e.Name()))
+			}
+			if diff := cmp.Diff(tc.want.remaining, remaining); diff != "" {
+				t.Errorf("\n%s\nCollect(...): -want remaining, +got remaining:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
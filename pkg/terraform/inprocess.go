@@ -0,0 +1,689 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/hashicorp/go-cty/cty"
+	ctyjson "github.com/hashicorp/go-cty/cty/json"
+	"github.com/hashicorp/go-cty/cty/msgpack"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+// InProcessWorkspace runs the operations of a single resource by calling the
+// ReadResource, PlanResourceChange and ApplyResourceChange functions of the
+// provider server directly instead of running the Terraform CLI. The state is
+// kept in memory and reproduced from the resource when it's not known, e.g.
+// after a restart.
+type InProcessWorkspace struct {
+	// LastOperation contains information about the last operation performed.
+	LastOperation *Operation
+
+	server       tfprotov5.ProviderServer
+	typeName     string
+	resource     *schema.Resource
+	resourceType cty.Type
+
+	// config is the desired configuration of the resource. state and private
+	// are the current state of the resource and the private data that the
+	// provider stores with it.
+	config  cty.Value
+	state   cty.Value
+	private []byte
+	// mu protects config, state and private.
+	mu sync.Mutex
+
+	asyncTimeouts  AsyncTimeouts
+	singleNested   []string
+	replaceAllowed bool
+	// dataSource is true if the workspace reads a data source, in which
+	// case the configuration is read by the ReadDataSource function of the
+	// provider server and the state is never reproduced.
+	dataSource bool
+	logger     logging.Logger
+}
+
+// NewInProcessWorkspace returns a new InProcessWorkspace that manages the
+// resource of the given type with the given schema through the given provider
+// server. The server must be configured already.
+func NewInProcessWorkspace(server tfprotov5.ProviderServer, typeName string, r *schema.Resource, l logging.Logger) *InProcessWorkspace {
+	ty := r.CoreConfigSchema().ImpliedType()
+	return &InProcessWorkspace{
+		LastOperation: &Operation{},
+		server:        server,
+		typeName:      typeName,
+		resource:      r,
+		resourceType:  ty,
+		config:        cty.NullVal(ty),
+		state:         cty.NullVal(ty),
+		asyncTimeouts: AsyncTimeouts{
+			Apply:   defaultAsyncTimeout,
+			Destroy: defaultAsyncTimeout,
+		},
+		logger: l,
+	}
+}
+
+// update sets the provider server, the desired configuration of the resource
+// from the given Terraform parameters and whether it can be replaced. The
+// server changes when the provider configuration of the resource changes.
+func (w *InProcessWorkspace) update(server tfprotov5.ProviderServer, params map[string]interface{}, replaceAllowed bool) error {
+	v, err := objectFromMap(params, w.resourceType)
+	if err != nil {
+		return errors.Wrap(err, "cannot convert parameters to resource configuration")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.server = server
+	w.config = v
+	w.replaceAllowed = replaceAllowed
+	return nil
+}
+
+// setStateIfUnknown sets the current state of the resource from the given
+// Terraform attributes if it's not known yet.
+func (w *InProcessWorkspace) setStateIfUnknown(attr map[string]interface{}, private []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.state.IsNull() {
+		return nil
+	}
+	if id, ok := attr["id"].(string); !ok || id == "" {
+		return nil
+	}
+	v, err := objectFromMap(attr, w.resourceType)
+	if err != nil {
+		return errors.Wrap(err, "cannot convert attributes to resource state")
+	}
+	w.state = v
+	w.private = private
+	return nil
+}
+
+// ApplyAsync makes an apply call without blocking and calls the given function
+// once that apply call finishes.
+func (w *InProcessWorkspace) ApplyAsync(callback CallbackFn) error {
+	if w.LastOperation.IsRunning() {
+		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+	}
+	w.LastOperation.MarkStart("apply")
+	ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(w.asyncTimeouts.Apply))
+	go func() {
+		defer cancel()
+		_, err := w.apply(ctx)
+		w.LastOperation.MarkEnd()
+		if cErr := callback(err, ctx); cErr != nil {
+			w.logger.Info("callback failed", "error", cErr.Error())
+		}
+	}()
+	return nil
+}
+
+// Apply makes a blocking apply call.
+func (w *InProcessWorkspace) Apply(ctx context.Context) (ApplyResult, error) {
+	if w.LastOperation.IsRunning() {
+		return ApplyResult{}, errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+	}
+	s, err := w.apply(ctx)
+	return ApplyResult{State: s}, err
+}
+
+// DestroyAsync makes a destroy call without blocking and calls the given
+// function once that destroy call finishes.
+func (w *InProcessWorkspace) DestroyAsync(callback CallbackFn) error {
+	switch {
+	// Destroy call is idempotent and can be called repeatedly.
+	case w.LastOperation.Type == "destroy":
+		return nil
+	case w.LastOperation.IsRunning():
+		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+	}
+	w.LastOperation.MarkStart("destroy")
+	ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(w.asyncTimeouts.Destroy))
+	go func() {
+		defer cancel()
+		err := w.destroy(ctx)
+		w.LastOperation.MarkEnd()
+		if cErr := callback(err, ctx); cErr != nil {
+			w.logger.Info("callback failed", "error", cErr.Error())
+		}
+	}()
+	return nil
+}
+
+// Destroy makes a blocking destroy call.
+func (w *InProcessWorkspace) Destroy(ctx context.Context) error {
+	if w.LastOperation.IsRunning() {
+		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+	}
+	return w.destroy(ctx)
+}
+
+// Refresh reads the current state of the resource from the provider.
+func (w *InProcessWorkspace) Refresh(ctx context.Context) (RefreshResult, error) {
+	switch {
+	case w.LastOperation.IsRunning():
+		return RefreshResult{
+			IsApplying:   w.LastOperation.Type == "apply",
+			IsDestroying: w.LastOperation.Type == "destroy",
+		}, nil
+	case w.LastOperation.IsEnded():
+		defer w.LastOperation.Flush()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.dataSource {
+		return w.readDataSource(ctx)
+	}
+	if w.state.IsNull() {
+		return RefreshResult{}, nil
+	}
+	cur, err := dynamicValue(w.state, w.resourceType)
+	if err != nil {
+		return RefreshResult{}, err
+	}
+	resp, err := w.server.ReadResource(ctx, &tfprotov5.ReadResourceRequest{
+		TypeName:     w.typeName,
+		CurrentState: cur,
+		Private:      w.private,
+	})
+	if err != nil {
+		return RefreshResult{}, errors.Wrap(err, "cannot read resource")
+	}
+	if hasErrors(resp.Diagnostics) {
+		return RefreshResult{}, tferrors.NewRefreshFailed(diagnosticsLog(resp.Diagnostics))
+	}
+	s, err := valueFromDynamic(resp.NewState, w.resourceType)
+	if err != nil {
+		return RefreshResult{}, err
+	}
+	w.state, w.private = s, resp.Private
+	if s.IsNull() {
+		return RefreshResult{}, nil
+	}
+	st, err := w.stateV4()
+	if err != nil {
+		return RefreshResult{}, err
+	}
+	return RefreshResult{Exists: true, State: st}, nil
+}
+
+// readDataSource reads the data source with the desired configuration. It
+// must be called with the lock held.
+func (w *InProcessWorkspace) readDataSource(ctx context.Context) (RefreshResult, error) {
+	cfg, err := dynamicValue(w.config, w.resourceType)
+	if err != nil {
+		return RefreshResult{}, err
+	}
+	resp, err := w.server.ReadDataSource(ctx, &tfprotov5.ReadDataSourceRequest{
+		TypeName: w.typeName,
+		Config:   cfg,
+	})
+	if err != nil {
+		return RefreshResult{}, errors.Wrap(err, "cannot read data source")
+	}
+	if hasErrors(resp.Diagnostics) {
+		return RefreshResult{}, tferrors.NewRefreshFailed(diagnosticsLog(resp.Diagnostics))
+	}
+	s, err := valueFromDynamic(resp.State, w.resourceType)
+	if err != nil {
+		return RefreshResult{}, err
+	}
+	w.state = s
+	if s.IsNull() {
+		return RefreshResult{}, nil
+	}
+	st, err := w.stateV4()
+	if err != nil {
+		return RefreshResult{}, err
+	}
+	return RefreshResult{Exists: true, State: st}, nil
+}
+
+// Plan compares the desired configuration of the resource with its current
+// state.
+func (w *InProcessWorkspace) Plan(ctx context.Context) (PlanResult, error) {
+	// The last operation is still ongoing.
+	if w.LastOperation.IsRunning() {
+		return PlanResult{}, errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	resp, planned, err := w.plan(ctx, w.config)
+	if err != nil {
+		return PlanResult{}, err
+	}
+	if w.state.IsNull() {
+		return PlanResult{}, nil
+	}
+	res := PlanResult{
+		Exists:   true,
+		UpToDate: planned.IsWhollyKnown() && planned.Equals(w.state).True(),
+	}
+	if res.UpToDate {
+		return res, nil
+	}
+	res.RequiresReplace = len(resp.RequiresReplace) != 0
+	res.Diff = NewPlanDiff(w.change(planned))
+	return res, nil
+}
+
+// Observe reads the current state of the resource and compares it with the
+// desired configuration. Both are calls to the provider server in this
+// process, so they're not combined.
+func (w *InProcessWorkspace) Observe(ctx context.Context) (ObserveResult, error) {
+	r, err := w.Refresh(ctx)
+	if err != nil || !r.Exists || r.IsApplying || r.IsDestroying || w.dataSource {
+		return ObserveResult{RefreshResult: r, UpToDate: r.Exists}, err
+	}
+	p, err := w.Plan(ctx)
+	if err != nil {
+		return ObserveResult{}, err
+	}
+	return ObserveResult{
+		RefreshResult:   r,
+		UpToDate:        p.UpToDate,
+		RequiresReplace: p.RequiresReplace,
+		Diff:            p.Diff,
+	}, nil
+}
+
+// plan plans the change of the resource to the given configuration. It must
+// be called with the lock held.
+func (w *InProcessWorkspace) plan(ctx context.Context, config cty.Value) (*tfprotov5.PlanResourceChangeResponse, cty.Value, error) {
+	prior, err := dynamicValue(w.state, w.resourceType)
+	if err != nil {
+		return nil, cty.NilVal, err
+	}
+	proposed, err := dynamicValue(proposedNewState(w.resource, w.state, config), w.resourceType)
+	if err != nil {
+		return nil, cty.NilVal, err
+	}
+	cfg, err := dynamicValue(config, w.resourceType)
+	if err != nil {
+		return nil, cty.NilVal, err
+	}
+	resp, err := w.server.PlanResourceChange(ctx, &tfprotov5.PlanResourceChangeRequest{
+		TypeName:         w.typeName,
+		PriorState:       prior,
+		ProposedNewState: proposed,
+		Config:           cfg,
+		PriorPrivate:     w.private,
+	})
+	if err != nil {
+		return nil, cty.NilVal, errors.Wrap(err, "cannot plan resource change")
+	}
+	if hasErrors(resp.Diagnostics) {
+		return nil, cty.NilVal, tferrors.NewPlanFailed(diagnosticsLog(resp.Diagnostics))
+	}
+	planned, err := valueFromDynamic(resp.PlannedState, w.resourceType)
+	return resp, planned, err
+}
+
+// apply plans and applies the change of the resource to its desired
+// configuration.
+func (w *InProcessWorkspace) apply(ctx context.Context) (*json.StateV4, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	resp, _, err := w.plan(ctx, w.config)
+	if err != nil {
+		return nil, err
+	}
+	// The provider cannot update the resource in place, so it's replaced
+	// the way Terraform does, i.e. it's destroyed and created again.
+	if !w.state.IsNull() && len(resp.RequiresReplace) != 0 {
+		if !w.replaceAllowed {
+			return nil, tferrors.NewApplyFailed(errorLog("Instance cannot be destroyed", "The resource requires replacement but its replacement is not allowed."))
+		}
+		if err := w.destroyLocked(ctx, tferrors.NewApplyFailed); err != nil {
+			return nil, err
+		}
+		if resp, _, err = w.plan(ctx, w.config); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.applyPlan(ctx, w.config, resp, tferrors.NewApplyFailed); err != nil {
+		return nil, err
+	}
+	return w.stateV4()
+}
+
+// destroy plans and applies the deletion of the resource.
+func (w *InProcessWorkspace) destroy(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.destroyLocked(ctx, tferrors.NewDestroyFailed)
+}
+
+// destroyLocked plans and applies the deletion of the resource, reporting
+// the failures with the given function. It must be called with the lock
+// held.
+func (w *InProcessWorkspace) destroyLocked(ctx context.Context, failed func([]byte) error) error {
+	if w.state.IsNull() {
+		return nil
+	}
+	null := cty.NullVal(w.resourceType)
+	resp, _, err := w.plan(ctx, null)
+	if err != nil {
+		return err
+	}
+	return w.applyPlan(ctx, null, resp, failed)
+}
+
+// applyPlan applies the given planned change and stores the new state of the
+// resource. It must be called with the lock held.
+func (w *InProcessWorkspace) applyPlan(ctx context.Context, config cty.Value, plan *tfprotov5.PlanResourceChangeResponse, failed func([]byte) error) error {
+	prior, err := dynamicValue(w.state, w.resourceType)
+	if err != nil {
+		return err
+	}
+	cfg, err := dynamicValue(config, w.resourceType)
+	if err != nil {
+		return err
+	}
+	resp, err := w.server.ApplyResourceChange(ctx, &tfprotov5.ApplyResourceChangeRequest{
+		TypeName:       w.typeName,
+		PriorState:     prior,
+		PlannedState:   plan.PlannedState,
+		Config:         cfg,
+		PlannedPrivate: plan.PlannedPrivate,
+	})
+	if err != nil {
+		return errors.Wrap(err, "cannot apply resource change")
+	}
+	// The new state is stored even if the operation fails since the
+	// resource could be created or deleted partially.
+	if resp.NewState != nil {
+		s, err := valueFromDynamic(resp.NewState, w.resourceType)
+		if err != nil {
+			return err
+		}
+		w.state, w.private = s, resp.Private
+	}
+	if hasErrors(resp.Diagnostics) {
+		return failed(diagnosticsLog(resp.Diagnostics))
+	}
+	return nil
+}
+
+// stateV4 returns the current state of the resource in the format of the
+// Terraform state file. It must be called with the lock held.
+func (w *InProcessWorkspace) stateV4() (*json.StateV4, error) {
+	s := json.NewStateV4()
+	if w.state.IsNull() {
+		return s, nil
+	}
+	raw, err := ctyjson.Marshal(w.state, w.resourceType)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal resource state")
+	}
+	if len(w.singleNested) != 0 {
+		attr := map[string]interface{}{}
+		if err := json.JSParser.Unmarshal(raw, &attr); err != nil {
+			return nil, errors.Wrap(err, "cannot unmarshal state attributes")
+		}
+		resource.WrapSingleNested(attr, w.singleNested)
+		if raw, err = json.JSParser.Marshal(attr); err != nil {
+			return nil, errors.Wrap(err, "cannot marshal state attributes")
+		}
+	}
+	mode := "managed"
+	if w.dataSource {
+		mode = "data"
+	}
+	s.Resources = []json.ResourceStateV4{
+		{
+			Mode: mode,
+			Type: w.typeName,
+			Instances: []json.InstanceObjectStateV4{
+				{
+					SchemaVersion: uint64(w.resource.SchemaVersion),
+					AttributesRaw: raw,
+					PrivateRaw:    w.private,
+				},
+			},
+		},
+	}
+	return s, nil
+}
+
+// change returns the change from the current state of the resource to the
+// given planned state in the format of the Terraform plan. The unknown values
+// of the planned state are marked in AfterUnknown.
+func (w *InProcessWorkspace) change(planned cty.Value) *tfjson.Change {
+	sensitive, unknown := map[string]interface{}{}, map[string]interface{}{}
+	markPaths(w.resource, w.state, sensitive, nil)
+	markPaths(w.resource, planned, sensitive, unknown)
+	return &tfjson.Change{
+		Before:          valueToInterface(w.state),
+		After:           valueToInterface(planned),
+		AfterUnknown:    unknown,
+		BeforeSensitive: sensitive,
+	}
+}
+
+// proposedNewState returns the state proposed to the provider for the given
+// configuration, which is the configuration where the unset computed
+// attributes take their values from the prior state. Unlike Terraform, only
+// the top-level attributes are merged.
+func proposedNewState(r *schema.Resource, prior, config cty.Value) cty.Value {
+	if config.IsNull() || prior.IsNull() {
+		return config
+	}
+	vals := config.AsValueMap()
+	for k, v := range vals {
+		if s, ok := r.Schema[k]; ok && s.Computed && v.IsNull() {
+			vals[k] = prior.GetAttr(k)
+		}
+	}
+	return cty.ObjectVal(vals)
+}
+
+// objectFromMap converts the given Terraform attributes to a value of the
+// given object type. The attributes that are not in the type are ignored
+// and the missing ones are null.
+func objectFromMap(m map[string]interface{}, ty cty.Type) (cty.Value, error) {
+	filtered := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if ty.HasAttribute(k) {
+			filtered[k] = v
+		}
+	}
+	raw, err := json.JSParser.Marshal(filtered)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return ctyjson.Unmarshal(raw, ty)
+}
+
+func dynamicValue(v cty.Value, ty cty.Type) (*tfprotov5.DynamicValue, error) {
+	raw, err := msgpack.Marshal(v, ty)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encode value")
+	}
+	return &tfprotov5.DynamicValue{MsgPack: raw}, nil
+}
+
+func valueFromDynamic(dv *tfprotov5.DynamicValue, ty cty.Type) (cty.Value, error) {
+	if dv == nil {
+		return cty.NullVal(ty), nil
+	}
+	v, err := msgpack.Unmarshal(dv.MsgPack, ty)
+	return v, errors.Wrap(err, "cannot decode value")
+}
+
+// valueToInterface returns the JSON representation of the given value where
+// the unknown values are null.
+func valueToInterface(v cty.Value) interface{} {
+	if v.IsNull() {
+		return nil
+	}
+	known, err := cty.Transform(v, func(_ cty.Path, v cty.Value) (cty.Value, error) {
+		if !v.IsKnown() {
+			return cty.NullVal(v.Type()), nil
+		}
+		return v, nil
+	})
+	if err != nil {
+		return nil
+	}
+	raw, err := ctyjson.Marshal(known, known.Type())
+	if err != nil {
+		return nil
+	}
+	var out interface{}
+	if err := json.JSParser.Unmarshal(raw, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// markPaths marks the paths of the sensitive attributes and, if unknown is
+// not nil, the unknown values in the given value. The paths are in the
+// flattened form that NewPlanDiff uses.
+func markPaths(r *schema.Resource, v cty.Value, sensitive, unknown map[string]interface{}) {
+	if v.IsNull() {
+		return
+	}
+	_ = cty.Walk(v, func(p cty.Path, v cty.Value) (bool, error) {
+		fp, ok := flatPath(p)
+		if !ok || fp == "" {
+			return true, nil
+		}
+		if unknown != nil && !v.IsKnown() {
+			unknown[fp] = true
+			return false, nil
+		}
+		if isSensitive(r, p) {
+			sensitive[fp] = true
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+// flatPath returns the given path in the flattened form, e.g. "rule.0.port".
+// The elements of sets cannot be addressed.
+func flatPath(p cty.Path) (string, bool) {
+	s := ""
+	for _, step := range p {
+		switch t := step.(type) {
+		case cty.GetAttrStep:
+			s = joinPath(s, t.Name)
+		case cty.IndexStep:
+			switch {
+			case t.Key.Type() == cty.String:
+				s = joinPath(s, t.Key.AsString())
+			case t.Key.Type() == cty.Number:
+				i, _ := t.Key.AsBigFloat().Int64()
+				s = joinPath(s, strconv.FormatInt(i, 10))
+			default:
+				return s, false
+			}
+		}
+	}
+	return s, true
+}
+
+// isSensitive returns whether the attribute at the given path is sensitive
+// in the given schema.
+func isSensitive(r *schema.Resource, p cty.Path) bool {
+	cur := r
+	for _, step := range p {
+		a, ok := step.(cty.GetAttrStep)
+		if !ok {
+			continue
+		}
+		if cur == nil {
+			return false
+		}
+		s, ok := cur.Schema[a.Name]
+		if !ok {
+			return false
+		}
+		if s.Sensitive {
+			return true
+		}
+		cur, _ = s.Elem.(*schema.Resource)
+	}
+	return false
+}
+
+func hasErrors(diags []*tfprotov5.Diagnostic) bool {
+	for _, d := range diags {
+		if d != nil && d.Severity == tfprotov5.DiagnosticSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// diagnosticsError returns an error with the summaries of the error
+// diagnostics.
+func diagnosticsError(diags []*tfprotov5.Diagnostic) error {
+	var msgs []string
+	for _, d := range diags {
+		if d != nil && d.Severity == tfprotov5.DiagnosticSeverityError {
+			msgs = append(msgs, d.Summary)
+		}
+	}
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+// diagnosticsLog returns the error diagnostics in the JSON log format of the
+// Terraform CLI so that they're reported the same way.
+func diagnosticsLog(diags []*tfprotov5.Diagnostic) []byte {
+	var b bytes.Buffer
+	for _, d := range diags {
+		if d == nil || d.Severity != tfprotov5.DiagnosticSeverityError {
+			continue
+		}
+		b.Write(errorLog(d.Summary, d.Detail))
+	}
+	return b.Bytes()
+}
+
+// errorLog returns an error log line in the JSON log format of the Terraform
+// CLI.
+func errorLog(summary, detail string) []byte {
+	raw, err := json.JSParser.Marshal(tferrors.TerraformLog{
+		Level:   "error",
+		Message: summary,
+		Diagnostic: tferrors.LogDiagnostic{
+			Severity: "error",
+			Summary:  summary,
+			Detail:   detail,
+		},
+	})
+	if err != nil {
+		return nil
+	}
+	return append(raw, '\n')
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"golang.org/x/sync/singleflight"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+const (
+	errInProcessEnv = "environment variables in the Terraform setup are not supported by the in-process workspaces since they would be shared by all the resources"
+	errNoSchema     = "resource configuration does not have the Terraform schema of the resource"
+)
+
+// NewInProcessWorkspaceStore returns a new InProcessWorkspaceStore that
+// manages the resources through the provider returned by the given function.
+// The function is called once for every distinct provider configuration, so
+// it must return a new provider each time.
+func NewInProcessWorkspaceStore(l logging.Logger, newProvider func() *schema.Provider) *InProcessWorkspaceStore {
+	return &InProcessWorkspaceStore{
+		store:       map[types.UID]*InProcessWorkspace{},
+		servers:     map[string]tfprotov5.ProviderServer{},
+		refs:        map[string]int{},
+		keys:        map[types.UID]string{},
+		newProvider: newProvider,
+		logger:      l,
+	}
+}
+
+// InProcessWorkspaceStore allows you to manage multiple InProcessWorkspaces.
+// Instead of running a native provider process, the provider is linked into
+// the provider binary and served in-process, one server per distinct provider
+// configuration. A server is dropped once no workspace uses its provider
+// configuration anymore, e.g. after the credentials are rotated.
+type InProcessWorkspaceStore struct {
+	newProvider func() *schema.Provider
+	logger      logging.Logger
+	// configure makes sure that a provider configuration is configured
+	// only once at a time, without holding the lock.
+	configure singleflight.Group
+
+	mu    sync.Mutex
+	store map[types.UID]*InProcessWorkspace
+	// servers are the configured provider servers keyed by the hash of
+	// their provider configuration.
+	servers map[string]tfprotov5.ProviderServer
+	// refs is the number of workspaces that use each server.
+	refs map[string]int
+	// keys is the key of the server each workspace uses.
+	keys map[types.UID]string
+}
+
+// Workspace returns the InProcessWorkspace of the given resource with its
+// desired configuration updated from the resource. The state of a resource
+// seen for the first time is reproduced from the resource.
+func (ws *InProcessWorkspaceStore) Workspace(ctx context.Context, c resource.SecretClient, tr resource.Terraformed, ts Setup, cfg *config.Resource) (*InProcessWorkspace, error) {
+	if len(ts.Env) != 0 {
+		return nil, errors.New(errInProcessEnv)
+	}
+	if cfg.TerraformResource == nil {
+		return nil, errors.New(errNoSchema)
+	}
+	fp, err := NewFileProducer(ctx, c, "", tr, ts, cfg, WithFileSystem(afero.NewMemMapFs()))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create a new file producer")
+	}
+	attr, private, err := fp.instanceState(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot reproduce state")
+	}
+	server, key, err := ws.server(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	// An invalid timeout annotation is reported by the controller, see
+	// controller.Connector, and the configured timeouts are used instead.
+	ato, _ := NewAsyncTimeouts(tr, cfg.OperationTimeouts)
+	ws.mu.Lock()
+	w, ok := ws.store[tr.GetUID()]
+	if !ok {
+		w = NewInProcessWorkspace(server, tr.GetTerraformResourceType(), cfg.TerraformResource, ws.logger.WithValues("uid", tr.GetUID()))
+		w.singleNested = cfg.SingleNestedAttributes
+		w.dataSource = cfg.DataSource
+		ws.store[tr.GetUID()] = w
+	}
+	ws.use(tr.GetUID(), key, server)
+	ws.mu.Unlock()
+	w.asyncTimeouts = ato
+	if err := w.update(server, fp.parameters, resource.IsReplacementAllowed(tr, cfg.ReplacementPolicy)); err != nil {
+		return nil, err
+	}
+	// The state of a data source is produced by reading it.
+	if cfg.DataSource {
+		return w, nil
+	}
+	return w, w.setStateIfUnknown(attr, private)
+}
+
+// Remove erases the record of the workspace of the given resource from the
+// store.
+func (ws *InProcessWorkspaceStore) Remove(obj xpresource.Object) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.release(obj.GetUID())
+	return nil
+}
+
+// Detach erases the record of the workspace of the given resource from the
+// store without destroying its external resource. It fails while an
+// operation of the workspace is running.
+func (ws *InProcessWorkspaceStore) Detach(_ context.Context, obj xpresource.Object) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if w, ok := ws.store[obj.GetUID()]; ok && w.LastOperation.IsRunning() {
+		return errors.Errorf(errFmtDetachRunning, w.LastOperation.Type)
+	}
+	ws.release(obj.GetUID())
+	return nil
+}
+
+// use records that the workspace with the given UID uses the given server
+// with the given key. The server that the workspace used before is dropped
+// if no other workspace uses it. It must be called with the lock held.
+func (ws *InProcessWorkspaceStore) use(uid types.UID, key string, s tfprotov5.ProviderServer) {
+	old, ok := ws.keys[uid]
+	if ok && old == key {
+		return
+	}
+	// The server could have been dropped after it was returned.
+	ws.servers[key] = s
+	ws.refs[key]++
+	ws.keys[uid] = key
+	if ok {
+		ws.unref(old)
+	}
+}
+
+// release erases the record of the workspace with the given UID and drops
+// the server it used if no other workspace uses it. It must be called with
+// the lock held.
+func (ws *InProcessWorkspaceStore) release(uid types.UID) {
+	delete(ws.store, uid)
+	if key, ok := ws.keys[uid]; ok {
+		delete(ws.keys, uid)
+		ws.unref(key)
+	}
+}
+
+// unref drops a use of the server with the given key, and the server itself
+// if it's not used anymore. It must be called with the lock held.
+func (ws *InProcessWorkspaceStore) unref(key string) {
+	ws.refs[key]--
+	if ws.refs[key] > 0 {
+		return
+	}
+	delete(ws.refs, key)
+	delete(ws.servers, key)
+}
+
+// server returns the provider server configured with the provider
+// configuration of the given setup and its key, creating it if needed. The
+// provider is configured without holding the lock since it could make calls
+// to the Cloud API.
+func (ws *InProcessWorkspaceStore) server(ctx context.Context, ts Setup) (tfprotov5.ProviderServer, string, error) {
+	raw, err := json.JSParser.Marshal(ts.Configuration)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "cannot marshal provider configuration")
+	}
+	sum := sha256.Sum256(raw)
+	key := hex.EncodeToString(sum[:])
+	ws.mu.Lock()
+	s, ok := ws.servers[key]
+	ws.mu.Unlock()
+	if ok {
+		return s, key, nil
+	}
+	v, err, _ := ws.configure.Do(key, func() (interface{}, error) {
+		// The server could have been created by a call that ended after
+		// the server was looked up.
+		ws.mu.Lock()
+		s, ok := ws.servers[key]
+		ws.mu.Unlock()
+		if ok {
+			return s, nil
+		}
+		s, err := ws.newServer(ctx, ts)
+		if err != nil {
+			return nil, err
+		}
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		ws.servers[key] = s
+		return s, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return v.(tfprotov5.ProviderServer), key, nil
+}
+
+// newServer returns a new provider server configured with the provider
+// configuration of the given setup.
+func (ws *InProcessWorkspaceStore) newServer(ctx context.Context, ts Setup) (tfprotov5.ProviderServer, error) {
+	p := ws.newProvider()
+	s := schema.NewGRPCProviderServer(p)
+	ty := schema.InternalMap(p.Schema).CoreConfigSchema().ImpliedType()
+	v, err := objectFromMap(ts.Configuration, ty)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot convert provider configuration")
+	}
+	dv, err := dynamicValue(v, ty)
+	if err != nil {
+		return nil, err
+	}
+	prep, err := s.PrepareProviderConfig(ctx, &tfprotov5.PrepareProviderConfigRequest{Config: dv})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot prepare provider configuration")
+	}
+	if hasErrors(prep.Diagnostics) {
+		return nil, errors.Wrap(diagnosticsError(prep.Diagnostics), "cannot prepare provider configuration")
+	}
+	if prep.PreparedConfig != nil {
+		dv = prep.PreparedConfig
+	}
+	resp, err := s.ConfigureProvider(ctx, &tfprotov5.ConfigureProviderRequest{TerraformVersion: ts.Version, Config: dv})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot configure provider")
+	}
+	if hasErrors(resp.Diagnostics) {
+		return nil, errors.Wrap(diagnosticsError(resp.Diagnostics), "cannot configure provider")
+	}
+	return s, nil
+}
@@ -0,0 +1,544 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+const inProcessType = "test_thing"
+
+// thingAPI is the in-memory external API managed by the test provider.
+type thingAPI struct {
+	mu     sync.Mutex
+	things map[string]map[string]interface{}
+}
+
+func (a *thingAPI) provider() *schema.Provider {
+	return &schema.Provider{
+		ResourcesMap: map[string]*schema.Resource{
+			inProcessType: a.resource(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			inProcessType: a.dataSource(),
+		},
+	}
+}
+
+func (a *thingAPI) dataSource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"size": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+		ReadContext: func(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			name := d.Get("name").(string)
+			t, ok := a.things[name]
+			if !ok {
+				return diag.Errorf("thing %s is not found", name)
+			}
+			d.SetId(name)
+			return diag.FromErr(d.Set("size", t["size"]))
+		},
+	}
+}
+
+func (a *thingAPI) resource() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"size": {
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"password": {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"arn": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+		CreateContext: func(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+			if d.Get("size").(int) < 0 {
+				return diag.Errorf("size cannot be negative")
+			}
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			name := d.Get("name").(string)
+			a.things[name] = map[string]interface{}{"size": d.Get("size"), "password": d.Get("password")}
+			d.SetId(name)
+			return diag.FromErr(d.Set("arn", "arn:"+name))
+		},
+		ReadContext: func(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			t, ok := a.things[d.Id()]
+			if !ok {
+				d.SetId("")
+				return nil
+			}
+			if err := d.Set("size", t["size"]); err != nil {
+				return diag.FromErr(err)
+			}
+			return diag.FromErr(d.Set("arn", "arn:"+d.Id()))
+		},
+		UpdateContext: func(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			a.things[d.Id()]["size"] = d.Get("size")
+			return nil
+		},
+		DeleteContext: func(_ context.Context, d *schema.ResourceData, _ interface{}) diag.Diagnostics {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			delete(a.things, d.Id())
+			return nil
+		},
+	}
+}
+
+// newInProcessWorkspace returns an InProcessWorkspace of a test_thing with
+// the given parameters and, if it's not nil, the given state attributes.
+func newInProcessWorkspace(t *testing.T, a *thingAPI, params, attr map[string]interface{}, replaceAllowed bool) *InProcessWorkspace {
+	t.Helper()
+	p := a.provider()
+	s := schema.NewGRPCProviderServer(p)
+	if _, err := s.ConfigureProvider(context.TODO(), &tfprotov5.ConfigureProviderRequest{Config: mustDynamicValue(t, p)}); err != nil {
+		t.Fatalf("cannot configure provider: %s", err)
+	}
+	w := NewInProcessWorkspace(s, inProcessType, p.ResourcesMap[inProcessType], logging.NewNopLogger())
+	if err := w.update(s, params, replaceAllowed); err != nil {
+		t.Fatalf("cannot update workspace: %s", err)
+	}
+	if attr != nil {
+		if err := w.setStateIfUnknown(attr, nil); err != nil {
+			t.Fatalf("cannot set state: %s", err)
+		}
+	}
+	return w
+}
+
+func mustDynamicValue(t *testing.T, p *schema.Provider) *tfprotov5.DynamicValue {
+	t.Helper()
+	ty := schema.InternalMap(p.Schema).CoreConfigSchema().ImpliedType()
+	v, err := objectFromMap(map[string]interface{}{}, ty)
+	if err != nil {
+		t.Fatalf("cannot convert provider configuration: %s", err)
+	}
+	dv, err := dynamicValue(v, ty)
+	if err != nil {
+		t.Fatalf("cannot encode provider configuration: %s", err)
+	}
+	return dv
+}
+
+func stateAttributes(t *testing.T, s *json.StateV4) map[string]interface{} {
+	t.Helper()
+	if s == nil || len(s.Resources) == 0 {
+		return nil
+	}
+	attr := map[string]interface{}{}
+	if err := json.JSParser.Unmarshal(s.Resources[0].Instances[0].AttributesRaw, &attr); err != nil {
+		t.Fatalf("cannot unmarshal attributes: %s", err)
+	}
+	return attr
+}
+
+func TestInProcessWorkspaceApply(t *testing.T) {
+	type args struct {
+		params         map[string]interface{}
+		attr           map[string]interface{}
+		replaceAllowed bool
+	}
+	type want struct {
+		attr   map[string]interface{}
+		things map[string]map[string]interface{}
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		things map[string]map[string]interface{}
+		args
+		want
+	}{
+		"Create": {
+			reason: "A resource without state should be created.",
+			things: map[string]map[string]interface{}{},
+			args: args{
+				params: map[string]interface{}{"name": "a", "size": 1},
+			},
+			want: want{
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": float64(1), "password": nil, "arn": "arn:a"},
+				things: map[string]map[string]interface{}{"a": {"size": 1, "password": ""}},
+			},
+		},
+		"Update": {
+			reason: "An existing resource should be updated in place.",
+			things: map[string]map[string]interface{}{"a": {"size": 1}},
+			args: args{
+				params: map[string]interface{}{"name": "a", "size": 2},
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": 1, "arn": "arn:a"},
+			},
+			want: want{
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": float64(2), "password": nil, "arn": "arn:a"},
+				things: map[string]map[string]interface{}{"a": {"size": 2}},
+			},
+		},
+		"ReplaceNotAllowed": {
+			reason: "A resource that requires replacement should not be replaced unless it's allowed.",
+			things: map[string]map[string]interface{}{"a": {"size": 1}},
+			args: args{
+				params: map[string]interface{}{"name": "b", "size": 1},
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": 1, "arn": "arn:a"},
+			},
+			want: want{
+				things: map[string]map[string]interface{}{"a": {"size": 1}},
+				err:    tferrors.NewApplyFailed(errorLog("Instance cannot be destroyed", "The resource requires replacement but its replacement is not allowed.")),
+			},
+		},
+		"Replace": {
+			reason: "A resource that requires replacement should be destroyed and created again if it's allowed.",
+			things: map[string]map[string]interface{}{"a": {"size": 1}},
+			args: args{
+				params:         map[string]interface{}{"name": "b", "size": 1},
+				attr:           map[string]interface{}{"id": "a", "name": "a", "size": 1, "arn": "arn:a"},
+				replaceAllowed: true,
+			},
+			want: want{
+				attr:   map[string]interface{}{"id": "b", "name": "b", "size": float64(1), "password": nil, "arn": "arn:b"},
+				things: map[string]map[string]interface{}{"b": {"size": 1, "password": ""}},
+			},
+		},
+		"Failure": {
+			reason: "The error diagnostics of the provider should be reported as an apply failure.",
+			things: map[string]map[string]interface{}{},
+			args: args{
+				params: map[string]interface{}{"name": "a", "size": -1},
+			},
+			want: want{
+				things: map[string]map[string]interface{}{},
+				err:    tferrors.NewApplyFailed(errorLog("size cannot be negative", "")),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := &thingAPI{things: tc.things}
+			w := newInProcessWorkspace(t, a, tc.args.params, tc.args.attr, tc.args.replaceAllowed)
+			res, err := w.Apply(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nApply(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.attr, stateAttributes(t, res.State)); diff != "" {
+				t.Errorf("\n%s\nApply(...): -want attributes, +got attributes:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.things, a.things); diff != "" {
+				t.Errorf("\n%s\nApply(...): -want things, +got things:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInProcessWorkspacePlan(t *testing.T) {
+	type args struct {
+		params map[string]interface{}
+		attr   map[string]interface{}
+	}
+	type want struct {
+		r   PlanResult
+		err error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NotExists": {
+			reason: "A resource without state should be reported as not existing.",
+			args: args{
+				params: map[string]interface{}{"name": "a"},
+			},
+		},
+		"UpToDate": {
+			reason: "A resource whose state matches its configuration should be up-to-date.",
+			args: args{
+				params: map[string]interface{}{"name": "a", "size": 1},
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": 1, "arn": "arn:a"},
+			},
+			want: want{
+				r: PlanResult{Exists: true, UpToDate: true},
+			},
+		},
+		"Update": {
+			reason: "The attributes that will be changed should be reported with the sensitive ones redacted.",
+			args: args{
+				params: map[string]interface{}{"name": "a", "size": 2, "password": "secret"},
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": 1, "arn": "arn:a"},
+			},
+			want: want{
+				r: PlanResult{
+					Exists: true,
+					Diff: PlanDiff{
+						{Path: "password", Sensitive: true},
+						{Path: "size", Before: float64(1), After: float64(2)},
+					},
+				},
+			},
+		},
+		"Replace": {
+			reason: "A resource whose immutable attributes change should require replacement.",
+			args: args{
+				params: map[string]interface{}{"name": "b", "size": 1},
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": 1, "arn": "arn:a"},
+			},
+			want: want{
+				r: PlanResult{
+					Exists:          true,
+					RequiresReplace: true,
+					Diff: PlanDiff{
+						{Path: "name", Before: "a", After: "b"},
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := newInProcessWorkspace(t, &thingAPI{things: map[string]map[string]interface{}{}}, tc.args.params, tc.args.attr, false)
+			r, err := w.Plan(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nPlan(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.r, r); diff != "" {
+				t.Errorf("\n%s\nPlan(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInProcessWorkspaceRefresh(t *testing.T) {
+	type want struct {
+		exists bool
+		attr   map[string]interface{}
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		things map[string]map[string]interface{}
+		attr   map[string]interface{}
+		want
+	}{
+		"NoState": {
+			reason: "A resource without state should not exist.",
+		},
+		"Exists": {
+			reason: "The state of an existing resource should be read from the provider.",
+			things: map[string]map[string]interface{}{"a": {"size": 3}},
+			attr:   map[string]interface{}{"id": "a", "name": "a", "size": 1},
+			want: want{
+				exists: true,
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": float64(3), "password": nil, "arn": "arn:a"},
+			},
+		},
+		"Gone": {
+			reason: "A resource that's not found by the provider should not exist.",
+			attr:   map[string]interface{}{"id": "a", "name": "a", "size": 1},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := &thingAPI{things: tc.things}
+			if a.things == nil {
+				a.things = map[string]map[string]interface{}{}
+			}
+			w := newInProcessWorkspace(t, a, map[string]interface{}{"name": "a"}, tc.attr, false)
+			r, err := w.Refresh(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nRefresh(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.exists, r.Exists); diff != "" {
+				t.Errorf("\n%s\nRefresh(...): -want exists, +got exists:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.attr, stateAttributes(t, r.State)); diff != "" {
+				t.Errorf("\n%s\nRefresh(...): -want attributes, +got attributes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInProcessWorkspaceDestroy(t *testing.T) {
+	a := &thingAPI{things: map[string]map[string]interface{}{"a": {"size": 1}}}
+	w := newInProcessWorkspace(t, a, map[string]interface{}{"name": "a", "size": 1}, map[string]interface{}{"id": "a", "name": "a", "size": 1, "arn": "arn:a"}, false)
+	if err := w.Destroy(context.TODO()); err != nil {
+		t.Fatalf("Destroy(...): unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(map[string]map[string]interface{}{}, a.things); diff != "" {
+		t.Errorf("Destroy(...): -want things, +got things:\n%s", diff)
+	}
+	r, err := w.Refresh(context.TODO())
+	if err != nil {
+		t.Fatalf("Refresh(...): unexpected error: %s", err)
+	}
+	if r.Exists {
+		t.Errorf("Refresh(...): destroyed resource should not exist")
+	}
+}
+
+func TestInProcessWorkspaceReadDataSource(t *testing.T) {
+	type want struct {
+		exists bool
+		attr   map[string]interface{}
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		things map[string]map[string]interface{}
+		want
+	}{
+		"Found": {
+			reason: "The data source should be read with the desired configuration.",
+			things: map[string]map[string]interface{}{"a": {"size": 3}},
+			want: want{
+				exists: true,
+				attr:   map[string]interface{}{"id": "a", "name": "a", "size": float64(3)},
+			},
+		},
+		"NotFound": {
+			reason: "The failure of reading the data source should be returned.",
+			things: map[string]map[string]interface{}{},
+			want: want{
+				err: tferrors.NewRefreshFailed(errorLog("thing a is not found", "")),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			a := &thingAPI{things: tc.things}
+			p := a.provider()
+			s := schema.NewGRPCProviderServer(p)
+			if _, err := s.ConfigureProvider(context.TODO(), &tfprotov5.ConfigureProviderRequest{Config: mustDynamicValue(t, p)}); err != nil {
+				t.Fatalf("cannot configure provider: %s", err)
+			}
+			w := NewInProcessWorkspace(s, inProcessType, p.DataSourcesMap[inProcessType], logging.NewNopLogger())
+			w.dataSource = true
+			if err := w.update(s, map[string]interface{}{"name": "a"}, false); err != nil {
+				t.Fatalf("cannot update workspace: %s", err)
+			}
+			r, err := w.Refresh(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nRefresh(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.exists, r.Exists); diff != "" {
+				t.Errorf("\n%s\nRefresh(...): -want exists, +got exists:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.attr, stateAttributes(t, r.State)); diff != "" {
+				t.Errorf("\n%s\nRefresh(...): -want attributes, +got attributes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInProcessWorkspaceStoreServers(t *testing.T) {
+	a := &thingAPI{things: map[string]map[string]interface{}{}}
+	var mu sync.Mutex
+	configured := 0
+	ws := NewInProcessWorkspaceStore(logging.NewNopLogger(), func() *schema.Provider {
+		mu.Lock()
+		configured++
+		mu.Unlock()
+		p := a.provider()
+		p.Schema = map[string]*schema.Schema{
+			"token": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		}
+		return p
+	})
+	setup := func(token string) Setup {
+		return Setup{Configuration: map[string]interface{}{"token": token}}
+	}
+
+	// The concurrent calls with the same configuration share a server.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := ws.server(context.TODO(), setup("old")); err != nil {
+				t.Errorf("server(...): %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if configured != 1 {
+		t.Errorf("server(...): a provider configuration should be configured once, configured %d times", configured)
+	}
+
+	old, oldKey, _ := ws.server(context.TODO(), setup("old"))
+	ws.mu.Lock()
+	ws.use("a", oldKey, old)
+	ws.use("b", oldKey, old)
+	ws.mu.Unlock()
+	// The credentials of the workspaces are rotated one by one.
+	rotated, rotatedKey, err := ws.server(context.TODO(), setup("rotated"))
+	if err != nil {
+		t.Fatalf("server(...): %v", err)
+	}
+	ws.mu.Lock()
+	ws.use("a", rotatedKey, rotated)
+	if diff := cmp.Diff(2, len(ws.servers)); diff != "" {
+		t.Errorf("use(...): a server that is still used should be kept: -want, +got:\n%s", diff)
+	}
+	ws.use("b", rotatedKey, rotated)
+	if _, ok := ws.servers[oldKey]; ok {
+		t.Error("use(...): a server that is not used anymore should be dropped")
+	}
+	ws.release("a")
+	ws.release("b")
+	if diff := cmp.Diff(0, len(ws.servers)+len(ws.refs)+len(ws.keys)); diff != "" {
+		t.Errorf("release(...): the servers of the released workspaces should be dropped: -want, +got:\n%s", diff)
+	}
+	ws.mu.Unlock()
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsNamespace = "terrajet"
+	metricsSubsystem = "terraform"
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	// cliDuration is the duration of the Terraform CLI processes by the
+	// operation, the Terraform resource type and the result.
+	cliDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "cli_duration_seconds",
+		Help:      "Duration of the Terraform CLI operations.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 10),
+	}, []string{"operation", "resource", "result"})
+
+	// asyncOperations is the number of the running async operations by the
+	// operation.
+	asyncOperations = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "async_operations",
+		Help:      "Number of the running async Terraform operations.",
+	}, []string{"operation"})
+
+	// schedulerQueueDepth is the number of the operations that are waiting
+	// for their turn in the Scheduler by whether they're async, see
+	// Scheduler.QueueDepth.
+	schedulerQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "scheduler_queue_depth",
+		Help:      "Number of the Terraform operations waiting for their turn to start.",
+	}, []string{"async"})
+
+	// workspaces is the number of the workspaces in the WorkspaceStore.
+	workspaces = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "workspaces",
+		Help:      "Number of the Terraform workspaces.",
+	})
+
+	// workspaceDiskBytes is the total size of the files in the workspace
+	// directories.
+	workspaceDiskBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "workspace_disk_bytes",
+		Help:      "Total size of the files in the Terraform workspace directories.",
+	})
+
+	// providerRestarts is the number of the times a shared native provider
+	// process exited unexpectedly and had to be started again.
+	providerRestarts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "provider_restarts_total",
+		Help:      "Number of the times a shared native provider process exited unexpectedly.",
+	})
+)
+
+func init() {
+	// The metrics are served on the /metrics endpoint of the manager.
+	metrics.Registry.MustRegister(cliDuration, asyncOperations, schedulerQueueDepth, workspaces, workspaceDiskBytes, providerRestarts)
+}
+
+// observeCLIDuration records the duration of a Terraform CLI operation of
+// the given resource type that started at the given time.
+func observeCLIDuration(op, resourceType string, start time.Time, err error) {
+	result := resultSuccess
+	if err != nil {
+		result = resultFailure
+	}
+	cliDuration.WithLabelValues(op, resourceType, result).Observe(time.Since(start).Seconds())
+}
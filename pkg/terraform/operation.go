@@ -19,15 +19,51 @@ package terraform
 import (
 	"sync"
 	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	// operationRecordFile is the file in the workspace directory where the
+	// last async operation is recorded.
+	operationRecordFile = ".terrajet-operation.json"
 )
 
+// processID identifies the provider process that runs the operations. It's
+// used to tell whether a recorded operation that hasn't ended belongs to
+// the current process or to a previous one that didn't get to record its
+// end, e.g. because the provider was restarted.
+var processID = string(uuid.NewUUID())
+
+// OperationRecord is the persisted form of an Operation.
+type OperationRecord struct {
+	Type      string     `json:"type"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+	EndTime   *time.Time `json:"endTime,omitempty"`
+	// ProcessID identifies the provider process that ran the operation.
+	ProcessID string `json:"processID"`
+}
+
+// IsInterrupted returns whether the recorded operation was started by
+// another process and never ended.
+func (r OperationRecord) IsInterrupted() bool {
+	return r.StartTime != nil && r.EndTime == nil && r.ProcessID != processID
+}
+
 // Operation is the representation of a single Terraform CLI operation.
 type Operation struct {
 	Type string
 
 	startTime *time.Time
 	endTime   *time.Time
-	mu        sync.RWMutex
+	waiting   bool
+	cancel    func()
+	kill      func()
+	cancelled bool
+	// cancelTime is when the ongoing operation was cancelled.
+	cancelTime time.Time
+	done       chan struct{}
+	mu         sync.RWMutex
 }
 
 // MarkStart marks the operation as started.
@@ -38,6 +74,12 @@ func (o *Operation) MarkStart(t string) {
 	o.Type = t
 	o.startTime = &now
 	o.endTime = nil
+	o.waiting = false
+	o.cancel = nil
+	o.kill = nil
+	o.cancelled = false
+	o.cancelTime = time.Time{}
+	o.done = make(chan struct{})
 }
 
 // MarkEnd marks the operation as ended.
@@ -46,6 +88,13 @@ func (o *Operation) MarkEnd() {
 	defer o.mu.Unlock()
 	now := time.Now()
 	o.endTime = &now
+	o.waiting = false
+	o.cancel = nil
+	o.kill = nil
+	if o.done != nil {
+		close(o.done)
+		o.done = nil
+	}
 }
 
 // Flush cleans the operation information.
@@ -55,6 +104,123 @@ func (o *Operation) Flush() {
 	o.Type = ""
 	o.startTime = nil
 	o.endTime = nil
+	o.cancel = nil
+	o.kill = nil
+	o.cancelled = false
+	o.cancelTime = time.Time{}
+}
+
+// SetWaiting sets whether the ongoing operation is waiting for its turn to
+// start the Terraform CLI process.
+func (o *Operation) SetWaiting(waiting bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.waiting = waiting
+}
+
+// IsWaiting returns whether the ongoing operation is waiting for its turn to
+// start the Terraform CLI process.
+func (o *Operation) IsWaiting() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.waiting && o.startTime != nil && o.endTime == nil
+}
+
+// SetCancel sets the function that stops the ongoing operation gracefully.
+// If the operation has already been cancelled, the function is called right
+// away so that a process started after the cancellation is stopped as well.
+func (o *Operation) SetCancel(fn func()) {
+	o.mu.Lock()
+	o.cancel = fn
+	cancelled := o.cancelled
+	o.mu.Unlock()
+	if cancelled && fn != nil {
+		fn()
+	}
+}
+
+// SetKill sets the function that stops the ongoing operation forcibly.
+func (o *Operation) SetKill(fn func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.kill = fn
+}
+
+// Cancel requests the ongoing operation to stop and reports whether there is
+// an ongoing operation to stop. The request is latched, i.e. if the operation
+// cannot be stopped yet, it's stopped as soon as its cancel function is set.
+// The operation is not guaranteed to be stopped when Cancel returns, see
+// Done.
+func (o *Operation) Cancel() bool {
+	o.mu.Lock()
+	running := o.startTime != nil && o.endTime == nil
+	if running && !o.cancelled {
+		o.cancelled = true
+		o.cancelTime = time.Now()
+	}
+	cancel := o.cancel
+	o.mu.Unlock()
+	if !running {
+		return false
+	}
+	if cancel != nil {
+		cancel()
+	}
+	return true
+}
+
+// Kill stops the ongoing operation forcibly and reports whether it could be
+// stopped, see SetKill.
+func (o *Operation) Kill() bool {
+	o.mu.RLock()
+	kill := o.kill
+	running := o.startTime != nil && o.endTime == nil
+	o.mu.RUnlock()
+	if kill == nil || !running {
+		return false
+	}
+	kill()
+	return true
+}
+
+// IsCancelled returns whether the ongoing operation has been cancelled.
+func (o *Operation) IsCancelled() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.cancelled
+}
+
+// CancelTime returns when the ongoing operation was cancelled, or the zero
+// time if it hasn't been cancelled.
+func (o *Operation) CancelTime() time.Time {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.cancelTime
+}
+
+// Done returns a channel that's closed when the ongoing operation ends. If
+// there is no ongoing operation, the returned channel is already closed.
+func (o *Operation) Done() <-chan struct{} {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.done == nil || o.startTime == nil || o.endTime != nil {
+		c := make(chan struct{})
+		close(c)
+		return c
+	}
+	return o.done
+}
+
+// Record returns the OperationRecord of the operation to be persisted.
+func (o *Operation) Record() OperationRecord {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return OperationRecord{
+		Type:      o.Type,
+		StartTime: o.startTime,
+		EndTime:   o.endTime,
+		ProcessID: processID,
+	}
 }
 
 // IsEnded returns whether the operation has ended, regardless of its result.
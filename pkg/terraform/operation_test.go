@@ -62,6 +62,67 @@ func TestOperation(t *testing.T) {
 				result: true,
 			},
 		},
+		"Cancelled": {
+			args: args{
+				calls: func(o *Operation) {
+					o.MarkStart("type")
+					o.SetCancel(o.MarkEnd)
+				},
+			},
+			want: want{
+				checks: func(o *Operation) bool {
+					cancelled := o.Cancel()
+					<-o.Done()
+					return cancelled && o.IsEnded()
+				},
+				result: true,
+			},
+		},
+		"CancelledBeforeStop": {
+			args: args{
+				calls: func(o *Operation) {
+					o.MarkStart("type")
+					o.Cancel()
+					o.SetCancel(o.MarkEnd)
+				},
+			},
+			want: want{
+				checks: func(o *Operation) bool {
+					<-o.Done()
+					return o.IsCancelled() && o.IsEnded()
+				},
+				result: true,
+			},
+		},
+		"NotRunning": {
+			args: args{
+				calls: func(o *Operation) {
+					o.SetCancel(func() {})
+				},
+			},
+			want: want{
+				checks: func(o *Operation) bool {
+					return o.Cancel() || o.IsCancelled()
+				},
+				result: false,
+			},
+		},
+		"Killed": {
+			args: args{
+				calls: func(o *Operation) {
+					o.MarkStart("type")
+					o.SetKill(o.MarkEnd)
+				},
+			},
+			want: want{
+				checks: func(o *Operation) bool {
+					killed := o.Kill()
+					<-o.Done()
+					return killed && o.IsEnded()
+				},
+				result: true,
+			},
+		},
 		"Flushed": {
 			args: args{
 				calls: func(o *Operation) {
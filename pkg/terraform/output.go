@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+// Types of the machine-readable UI messages emitted by Terraform CLI when
+// it's run with -json flag:
+// https://www.terraform.io/internals/machine-readable-ui#message-types
+const (
+	MessageTypeApplyStart    = "apply_start"
+	MessageTypeApplyProgress = "apply_progress"
+	MessageTypeApplyComplete = "apply_complete"
+	MessageTypeApplyErrored  = "apply_errored"
+	MessageTypeDiagnostic    = "diagnostic"
+	MessageTypeChangeSummary = "change_summary"
+)
+
+const (
+	reasonApplyStart    event.Reason = "TerraformApplyStart"
+	reasonApplyComplete event.Reason = "TerraformApplyComplete"
+	reasonApplyErrored  event.Reason = "TerraformApplyErrored"
+	reasonDiagnostic    event.Reason = "TerraformDiagnostic"
+)
+
+// UIMessage is a single line of the machine-readable UI output of Terraform
+// CLI.
+type UIMessage struct {
+	Level      string                  `json:"@level"`
+	Message    string                  `json:"@message"`
+	Module     string                  `json:"@module"`
+	Timestamp  string                  `json:"@timestamp"`
+	Type       string                  `json:"type"`
+	Hook       *UIHook                 `json:"hook,omitempty"`
+	Diagnostic *tferrors.LogDiagnostic `json:"diagnostic,omitempty"`
+}
+
+// UIHook contains the details of an apply_* message about the resource
+// being operated on.
+type UIHook struct {
+	Resource       UIResource `json:"resource"`
+	Action         string     `json:"action"`
+	IDKey          string     `json:"id_key,omitempty"`
+	IDValue        string     `json:"id_value,omitempty"`
+	ElapsedSeconds float64    `json:"elapsed_seconds,omitempty"`
+}
+
+// UIResource identifies the resource an apply_* message is about.
+type UIResource struct {
+	Addr         string `json:"addr"`
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+}
+
+// OutputSink consumes the output of Terraform CLI while an operation is still
+// running.
+type OutputSink interface {
+	// Consume is called for every line of the output of the given operation
+	// type as soon as it's written by Terraform CLI. Lines that are not in
+	// machine-readable UI format are passed with only Message set.
+	Consume(op string, m UIMessage)
+}
+
+// OutputSinkFn is a function that implements the OutputSink interface. It
+// can be used to report progress of operations.
+type OutputSinkFn func(op string, m UIMessage)
+
+// Consume calls the OutputSinkFn.
+func (fn OutputSinkFn) Consume(op string, m UIMessage) {
+	fn(op, m)
+}
+
+// OutputSinks fans the output out to all of its OutputSinks.
+type OutputSinks []OutputSink
+
+// Consume calls Consume of each OutputSink serially.
+func (ss OutputSinks) Consume(op string, m UIMessage) {
+	for _, s := range ss {
+		s.Consume(op, m)
+	}
+}
+
+// NewLoggerSink returns an OutputSink that writes every message to the given
+// logger in debug level.
+func NewLoggerSink(l logging.Logger) OutputSinkFn {
+	return func(op string, m UIMessage) {
+		kv := []interface{}{"operation", op, "message", m.Message}
+		if m.Type != "" {
+			kv = append(kv, "type", m.Type)
+		}
+		l.Debug("terraform output", kv...)
+	}
+}
+
+// NewEventSink returns an OutputSink that records the start, completion and
+// failure of the resource operations as well as the diagnostics as events on
+// the given object. Progress messages are skipped not to flood the event
+// stream of the object.
+func NewEventSink(r event.Recorder, obj runtime.Object) OutputSinkFn {
+	return func(op string, m UIMessage) {
+		switch m.Type {
+		case MessageTypeApplyStart:
+			r.Event(obj, event.Normal(reasonApplyStart, m.Message, "operation", op))
+		case MessageTypeApplyComplete:
+			r.Event(obj, event.Normal(reasonApplyComplete, m.Message, "operation", op))
+		case MessageTypeApplyErrored:
+			r.Event(obj, event.Warning(reasonApplyErrored, errors.New(m.Message), "operation", op))
+		case MessageTypeDiagnostic:
+			if m.Diagnostic == nil {
+				return
+			}
+			msg := fmt.Sprintf("%s: %s", m.Diagnostic.Summary, m.Diagnostic.Detail)
+			if m.Diagnostic.Severity == "error" {
+				r.Event(obj, event.Warning(reasonDiagnostic, errors.New(msg), "operation", op))
+				return
+			}
+			r.Event(obj, event.Normal(reasonDiagnostic, msg, "operation", op))
+		}
+	}
+}
+
+// NewOutputSinkFn returns the OutputSink to be used for the workspace of the
+// given object.
+type NewOutputSinkFn func(obj runtime.Object, l logging.Logger) OutputSink
+
+// outputWriter is an io.Writer that splits what's written into lines and
+// sends them to an OutputSink while keeping a copy of the whole output.
+type outputWriter struct {
+	op   string
+	sink OutputSink
+
+	mu      sync.Mutex
+	out     bytes.Buffer
+	partial []byte
+}
+
+func newOutputWriter(op string, s OutputSink) *outputWriter {
+	return &outputWriter{op: op, sink: s}
+}
+
+// Write is safe to be called concurrently so that the same writer can be
+// used for both stdout and stderr.
+func (ow *outputWriter) Write(p []byte) (int, error) {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	ow.out.Write(p)
+	ow.partial = append(ow.partial, p...)
+	for {
+		i := bytes.IndexByte(ow.partial, '\n')
+		if i < 0 {
+			break
+		}
+		ow.consume(ow.partial[:i])
+		ow.partial = ow.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush sends the last line to the sink if it's not terminated by a newline.
+func (ow *outputWriter) Flush() {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	ow.consume(ow.partial)
+	ow.partial = nil
+}
+
+// Bytes returns everything that has been written so far.
+func (ow *outputWriter) Bytes() []byte {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	return ow.out.Bytes()
+}
+
+func (ow *outputWriter) consume(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	m := UIMessage{}
+	if err := json.JSParser.Unmarshal(line, &m); err != nil {
+		m = UIMessage{Message: string(line)}
+	}
+	ow.sink.Consume(ow.op, m)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+)
+
+var (
+	applyStart    = `{"@level":"info","@message":"very-cool-type.name: Creating...","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","hook":{"resource":{"addr":"very-cool-type.name","resource_type":"very-cool-type","resource_name":"name"},"action":"create"},"type":"apply_start"}`
+	applyComplete = `{"@level":"info","@message":"very-cool-type.name: Creation complete after 1s [id=some-id]","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","hook":{"resource":{"addr":"very-cool-type.name","resource_type":"very-cool-type","resource_name":"name"},"action":"create","id_key":"id","id_value":"some-id","elapsed_seconds":1},"type":"apply_complete"}`
+	diagnosticErr = `{"@level":"error","@message":"Error: boom","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","diagnostic":{"severity":"error","summary":"boom","detail":"details"},"type":"diagnostic"}`
+)
+
+type recorder struct {
+	events []event.Event
+}
+
+func (r *recorder) Event(_ runtime.Object, e event.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) WithAnnotations(_ ...string) event.Recorder {
+	return r
+}
+
+func TestOutputWriter(t *testing.T) {
+	type args struct {
+		writes []string
+	}
+	type want struct {
+		messages []UIMessage
+		out      string
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"MachineReadable": {
+			reason: "Each machine-readable line should be parsed and sent to the sink.",
+			args: args{
+				writes: []string{applyStart + "\n" + applyComplete + "\n"},
+			},
+			want: want{
+				messages: []UIMessage{
+					{
+						Level:     "info",
+						Message:   "very-cool-type.name: Creating...",
+						Module:    "terraform.ui",
+						Timestamp: "0000-00-00T00:00:00.000000+03:00",
+						Type:      MessageTypeApplyStart,
+						Hook: &UIHook{
+							Resource: UIResource{Addr: "very-cool-type.name", ResourceType: "very-cool-type", ResourceName: "name"},
+							Action:   "create",
+						},
+					},
+					{
+						Level:     "info",
+						Message:   "very-cool-type.name: Creation complete after 1s [id=some-id]",
+						Module:    "terraform.ui",
+						Timestamp: "0000-00-00T00:00:00.000000+03:00",
+						Type:      MessageTypeApplyComplete,
+						Hook: &UIHook{
+							Resource:       UIResource{Addr: "very-cool-type.name", ResourceType: "very-cool-type", ResourceName: "name"},
+							Action:         "create",
+							IDKey:          "id",
+							IDValue:        "some-id",
+							ElapsedSeconds: 1,
+						},
+					},
+				},
+				out: applyStart + "\n" + applyComplete + "\n",
+			},
+		},
+		"PartialWrites": {
+			reason: "Lines that are split into multiple writes should be sent once they are complete.",
+			args: args{
+				writes: []string{"first ", "line\nsecond", " line"},
+			},
+			want: want{
+				messages: []UIMessage{
+					{Message: "first line"},
+					{Message: "second line"},
+				},
+				out: "first line\nsecond line",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got []UIMessage
+			ow := newOutputWriter(applyType, OutputSinkFn(func(op string, m UIMessage) {
+				if op != applyType {
+					t.Errorf("\n%s\nConsume(...): unexpected operation type %q", tc.reason, op)
+				}
+				got = append(got, m)
+			}))
+			for _, w := range tc.args.writes {
+				if _, err := ow.Write([]byte(w)); err != nil {
+					t.Fatalf("Write(...): %v", err)
+				}
+			}
+			ow.Flush()
+			if diff := cmp.Diff(tc.want.messages, got); diff != "" {
+				t.Errorf("\n%s\nConsume(...): -want messages, +got messages:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.out, string(ow.Bytes())); diff != "" {
+				t.Errorf("\n%s\nBytes(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestEventSink(t *testing.T) {
+	type want struct {
+		types   []event.Type
+		reasons []event.Reason
+	}
+	cases := map[string]struct {
+		reason string
+		m      UIMessage
+		want
+	}{
+		"ApplyStart": {
+			reason: "An event should be recorded when the apply starts.",
+			m:      UIMessage{Type: MessageTypeApplyStart, Message: "creating"},
+			want: want{
+				types:   []event.Type{event.TypeNormal},
+				reasons: []event.Reason{reasonApplyStart},
+			},
+		},
+		"Progress": {
+			reason: "Progress messages should not be recorded.",
+			m:      UIMessage{Type: MessageTypeApplyProgress, Message: "still creating"},
+		},
+		"ErrorDiagnostic": {
+			reason: "Error diagnostics should be recorded as warnings.",
+			m: UIMessage{Type: MessageTypeDiagnostic, Diagnostic: &tferrors.LogDiagnostic{
+				Severity: "error",
+				Summary:  "boom",
+			}},
+			want: want{
+				types:   []event.Type{event.TypeWarning},
+				reasons: []event.Reason{reasonDiagnostic},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := &recorder{}
+			NewEventSink(r, &xpfake.Managed{}).Consume(applyType, tc.m)
+			var types []event.Type
+			var reasons []event.Reason
+			for _, e := range r.events {
+				types = append(types, e.Type)
+				reasons = append(reasons, e.Reason)
+			}
+			if diff := cmp.Diff(tc.want.types, types); diff != "" {
+				t.Errorf("\n%s\nConsume(...): -want types, +got types:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.reasons, reasons); diff != "" {
+				t.Errorf("\n%s\nConsume(...): -want reasons, +got reasons:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
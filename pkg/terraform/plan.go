@@ -0,0 +1,195 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	"github.com/crossplane/terrajet/pkg/workspace"
+)
+
+const (
+	planFile = "terraform.tfplan"
+	// planFileStateEntry is the entry of the plan file archive that contains
+	// the refreshed state that the plan is based on.
+	planFileStateEntry = "tfstate"
+
+	// summaryDestroyPrevented is the summary of the diagnostic Terraform
+	// reports when a plan needs to destroy a resource whose prevent_destroy
+	// lifecycle argument is set.
+	summaryDestroyPrevented = "Instance cannot be destroyed"
+)
+
+// AttributeDiff is the difference of a single attribute between the current
+// state and the desired configuration of a resource.
+type AttributeDiff = workspace.AttributeDiff
+
+// PlanDiff is the list of attributes whose desired values differ from their
+// current values.
+type PlanDiff = workspace.PlanDiff
+
+// planFileState returns the refreshed state in the given plan file, which is
+// a zip archive.
+func planFileState(raw []byte) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot open plan file")
+	}
+	for _, f := range r.File {
+		if f.Name != planFileStateEntry {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot open state in plan file")
+		}
+		defer rc.Close() // nolint:errcheck
+		s, err := io.ReadAll(rc)
+		return s, errors.Wrap(err, "cannot read state in plan file")
+	}
+	return nil, errors.New("cannot find state in plan file")
+}
+
+// ResourceChange returns the planned change of the resource from the JSON
+// representation of a plan, i.e. output of "terraform show -json <plan>".
+// Since the workspaces contain a single resource, the first managed resource
+// change is returned.
+func ResourceChange(raw []byte) (*tfjson.Change, error) {
+	p := &tfjson.Plan{}
+	if err := p.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+	for _, rc := range p.ResourceChanges {
+		if rc.Mode == tfjson.ManagedResourceMode && rc.Change != nil {
+			return rc.Change, nil
+		}
+	}
+	return &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}, nil
+}
+
+// NewPlanDiff returns the attribute level diff of the given change.
+func NewPlanDiff(c *tfjson.Change) PlanDiff {
+	before, after := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", c.Before, before)
+	flatten("", c.After, after)
+	sensitive, unknown := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", c.BeforeSensitive, sensitive)
+	flatten("", c.AfterSensitive, sensitive)
+	flatten("", c.AfterUnknown, unknown)
+
+	paths := map[string]struct{}{}
+	for p := range before {
+		paths[p] = struct{}{}
+	}
+	for p := range after {
+		paths[p] = struct{}{}
+	}
+	for p, v := range unknown {
+		if v == true {
+			paths[p] = struct{}{}
+		}
+	}
+	var pd PlanDiff
+	for p := range paths {
+		ad := AttributeDiff{
+			Path:      p,
+			Sensitive: isMarked(sensitive, p),
+			Unknown:   isMarked(unknown, p),
+		}
+		b, a := before[p], after[p]
+		if !ad.Unknown && workspace.FormatValue(b) == workspace.FormatValue(a) {
+			continue
+		}
+		if !ad.Sensitive {
+			ad.Before, ad.After = b, a
+		}
+		pd = append(pd, ad)
+	}
+	sort.Slice(pd, func(i, j int) bool {
+		return pd[i].Path < pd[j].Path
+	})
+	return pd
+}
+
+// isDestroyPrevented returns whether the given plan output reports that the
+// resource has to be destroyed while it's protected against destruction. Since
+// a plan never destroys the resource in the workspace on its own, this means
+// the resource needs to be replaced.
+func isDestroyPrevented(out []byte) bool {
+	for _, l := range strings.Split(string(out), "\n") {
+		m := UIMessage{}
+		if err := json.JSParser.UnmarshalFromString(l, &m); err != nil {
+			continue
+		}
+		if m.Diagnostic != nil && m.Diagnostic.Summary == summaryDestroyPrevented {
+			return true
+		}
+	}
+	return false
+}
+
+// flatten writes the leaf values of the given object to out with their
+// paths as keys. Empty maps and lists are skipped.
+func flatten(prefix string, v interface{}, out map[string]interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			flatten(joinPath(prefix, k), e, out)
+		}
+	case []interface{}:
+		for i, e := range t {
+			flatten(joinPath(prefix, strconv.Itoa(i)), e, out)
+		}
+	case nil:
+	default:
+		if prefix != "" {
+			out[prefix] = t
+		}
+	}
+}
+
+// isMarked returns whether the given path or any of its parents is marked
+// with true in the given flattened marks. It's used to process sensitive
+// and unknown markers where a whole object could be marked.
+func isMarked(marks map[string]interface{}, path string) bool {
+	for p := path; ; {
+		if marks[p] == true {
+			return true
+		}
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			return false
+		}
+		p = p[:i]
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
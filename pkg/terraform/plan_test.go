@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+func TestNewPlanDiff(t *testing.T) {
+	type want struct {
+		diff    PlanDiff
+		summary string
+	}
+	cases := map[string]struct {
+		reason string
+		change *tfjson.Change
+		want
+	}{
+		"NoChange": {
+			reason: "There should be no diff if before and after are the same.",
+			change: &tfjson.Change{
+				Before: map[string]interface{}{"id": "some-id", "name": "a"},
+				After:  map[string]interface{}{"id": "some-id", "name": "a"},
+			},
+		},
+		"NestedChanges": {
+			reason: "Changed, added and removed nested attributes should be listed in order.",
+			change: &tfjson.Change{
+				Before: map[string]interface{}{
+					"tags": map[string]interface{}{"env": "dev", "team": "a"},
+					"rule": []interface{}{map[string]interface{}{"port": float64(80)}},
+				},
+				After: map[string]interface{}{
+					"tags": map[string]interface{}{"env": "prod", "owner": "b"},
+					"rule": []interface{}{map[string]interface{}{"port": float64(443)}},
+				},
+			},
+			want: want{
+				diff: PlanDiff{
+					{Path: "rule.0.port", Before: float64(80), After: float64(443)},
+					{Path: "tags.env", Before: "dev", After: "prod"},
+					{Path: "tags.owner", After: "b"},
+					{Path: "tags.team", Before: "a"},
+				},
+				summary: `rule.0.port: 80 => 443; tags.env: "dev" => "prod"; tags.owner: null => "b"; tags.team: "a" => null`,
+			},
+		},
+		"SensitiveAndUnknown": {
+			reason: "Sensitive values should be masked and unknown values should be marked.",
+			change: &tfjson.Change{
+				Before:          map[string]interface{}{"password": "old", "arn": "arn-1"},
+				After:           map[string]interface{}{"password": "new"},
+				AfterUnknown:    map[string]interface{}{"arn": true},
+				BeforeSensitive: map[string]interface{}{"password": true},
+				AfterSensitive:  map[string]interface{}{"password": true},
+			},
+			want: want{
+				diff: PlanDiff{
+					{Path: "arn", Before: "arn-1", Unknown: true},
+					{Path: "password", Sensitive: true},
+				},
+				summary: `arn: "arn-1" => (known after apply); password: (sensitive value) => (sensitive value)`,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			diff := NewPlanDiff(tc.change)
+			if diff := cmp.Diff(tc.want.diff, diff); diff != "" {
+				t.Errorf("\n%s\nNewPlanDiff(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.summary, diff.String()); diff != "" {
+				t.Errorf("\n%s\nString(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
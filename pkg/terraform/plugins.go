@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+const (
+	envCLIConfigFile = "TF_CLI_CONFIG_FILE"
+
+	// fileCLIConfig is the name of the CLI configuration file generated in
+	// the root directory of the workspaces.
+	fileCLIConfig = "terrajet.tfrc"
+
+	dirDotTerraform = ".terraform"
+)
+
+// CLIConfig is the configuration of Terraform CLI shared by all workspaces:
+// https://www.terraform.io/cli/config/config-file
+type CLIConfig struct {
+	// PluginCacheDir is the directory where the provider plugins are cached
+	// once they're downloaded, so that terraform init links them to the
+	// workspaces instead of downloading them for every workspace.
+	PluginCacheDir string
+	// FilesystemMirrorDir is a directory pre-populated with the provider
+	// plugins in the layout expected by Terraform, e.g. by running
+	// terraform providers mirror. If set, the providers are installed only
+	// from this directory.
+	FilesystemMirrorDir string
+}
+
+// IsEmpty returns whether no configuration is set.
+func (c CLIConfig) IsEmpty() bool {
+	return c.PluginCacheDir == "" && c.FilesystemMirrorDir == ""
+}
+
+// Render returns the content of the CLI configuration file.
+func (c CLIConfig) Render() string {
+	b := &strings.Builder{}
+	if c.PluginCacheDir != "" {
+		fmt.Fprintf(b, "plugin_cache_dir = %s\n", strconv.Quote(c.PluginCacheDir))
+	}
+	if c.FilesystemMirrorDir != "" {
+		fmt.Fprintf(b, "provider_installation {\n  filesystem_mirror {\n    path = %s\n  }\n}\n", strconv.Quote(c.FilesystemMirrorDir))
+	}
+	return b.String()
+}
+
+// writeCLIConfig writes the CLI configuration file to the given directory,
+// creates the plugin cache directory if needed and returns the environment
+// variable that points Terraform CLI to the file.
+func writeCLIConfig(fs afero.Afero, dir string, c CLIConfig) (string, error) {
+	if c.PluginCacheDir != "" {
+		// Terraform CLI doesn't create the plugin cache directory.
+		if err := fs.MkdirAll(c.PluginCacheDir, os.ModePerm); err != nil {
+			return "", errors.Wrap(err, "cannot create plugin cache directory")
+		}
+	}
+	p := filepath.Join(dir, fileCLIConfig)
+	if err := fs.WriteFile(p, []byte(c.Render()), 0600); err != nil {
+		return "", errors.Wrap(err, "cannot write CLI configuration file")
+	}
+	return fmt.Sprintf(fmtEnv, envCLIConfigFile, p), nil
+}
+
+// copyGoldenWorkspace makes the given workspace directory initialized by
+// copying the dependency lock file and the .terraform directory of the given
+// golden workspace, which is a workspace that has been initialized with the
+// same provider requirements. If the filesystem supports symbolic links, the
+// files in the .terraform directory are linked instead of copied so that
+// the provider plugins are not duplicated. It returns false if the golden
+// workspace hasn't been initialized.
+func copyGoldenWorkspace(fs afero.Afero, golden, dir string) (bool, error) {
+	for _, f := range []string{fileLock, dirDotTerraform} {
+		_, err := fs.Stat(filepath.Join(golden, f))
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "cannot stat %s in golden workspace", f)
+		}
+	}
+	if err := fs.RemoveAll(filepath.Join(dir, dirDotTerraform)); err != nil {
+		return false, errors.Wrap(err, "cannot remove partially initialized .terraform directory")
+	}
+	linker, canLink := fs.Fs.(afero.Linker)
+	err := fs.Walk(filepath.Join(golden, dirDotTerraform), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(golden, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		switch {
+		case info.IsDir():
+			return fs.MkdirAll(target, info.Mode().Perm())
+		case canLink:
+			return linker.SymlinkIfPossible(path, target)
+		default:
+			return copyFile(fs, path, target, info.Mode().Perm())
+		}
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "cannot copy .terraform directory of golden workspace")
+	}
+	// The lock file is always copied since Terraform CLI could update it.
+	if err := copyFile(fs, filepath.Join(golden, fileLock), filepath.Join(dir, fileLock), 0600); err != nil {
+		return false, errors.Wrap(err, "cannot copy dependency lock file of golden workspace")
+	}
+	return true, nil
+}
+
+func copyFile(fs afero.Afero, src, dst string, perm os.FileMode) error {
+	b, err := fs.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return fs.WriteFile(dst, b, perm)
+}
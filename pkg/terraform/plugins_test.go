@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+)
+
+func TestCLIConfigRender(t *testing.T) {
+	type args struct {
+		c CLIConfig
+	}
+	type want struct {
+		out string
+	}
+	cases := map[string]struct {
+		args
+		want
+	}{
+		"Empty": {},
+		"PluginCache": {
+			args: args{
+				c: CLIConfig{PluginCacheDir: "/plugins"},
+			},
+			want: want{
+				out: "plugin_cache_dir = \"/plugins\"\n",
+			},
+		},
+		"FilesystemMirror": {
+			args: args{
+				c: CLIConfig{PluginCacheDir: "/plugins", FilesystemMirrorDir: "/mirror"},
+			},
+			want: want{
+				out: `plugin_cache_dir = "/plugins"
+provider_installation {
+  filesystem_mirror {
+    path = "/mirror"
+  }
+}
+`,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want.out, tc.args.c.Render()); diff != "" {
+				t.Errorf("\n%s\nRender(...): -want out, +got out:\n%s", name, diff)
+			}
+		})
+	}
+}
+
+func TestCopyGoldenWorkspace(t *testing.T) {
+	provider := filepath.Join(dirDotTerraform, "providers", "registry.terraform.io", "hashicorp", "aws", "4.0.0", "linux_amd64", "terraform-provider-aws")
+	type args struct {
+		golden map[string]string
+	}
+	type want struct {
+		copied bool
+		files  map[string]string
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NotInitialized": {
+			reason: "Nothing should be copied if the golden workspace isn't initialized.",
+			args: args{
+				golden: map[string]string{provider: "binary"},
+			},
+			want: want{
+				files: map[string]string{},
+			},
+		},
+		"Copied": {
+			reason: "The .terraform directory and the lock file should be copied.",
+			args: args{
+				golden: map[string]string{provider: "binary", fileLock: "lock"},
+			},
+			want: want{
+				copied: true,
+				files:  map[string]string{provider: "binary", fileLock: "lock"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.Afero{Fs: afero.NewMemMapFs()}
+			for f, c := range tc.args.golden {
+				if err := fs.WriteFile(filepath.Join("/golden", f), []byte(c), os.ModePerm); err != nil {
+					t.Fatalf("WriteFile(...): %v", err)
+				}
+			}
+			copied, err := copyGoldenWorkspace(fs, "/golden", "/workspace")
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ncopyGoldenWorkspace(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.copied, copied); diff != "" {
+				t.Errorf("\n%s\ncopyGoldenWorkspace(...): -want copied, +got copied:\n%s", tc.reason, diff)
+			}
+			files := map[string]string{}
+			for _, f := range []string{provider, fileLock} {
+				if b, err := fs.ReadFile(filepath.Join("/workspace", f)); err == nil {
+					files[f] = string(b)
+				}
+			}
+			if diff := cmp.Diff(tc.want.files, files); diff != "" {
+				t.Errorf("\n%s\ncopyGoldenWorkspace(...): -want files, +got files:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"os"
+	"reflect"
+
+	k8sExec "k8s.io/utils/exec"
+)
+
+// processOf returns the OS process of the given command, or nil if it hasn't
+// been started or it's not backed by an OS process, e.g. a fake command. The
+// commands of k8s.io/utils/exec are os/exec commands underneath but they
+// don't expose their processes. Their Stop method cannot be used instead
+// since it sends SIGTERM and panics if the process hasn't exited in 10
+// seconds without being waited on.
+func processOf(cmd k8sExec.Cmd) *os.Process {
+	v := reflect.ValueOf(cmd)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.Elem().FieldByName("Process")
+	if !f.IsValid() || !f.CanInterface() {
+		return nil
+	}
+	p, _ := f.Interface().(*os.Process)
+	return p
+}
+
+// processSignals returns the functions that send the given signal to the
+// process of the given started command and kill it. The commands that are not
+// backed by an OS process are stopped via their Stop method.
+func processSignals(cmd k8sExec.Cmd, sig os.Signal) (signal func(), kill func()) {
+	p := processOf(cmd)
+	if p == nil {
+		return cmd.Stop, cmd.Stop
+	}
+	return func() { _ = p.Signal(sig) }, func() { _ = p.Kill() }
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+)
+
+func TestProcessOf(t *testing.T) {
+	if p := processOf(&testingexec.FakeCmd{}); p != nil {
+		t.Errorf("processOf(...): a fake command should not have a process, got %v", p)
+	}
+	cmd := exec.New().Command("sleep", "10")
+	if p := processOf(cmd); p != nil {
+		t.Errorf("processOf(...): a command that is not started should not have a process, got %v", p)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	interrupt, _ := processSignals(cmd, syscall.SIGINT)
+	interrupt()
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("processSignals(...): the interrupted process should exit with an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("processSignals(...): the interrupted process did not exit")
+	}
+}
+
+func TestProviderProcessStop(t *testing.T) {
+	cases := map[string]struct {
+		reason    string
+		stopFirst bool
+	}{
+		"Started": {
+			reason: "A started process should be terminated.",
+		},
+		"StoppedBeforeStart": {
+			reason:    "A process that is stopped before it starts should be terminated once it starts.",
+			stopFirst: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := newProcess()
+			if tc.stopFirst {
+				p.stop(time.Second)
+			}
+			cmd := exec.New().Command("sleep", "10")
+			if err := cmd.Start(); err != nil {
+				t.Skipf("cannot start sleep: %v", err)
+			}
+			go func() {
+				_ = cmd.Wait()
+				close(p.exited)
+			}()
+			p.started(cmd)
+			if !tc.stopFirst {
+				p.stop(time.Second)
+			}
+			select {
+			case <-p.exited:
+			case <-time.After(5 * time.Second):
+				t.Errorf("\n%s\nstop(...): the process did not exit", tc.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AssigningProviderRunner is implemented by the ProviderRunners that run more
+// than one native provider process and assign each workspace to one of them.
+type AssigningProviderRunner interface {
+	ProviderRunner
+	// StartFor starts the native provider process assigned to the workspace
+	// with the given UID if it's not already running and returns its
+	// reattach configuration. The workspace is assigned to a process the
+	// first time it's seen.
+	StartFor(uid types.UID, providerConfig string) (string, error)
+	// GenerationFor returns the generation of the native provider process
+	// assigned to the workspace with the given UID, see
+	// SharedProvider.Generation.
+	GenerationFor(uid types.UID) uint64
+	// Release removes the assignment of the workspace with the given UID.
+	Release(uid types.UID)
+}
+
+// LoadBalancing is the strategy used to assign workspaces to the native
+// provider processes of a pool.
+type LoadBalancing string
+
+const (
+	// LoadBalancingRoundRobin assigns workspaces to the processes in turn.
+	LoadBalancingRoundRobin LoadBalancing = "RoundRobin"
+	// LoadBalancingLeastLoad assigns workspaces to the process with the
+	// least number of workspaces assigned.
+	LoadBalancingLeastLoad LoadBalancing = "LeastLoad"
+)
+
+// ProviderPoolOption lets you configure a SharedProviderPool.
+type ProviderPoolOption func(p *SharedProviderPool)
+
+// WithSharedProviderOptions sets the options used to configure every
+// SharedProvider in the pool.
+func WithSharedProviderOptions(opts ...SharedGRPCRunnerOption) ProviderPoolOption {
+	return func(p *SharedProviderPool) {
+		p.providerOpts = opts
+	}
+}
+
+// WithLoadBalancing sets the strategy used to assign workspaces to the
+// processes of the pool.
+func WithLoadBalancing(lb LoadBalancing) ProviderPoolOption {
+	return func(p *SharedProviderPool) {
+		p.loadBalancing = lb
+	}
+}
+
+// WithProviderConfigPinning makes the pool assign all the workspaces of a
+// ProviderConfig to the same process so that a process serves the
+// credentials of as few ProviderConfigs as possible. A new ProviderConfig is
+// pinned to the process with the fewest ProviderConfigs pinned, so processes
+// are shared by ProviderConfigs only if there are more ProviderConfigs than
+// processes.
+func WithProviderConfigPinning() ProviderPoolOption {
+	return func(p *SharedProviderPool) {
+		p.pinProviderConfig = true
+	}
+}
+
+// NewSharedProviderPool returns a new SharedProviderPool that runs the given
+// number of native provider processes. A size less than 1 is treated as 1.
+func NewSharedProviderPool(l logging.Logger, nativeProviderPath, nativeProviderName string, size int, opts ...ProviderPoolOption) *SharedProviderPool {
+	if size < 1 {
+		size = 1
+	}
+	p := &SharedProviderPool{
+		loadBalancing: LoadBalancingRoundRobin,
+		workspaces:    map[types.UID]assignment{},
+		pins:          map[string]int{},
+		pinUsers:      map[string]int{},
+		load:          make([]int, size),
+	}
+	for _, o := range opts {
+		o(p)
+	}
+	p.providers = make([]*SharedProvider, size)
+	for i := range p.providers {
+		p.providers[i] = NewSharedProvider(l.WithValues("processIndex", i), nativeProviderPath, nativeProviderName, p.providerOpts...)
+	}
+	return p
+}
+
+// SharedProviderPool runs a pool of native provider processes in the shared
+// gRPC server mode and assigns each workspace to one of them.
+type SharedProviderPool struct {
+	providers         []*SharedProvider
+	providerOpts      []SharedGRPCRunnerOption
+	loadBalancing     LoadBalancing
+	pinProviderConfig bool
+
+	mu sync.Mutex
+	// workspaces is the assignment of each workspace to a process.
+	workspaces map[types.UID]assignment
+	// pins is the index of the process each ProviderConfig is pinned to and
+	// pinUsers is the number of workspaces of each pinned ProviderConfig. A
+	// pin is removed once no workspace uses its ProviderConfig.
+	pins     map[string]int
+	pinUsers map[string]int
+	// load is the number of workspaces assigned to each process.
+	load []int
+	next int
+}
+
+// Providers returns the native provider processes of the pool, e.g. to be
+// supervised by a ProviderSupervisor each.
+func (p *SharedProviderPool) Providers() []*SharedProvider {
+	return p.providers
+}
+
+// Start starts the next native provider process in turn if it's not already
+// running and returns its reattach configuration.
+func (p *SharedProviderPool) Start() (string, error) {
+	p.mu.Lock()
+	i := p.next
+	p.next = (p.next + 1) % len(p.providers)
+	p.mu.Unlock()
+	return p.providers[i].Start()
+}
+
+// StartFor starts the native provider process assigned to the workspace with
+// the given UID if it's not already running and returns its reattach
+// configuration.
+func (p *SharedProviderPool) StartFor(uid types.UID, providerConfig string) (string, error) {
+	return p.providers[p.assign(uid, providerConfig)].Start()
+}
+
+// GenerationFor returns the generation of the native provider process
+// assigned to the workspace with the given UID.
+func (p *SharedProviderPool) GenerationFor(uid types.UID) uint64 {
+	p.mu.Lock()
+	a, ok := p.workspaces[uid]
+	p.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	return p.providers[a.index].Generation()
+}
+
+// Release removes the assignment of the workspace with the given UID.
+func (p *SharedProviderPool) Release(uid types.UID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a, ok := p.workspaces[uid]; ok {
+		p.unassign(uid, a)
+	}
+}
+
+// unassign removes the given assignment of the workspace with the given UID
+// and the pin of its ProviderConfig if no other workspace uses it. It must be
+// called with the lock held.
+func (p *SharedProviderPool) unassign(uid types.UID, a assignment) {
+	p.load[a.index]--
+	delete(p.workspaces, uid)
+	if !p.pinProviderConfig {
+		return
+	}
+	p.pinUsers[a.providerConfig]--
+	if p.pinUsers[a.providerConfig] <= 0 {
+		delete(p.pinUsers, a.providerConfig)
+		delete(p.pins, a.providerConfig)
+	}
+}
+
+// assignment is the index of the process a workspace is assigned to and the
+// ProviderConfig of its resource at the time.
+type assignment struct {
+	index          int
+	providerConfig string
+}
+
+// assign returns the index of the process the workspace with the given UID
+// is assigned to, assigning it first if needed. If ProviderConfigs are
+// pinned, a workspace whose ProviderConfig has changed is assigned again.
+func (p *SharedProviderPool) assign(uid types.UID, providerConfig string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a, ok := p.workspaces[uid]; ok {
+		if !p.pinProviderConfig || a.providerConfig == providerConfig {
+			return a.index
+		}
+		p.unassign(uid, a)
+	}
+	var i int
+	switch {
+	case p.pinProviderConfig:
+		pinned, ok := p.pins[providerConfig]
+		if !ok {
+			pinned = p.leastPinned()
+			p.pins[providerConfig] = pinned
+		}
+		p.pinUsers[providerConfig]++
+		i = pinned
+	case p.loadBalancing == LoadBalancingLeastLoad:
+		i = leastIndex(p.load)
+	default:
+		i = p.next
+		p.next = (p.next + 1) % len(p.providers)
+	}
+	p.workspaces[uid] = assignment{index: i, providerConfig: providerConfig}
+	p.load[i]++
+	return i
+}
+
+// leastPinned returns the index of the process with the fewest
+// ProviderConfigs pinned. It must be called with the lock held.
+func (p *SharedProviderPool) leastPinned() int {
+	pinned := make([]int, len(p.providers))
+	for _, i := range p.pins {
+		pinned[i]++
+	}
+	return leastIndex(pinned)
+}
+
+// leastIndex returns the index of the smallest number, the first one if
+// there is more than one.
+func leastIndex(l []int) int {
+	least := 0
+	for i := range l {
+		if l[i] < l[least] {
+			least = i
+		}
+	}
+	return least
+}
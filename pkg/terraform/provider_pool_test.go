@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSharedProviderPoolAssign(t *testing.T) {
+	type workspace struct {
+		uid            types.UID
+		providerConfig string
+	}
+	type args struct {
+		size     int
+		opts     []ProviderPoolOption
+		released []types.UID
+		assign   []workspace
+	}
+	type want struct {
+		indexes []int
+		pins    map[string]int
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"RoundRobin": {
+			reason: "Workspaces should be assigned to the processes in turn.",
+			args: args{
+				size:   2,
+				assign: []workspace{{uid: "a"}, {uid: "b"}, {uid: "c"}},
+			},
+			want: want{
+				indexes: []int{0, 1, 0},
+			},
+		},
+		"Sticky": {
+			reason: "A workspace should stay assigned to the same process.",
+			args: args{
+				size:   2,
+				assign: []workspace{{uid: "a"}, {uid: "b"}, {uid: "a"}, {uid: "b"}},
+			},
+			want: want{
+				indexes: []int{0, 1, 0, 1},
+			},
+		},
+		"LeastLoad": {
+			reason: "Workspaces should be assigned to the process with the least number of workspaces.",
+			args: args{
+				size:     3,
+				opts:     []ProviderPoolOption{WithLoadBalancing(LoadBalancingLeastLoad)},
+				released: []types.UID{"b"},
+				assign:   []workspace{{uid: "a"}, {uid: "b"}, {uid: "c"}, {uid: "d"}, {uid: "e"}},
+			},
+			want: want{
+				indexes: []int{0, 1, 1, 2, 0},
+			},
+		},
+		"ProviderConfigPinning": {
+			reason: "All workspaces of a ProviderConfig should be assigned to the same process.",
+			args: args{
+				size: 2,
+				opts: []ProviderPoolOption{WithProviderConfigPinning()},
+				assign: []workspace{
+					{uid: "a", providerConfig: "pc1"},
+					{uid: "b", providerConfig: "pc1"},
+					{uid: "c", providerConfig: "pc2"},
+					{uid: "d", providerConfig: "pc1"},
+					{uid: "e", providerConfig: "pc3"},
+				},
+			},
+			want: want{
+				indexes: []int{0, 0, 1, 0, 0},
+				pins:    map[string]int{"pc1": 0, "pc2": 1, "pc3": 0},
+			},
+		},
+		"ProviderConfigChanged": {
+			reason: "A workspace whose ProviderConfig changed should be assigned to the process of its new ProviderConfig.",
+			args: args{
+				size: 2,
+				opts: []ProviderPoolOption{WithProviderConfigPinning()},
+				assign: []workspace{
+					{uid: "a", providerConfig: "pc1"},
+					{uid: "b", providerConfig: "pc2"},
+					{uid: "a", providerConfig: "pc2"},
+					{uid: "a", providerConfig: "pc2"},
+				},
+			},
+			want: want{
+				indexes: []int{0, 1, 1, 1},
+				pins:    map[string]int{"pc2": 1},
+			},
+		},
+		"ProviderConfigReleased": {
+			reason: "The pin of a ProviderConfig should be removed once none of its workspaces is assigned.",
+			args: args{
+				size:     2,
+				opts:     []ProviderPoolOption{WithProviderConfigPinning()},
+				released: []types.UID{"a"},
+				assign: []workspace{
+					{uid: "a", providerConfig: "pc1"},
+					{uid: "b", providerConfig: "pc2"},
+				},
+			},
+			want: want{
+				indexes: []int{0, 0},
+				pins:    map[string]int{"pc2": 0},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewSharedProviderPool(logging.NewNopLogger(), "path", "name", tc.args.size, tc.args.opts...)
+			released := map[types.UID]bool{}
+			for _, uid := range tc.args.released {
+				released[uid] = true
+			}
+			got := make([]int, 0, len(tc.args.assign))
+			for _, w := range tc.args.assign {
+				got = append(got, p.assign(w.uid, w.providerConfig))
+				// The workspaces are released right after they're
+				// assigned to free up their processes.
+				if released[w.uid] {
+					p.Release(w.uid)
+				}
+			}
+			if diff := cmp.Diff(tc.want.indexes, got); diff != "" {
+				t.Errorf("\n%s\nassign(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.pins, p.pins, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("\n%s\nassign(...): -want pins, +got pins:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -21,7 +21,10 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
@@ -33,6 +36,8 @@ import (
 const (
 	// error messages
 	errFmtTimeout = "timed out after %v while waiting for the reattach configuration string"
+	errFmtBackoff = "native provider process exited recently, it cannot be started again before %s"
+	errExited     = "native provider process exited before printing the reattach configuration string"
 
 	// an example value would be: '{"registry.terraform.io/hashicorp/aws": {"Protocol": "grpc", "ProtocolVersion":5, "Pid":... "Addr":{"Network": "unix","String": "..."}}}'
 	fmtReattachEnv    = `{"%s":{"Protocol":"grpc","ProtocolVersion":%d,"Pid":%d,"Test": true,"Addr":{"Network": "unix","String": "%s"}}}`
@@ -44,8 +49,18 @@ const (
 	// https://github.com/hashicorp/terraform/blob/d35bc0531255b496beb5d932f185cbcdb2d61a99/internal/plugin/serve.go#L33
 	valMagicCookie         = "d602bf8f470bc67ca7faa0386276bbdd4330efaf76d1a219cb4d6991ca9872b2"
 	defaultProtocolVersion = 5
-	regexReattachLine      = `.*unix\|(.*)\|grpc.*`
-	reattachTimeout        = 1 * time.Minute
+	// The handshake line of go-plugin servers is in the form of
+	// CORE-PROTOCOL-VERSION|APP-PROTOCOL-VERSION|NETWORK-TYPE|NETWORK-ADDR|PROTOCOL
+	// where the app protocol version is the Terraform plugin protocol version,
+	// e.g. 5 for the SDKv2 providers and 6 for the plugin framework ones.
+	regexReattachLine = `^(?:\d+\|(\d+)\|)?.*unix\|(.*)\|grpc.*`
+	reattachTimeout   = 1 * time.Minute
+
+	defaultMinRestartBackoff = 1 * time.Second
+	defaultMaxRestartBackoff = 1 * time.Minute
+	// providerStopGracePeriod is how long a stopped native provider process
+	// is waited for to exit before it's killed.
+	providerStopGracePeriod = 10 * time.Second
 )
 
 // ProviderRunner is the interface for running
@@ -55,6 +70,13 @@ type ProviderRunner interface {
 	Start() (string, error)
 }
 
+// ProviderGenerationTracker is implemented by the ProviderRunners whose
+// native provider process could exit and be started again. The generation
+// changes every time the process exits or is stopped.
+type ProviderGenerationTracker interface {
+	Generation() uint64
+}
+
 // NoOpProviderRunner is a no-op ProviderRunner
 type NoOpProviderRunner struct{}
 
@@ -80,6 +102,20 @@ type SharedProvider struct {
 	executor           exec.Interface
 	clock              clock.Clock
 	mu                 *sync.Mutex
+	// cmd is the running native provider process and addr is the address
+	// of its gRPC server.
+	cmd  exec.Cmd
+	proc *process
+	addr string
+	// generation is incremented every time a native provider process exits
+	// or is stopped.
+	generation uint64
+
+	minRestartBackoff time.Duration
+	maxRestartBackoff time.Duration
+	backoff           time.Duration
+	startTime         time.Time
+	nextStart         time.Time
 }
 
 // SharedGRPCRunnerOption lets you configure the shared gRPC runner.
@@ -100,13 +136,25 @@ func WithNativeProviderExecutor(e exec.Interface) SharedGRPCRunnerOption {
 }
 
 // WithProtocolVersion sets the gRPC protocol version in use between
-// the Terraform CLI and the native provider.
+// the Terraform CLI and the native provider. It's used only if the native
+// provider doesn't report its protocol version in its handshake line.
 func WithProtocolVersion(protocolVersion int) SharedGRPCRunnerOption {
 	return func(sr *SharedProvider) {
 		sr.protocolVersion = protocolVersion
 	}
 }
 
+// WithRestartBackoff sets the minimum and maximum time to wait before starting
+// the native provider process again after it exits. The wait time is doubled
+// every time a process exits before it runs for the maximum duration, and
+// reset otherwise.
+func WithRestartBackoff(min, max time.Duration) SharedGRPCRunnerOption {
+	return func(sr *SharedProvider) {
+		sr.minRestartBackoff = min
+		sr.maxRestartBackoff = max
+	}
+}
+
 // NewSharedProvider instantiates a SharedProvider with an
 // OS executor using the supplied logger
 func NewSharedProvider(l logging.Logger, nativeProviderPath, nativeProviderName string, opts ...SharedGRPCRunnerOption) *SharedProvider {
@@ -118,6 +166,8 @@ func NewSharedProvider(l logging.Logger, nativeProviderPath, nativeProviderName
 		executor:           exec.New(),
 		clock:              clock.RealClock{},
 		mu:                 &sync.Mutex{},
+		minRestartBackoff:  defaultMinRestartBackoff,
+		maxRestartBackoff:  defaultMaxRestartBackoff,
 	}
 	for _, o := range opts {
 		o(sr)
@@ -138,23 +188,37 @@ func (sr *SharedProvider) Start() (string, error) { //nolint:gocyclo
 		log.Debug("Shared gRPC server is running...", "reattachConfig", sr.reattachConfig)
 		return sr.reattachConfig, nil
 	}
+	if sr.clock.Now().Before(sr.nextStart) {
+		return "", errors.Errorf(errFmtBackoff, sr.nextStart.String())
+	}
 	errCh := make(chan error, 1)
-	reattachCh := make(chan string, 1)
+	reattachCh := make(chan handshake, 1)
 	re, err := regexp.Compile(regexReattachLine)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to compile regexp")
 	}
 
+	//#nosec G204 no user input
+	cmd := sr.executor.Command(sr.nativeProviderPath, sr.nativeProviderArgs...)
+	proc := newProcess()
+	sr.cmd = cmd
+	sr.proc = proc
 	go func() {
-		defer close(errCh)
-		defer close(reattachCh)
+		defer close(proc.exited)
 		defer func() {
+			// Start holds the lock until it returns, which it does once
+			// the reattach configuration or an error is sent, so the lock
+			// is taken only after that.
 			sr.mu.Lock()
-			sr.reattachConfig = ""
+			// The process could have been stopped and another one started
+			// in the meantime. Otherwise, it exited by itself and will be
+			// restarted by the next Start call.
+			if sr.cmd == cmd {
+				providerRestarts.Inc()
+				sr.ended()
+			}
 			sr.mu.Unlock()
 		}()
-		//#nosec G204 no user input
-		cmd := sr.executor.Command(sr.nativeProviderPath, sr.nativeProviderArgs...)
 		cmd.SetEnv(append(os.Environ(), fmt.Sprintf(fmtSetEnv, envMagicCookie, valMagicCookie)))
 		stdout, err := cmd.StdoutPipe()
 		if err != nil {
@@ -165,6 +229,8 @@ func (sr *SharedProvider) Start() (string, error) { //nolint:gocyclo
 			errCh <- err
 			return
 		}
+		proc.started(cmd)
+		handshaken := false
 		scanner := bufio.NewScanner(stdout)
 		for scanner.Scan() {
 			t := scanner.Text()
@@ -172,22 +238,156 @@ func (sr *SharedProvider) Start() (string, error) { //nolint:gocyclo
 			if matches == nil {
 				continue
 			}
-			reattachCh <- fmt.Sprintf(fmtReattachEnv, sr.nativeProviderName, sr.protocolVersion, os.Getpid(), matches[1])
+			h := handshake{addr: matches[2], protocolVersion: sr.protocolVersion}
+			if v, err := strconv.Atoi(matches[1]); err == nil {
+				h.protocolVersion = v
+			}
+			reattachCh <- h
+			handshaken = true
 			break
 		}
-		if err := cmd.Wait(); err != nil {
+		err = cmd.Wait()
+		if err != nil {
 			log.Info("Native Terraform provider process error", "error", err)
+		}
+		// The exit is reported to Start only if it's still waiting for the
+		// reattach configuration.
+		switch {
+		case handshaken:
+		case err != nil:
 			errCh <- err
+		default:
+			errCh <- errors.New(errExited)
 		}
 	}()
 
 	select {
-	case reattachConfig := <-reattachCh:
-		sr.reattachConfig = reattachConfig
+	case h := <-reattachCh:
+		sr.addr = h.addr
+		sr.startTime = sr.clock.Now()
+		sr.reattachConfig = fmt.Sprintf(fmtReattachEnv, sr.nativeProviderName, h.protocolVersion, os.Getpid(), h.addr)
 		return sr.reattachConfig, nil
 	case err := <-errCh:
+		sr.ended()
 		return "", err
 	case <-sr.clock.After(reattachTimeout):
+		go proc.stop(providerStopGracePeriod)
+		sr.ended()
 		return "", errors.Errorf(errFmtTimeout, reattachTimeout)
 	}
 }
+
+// process is a native provider process that can be stopped before it has
+// started, in which case it's stopped as soon as it starts.
+type process struct {
+	mu      sync.Mutex
+	term    func()
+	kill    func()
+	stopped bool
+	// exited is closed when the process exits or fails to start.
+	exited chan struct{}
+}
+
+func newProcess() *process {
+	return &process{exited: make(chan struct{})}
+}
+
+// started sets the functions that stop the process of the given command,
+// which has just started, and stops it if it has already been stopped.
+func (p *process) started(cmd exec.Cmd) {
+	term, kill := processSignals(cmd, syscall.SIGTERM)
+	p.mu.Lock()
+	p.term, p.kill = term, kill
+	stopped := p.stopped
+	p.mu.Unlock()
+	if stopped {
+		go p.stop(providerStopGracePeriod)
+	}
+}
+
+// stop sends SIGTERM to the process and kills it if it doesn't exit within
+// the given grace period. The exit of the process is awaited through the
+// exited channel since the process state cannot be read while the process
+// is being waited on.
+func (p *process) stop(gracePeriod time.Duration) {
+	p.mu.Lock()
+	p.stopped = true
+	term, kill := p.term, p.kill
+	p.mu.Unlock()
+	if term == nil {
+		return
+	}
+	term()
+	t := time.NewTimer(gracePeriod)
+	defer t.Stop()
+	select {
+	case <-p.exited:
+	case <-t.C:
+		kill()
+	}
+}
+
+// handshake is the information about the native provider process that's
+// parsed from its handshake line.
+type handshake struct {
+	addr            string
+	protocolVersion int
+}
+
+// ended clears the information about the native provider process that exited
+// or is stopped, and computes when the next one can be started. It must be
+// called with the lock held.
+func (sr *SharedProvider) ended() {
+	now := sr.clock.Now()
+	switch {
+	case sr.startTime.IsZero() || now.Sub(sr.startTime) < sr.maxRestartBackoff:
+		sr.backoff *= 2
+		if sr.backoff < sr.minRestartBackoff {
+			sr.backoff = sr.minRestartBackoff
+		}
+		if sr.backoff > sr.maxRestartBackoff {
+			sr.backoff = sr.maxRestartBackoff
+		}
+	default:
+		sr.backoff = 0
+	}
+	sr.nextStart = now.Add(sr.backoff)
+	sr.startTime = time.Time{}
+	sr.cmd = nil
+	sr.proc = nil
+	sr.reattachConfig = ""
+	sr.addr = ""
+	atomic.AddUint64(&sr.generation, 1)
+}
+
+// Stop stops the running native provider process, if any. The next Start call
+// starts a new one.
+func (sr *SharedProvider) Stop() {
+	sr.mu.Lock()
+	proc := sr.proc
+	if sr.cmd != nil {
+		sr.ended()
+	}
+	sr.mu.Unlock()
+	// The process is waited for without the lock so that a new one can be
+	// started in the meantime.
+	if proc != nil {
+		proc.stop(providerStopGracePeriod)
+	}
+}
+
+// Addr returns the address of the gRPC server of the running native provider
+// process, or an empty string if it's not running.
+func (sr *SharedProvider) Addr() string {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.addr
+}
+
+// Generation returns a number that changes every time the native provider
+// process exits or is stopped. The Terraform CLI processes that were attached
+// to a process that exited can be told apart by comparing the generation
+// before and after they run.
+func (sr *SharedProvider) Generation() uint64 {
+	return atomic.LoadUint64(&sr.generation)
+}
@@ -42,6 +42,11 @@ func TestStartSharedServer(t *testing.T) {
 	testReattachConfig1 := `1|5|unix|test1|grpc|`
 	testReattachConfig2 := `1|5|unix|test2|grpc|`
 	testErr := errors.New("boom")
+	now := time.Now()
+	// The native provider that stalls doesn't print anything until the
+	// test ends.
+	stalled, stall := io.Pipe()
+	defer stall.Close() //nolint:errcheck
 	type args struct {
 		runner ProviderRunner
 	}
@@ -67,6 +72,15 @@ func TestStartSharedServer(t *testing.T) {
 				reattachConfig: fmt.Sprintf(`{"provider-test":{"Protocol":"grpc","ProtocolVersion":5,"Pid":%d,"Test": true,"Addr":{"Network": "unix","String": "test1"}}}`, os.Getpid()),
 			},
 		},
+		"ProtocolVersionFromHandshake": {
+			args: args{
+				runner: NewSharedProvider(logging.NewNopLogger(), testPath, testName,
+					WithNativeProviderExecutor(newExecutorWithStoutPipe(`1|6|unix|test1|grpc|`, nil))),
+			},
+			want: want{
+				reattachConfig: fmt.Sprintf(`{"provider-test":{"Protocol":"grpc","ProtocolVersion":6,"Pid":%d,"Test": true,"Addr":{"Network": "unix","String": "test1"}}}`, os.Getpid()),
+			},
+		},
 		"AlreadyRunning": {
 			args: args{
 				runner: &SharedProvider{
@@ -90,13 +104,42 @@ func TestStartSharedServer(t *testing.T) {
 				err: testErr,
 			},
 		},
-		"NativeProviderTimeout": {
+		"RestartBackoff": {
+			args: args{
+				runner: &SharedProvider{
+					nativeProviderPath: testPath,
+					logger:             logging.NewNopLogger(),
+					executor:           newExecutorWithStoutPipe(testReattachConfig1, nil),
+					mu:                 &sync.Mutex{},
+					clock:              clock.NewFakeClock(now),
+					nextStart:          now.Add(time.Minute),
+				},
+			},
+			want: want{
+				err: errors.Errorf(errFmtBackoff, now.Add(time.Minute).String()),
+			},
+		},
+		"NativeProviderExited": {
 			args: args{
 				runner: &SharedProvider{
 					nativeProviderPath: testPath,
 					logger:             logging.NewNopLogger(),
 					executor:           newExecutorWithStoutPipe("invalid", nil),
 					mu:                 &sync.Mutex{},
+					clock:              clock.NewFakeClock(now),
+				},
+			},
+			want: want{
+				err: errors.New(errExited),
+			},
+		},
+		"NativeProviderTimeout": {
+			args: args{
+				runner: &SharedProvider{
+					nativeProviderPath: testPath,
+					logger:             logging.NewNopLogger(),
+					executor:           newExecutorWithStdout(stalled, nil),
+					mu:                 &sync.Mutex{},
 					clock:              &fakeClock{},
 				},
 			},
@@ -121,6 +164,90 @@ func TestStartSharedServer(t *testing.T) {
 	}
 }
 
+func TestSharedProviderEnded(t *testing.T) {
+	now := time.Now()
+	type args struct {
+		backoff   time.Duration
+		startTime time.Time
+	}
+	type want struct {
+		backoff    time.Duration
+		nextStart  time.Time
+		generation uint64
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"FailedToStart": {
+			reason: "The minimum backoff should be used if the process could not be started.",
+			want: want{
+				backoff:    time.Second,
+				nextStart:  now.Add(time.Second),
+				generation: 1,
+			},
+		},
+		"ExitedSoon": {
+			reason: "The backoff should be doubled if the process exited soon after it started.",
+			args: args{
+				backoff:   4 * time.Second,
+				startTime: now.Add(-10 * time.Second),
+			},
+			want: want{
+				backoff:    8 * time.Second,
+				nextStart:  now.Add(8 * time.Second),
+				generation: 1,
+			},
+		},
+		"MaxBackoff": {
+			reason: "The backoff should not exceed the maximum.",
+			args: args{
+				backoff:   time.Minute,
+				startTime: now.Add(-10 * time.Second),
+			},
+			want: want{
+				backoff:    time.Minute,
+				nextStart:  now.Add(time.Minute),
+				generation: 1,
+			},
+		},
+		"RanLongEnough": {
+			reason: "The backoff should be reset if the process ran for longer than the maximum backoff.",
+			args: args{
+				backoff:   time.Minute,
+				startTime: now.Add(-time.Hour),
+			},
+			want: want{
+				nextStart:  now,
+				generation: 1,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sr := &SharedProvider{
+				clock:             clock.NewFakeClock(now),
+				mu:                &sync.Mutex{},
+				reattachConfig:    "test",
+				addr:              "test",
+				minRestartBackoff: time.Second,
+				maxRestartBackoff: time.Minute,
+				backoff:           tc.args.backoff,
+				startTime:         tc.args.startTime,
+			}
+			sr.ended()
+			got := want{backoff: sr.backoff, nextStart: sr.nextStart, generation: sr.Generation()}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nended(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if sr.reattachConfig != "" || sr.Addr() != "" {
+				t.Errorf("\n%s\nended(...): reattach configuration is not cleared", tc.reason)
+			}
+		})
+	}
+}
+
 type fakeClock struct {
 	clock.FakeClock
 }
@@ -133,12 +260,16 @@ func (f *fakeClock) After(d time.Duration) <-chan time.Time {
 }
 
 func newExecutorWithStoutPipe(reattachConfig string, err error) exec.Interface {
+	return newExecutorWithStdout(io.NopCloser(strings.NewReader(reattachConfig)), err)
+}
+
+func newExecutorWithStdout(stdout io.ReadCloser, err error) exec.Interface {
 	return &testingexec.FakeExec{
 		CommandScript: []testingexec.FakeCommandAction{
 			func(cmd string, args ...string) exec.Cmd {
 				return &testingexec.FakeCmd{
 					StdoutPipeResponse: testingexec.FakeStdIOPipeResponse{
-						ReadCloser: io.NopCloser(strings.NewReader(reattachConfig)),
+						ReadCloser: stdout,
 						Error:      err,
 					},
 				}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+const (
+	errNotRunning = "native provider process is not running"
+
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultFailureThreshold    = 3
+
+	// pluginServiceName is the name of the service whose health is reported
+	// by the gRPC health service of the go-plugin servers.
+	pluginServiceName = "plugin"
+)
+
+// SupervisedProvider is a native provider process that can be supervised.
+type SupervisedProvider interface {
+	ProviderRunner
+	ProviderGenerationTracker
+	// Stop stops the running native provider process.
+	Stop()
+	// Addr returns the address of the gRPC server of the running native
+	// provider process, or an empty string if it's not running.
+	Addr() string
+}
+
+// HealthChecker checks the health of the native provider process serving at
+// the given address.
+type HealthChecker interface {
+	Check(ctx context.Context, addr string) error
+}
+
+// HealthCheckerFn is a function that satisfies the HealthChecker interface.
+type HealthCheckerFn func(ctx context.Context, addr string) error
+
+// Check checks the health of the native provider process.
+func (fn HealthCheckerFn) Check(ctx context.Context, addr string) error {
+	return fn(ctx, addr)
+}
+
+// NewGRPCHealthChecker returns a HealthChecker that queries the gRPC health
+// service of the native provider plugin through its unix socket.
+func NewGRPCHealthChecker() HealthChecker {
+	return HealthCheckerFn(func(ctx context.Context, addr string) error {
+		conn, err := grpc.DialContext(ctx, "unix://"+addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+		if err != nil {
+			return errors.Wrap(err, "cannot connect to native provider")
+		}
+		defer conn.Close() //nolint:errcheck
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: pluginServiceName})
+		if err != nil {
+			return errors.Wrap(err, "cannot check the health of native provider")
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return errors.Errorf("native provider is not serving: %s", resp.GetStatus().String())
+		}
+		return nil
+	})
+}
+
+// ProviderStatus is the observed status of the supervised native provider
+// process.
+type ProviderStatus struct {
+	// Running is true if a native provider process is running.
+	Running bool
+	// Healthy is true if the last health check succeeded.
+	Healthy bool
+	// Restarts is the number of times a native provider process exited or
+	// was stopped.
+	Restarts uint64
+	// LastCheckTime is the time of the last health check.
+	LastCheckTime time.Time
+	// LastError is the error of the last failed health check or restart.
+	LastError error
+}
+
+// ProviderSupervisorOption lets you configure a ProviderSupervisor.
+type ProviderSupervisorOption func(s *ProviderSupervisor)
+
+// WithHealthChecker sets the HealthChecker used by the supervisor.
+func WithHealthChecker(hc HealthChecker) ProviderSupervisorOption {
+	return func(s *ProviderSupervisor) {
+		s.checker = hc
+	}
+}
+
+// WithHealthCheckInterval sets the interval between health checks.
+func WithHealthCheckInterval(d time.Duration) ProviderSupervisorOption {
+	return func(s *ProviderSupervisor) {
+		s.interval = d
+	}
+}
+
+// WithHealthCheckTimeout sets the timeout of a single health check.
+func WithHealthCheckTimeout(d time.Duration) ProviderSupervisorOption {
+	return func(s *ProviderSupervisor) {
+		s.timeout = d
+	}
+}
+
+// WithFailureThreshold sets the number of consecutive failed health checks
+// after which the native provider process is restarted.
+func WithFailureThreshold(n int) ProviderSupervisorOption {
+	return func(s *ProviderSupervisor) {
+		s.failureThreshold = n
+	}
+}
+
+// WithSupervisorLogger sets the logger of the supervisor.
+func WithSupervisorLogger(l logging.Logger) ProviderSupervisorOption {
+	return func(s *ProviderSupervisor) {
+		s.logger = l
+	}
+}
+
+// NewProviderSupervisor returns a new ProviderSupervisor that supervises the
+// given native provider.
+func NewProviderSupervisor(p SupervisedProvider, opts ...ProviderSupervisorOption) *ProviderSupervisor {
+	s := &ProviderSupervisor{
+		provider:         p,
+		checker:          NewGRPCHealthChecker(),
+		interval:         defaultHealthCheckInterval,
+		timeout:          defaultHealthCheckTimeout,
+		failureThreshold: defaultFailureThreshold,
+		logger:           logging.NewNopLogger(),
+		clock:            clock.RealClock{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// ProviderSupervisor periodically checks the health of the native provider
+// process and restarts it when it's not running or is unhealthy. The
+// SupervisedProvider is responsible for backing off consecutive restarts.
+type ProviderSupervisor struct {
+	provider         SupervisedProvider
+	checker          HealthChecker
+	interval         time.Duration
+	timeout          time.Duration
+	failureThreshold int
+	logger           logging.Logger
+	clock            clock.Clock
+
+	mu       sync.RWMutex
+	failures int
+	status   ProviderStatus
+}
+
+// Start runs the supervision loop until the given context is cancelled, in
+// which case the native provider process is stopped. It satisfies the
+// manager.Runnable interface of controller-runtime.
+func (s *ProviderSupervisor) Start(ctx context.Context) error {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.provider.Stop()
+			return nil
+		case <-t.C:
+			s.supervise(ctx)
+		}
+	}
+}
+
+// Status returns the last observed status of the native provider process.
+func (s *ProviderSupervisor) Status() ProviderStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := s.status
+	st.Restarts = s.provider.Generation()
+	return st
+}
+
+// supervise checks the health of the native provider process once and
+// restarts it if needed.
+func (s *ProviderSupervisor) supervise(ctx context.Context) {
+	addr := s.provider.Addr()
+	err := errors.New(errNotRunning)
+	if addr != "" {
+		cctx, cancel := context.WithTimeout(ctx, s.timeout)
+		err = s.checker.Check(cctx, addr)
+		cancel()
+	}
+
+	s.mu.Lock()
+	s.status.Running = addr != ""
+	s.status.Healthy = err == nil
+	s.status.LastCheckTime = s.clock.Now()
+	if err == nil {
+		s.failures = 0
+		s.mu.Unlock()
+		return
+	}
+	s.status.LastError = err
+	s.failures++
+	restart := addr == "" || s.failures >= s.failureThreshold
+	if restart {
+		s.failures = 0
+	}
+	s.mu.Unlock()
+	if !restart {
+		return
+	}
+
+	s.logger.Info("Restarting native provider process", "error", err.Error())
+	// Terraform CLI processes attached to the stopped process fail and
+	// their workspaces report it with a clear error, see Workspace.
+	s.provider.Stop()
+	if _, err := s.provider.Start(); err != nil {
+		s.logger.Info("Cannot restart native provider process", "error", err.Error())
+		s.mu.Lock()
+		s.status.LastError = errors.Wrap(err, "cannot restart native provider process")
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Lock()
+	s.status.Running = true
+	s.mu.Unlock()
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+type fakeProvider struct {
+	addr       string
+	startErr   error
+	starts     int
+	stops      int
+	generation uint64
+}
+
+func (p *fakeProvider) Start() (string, error) {
+	p.starts++
+	if p.startErr != nil {
+		return "", p.startErr
+	}
+	p.addr = "new"
+	return "reattach", nil
+}
+
+func (p *fakeProvider) Stop() {
+	p.stops++
+	if p.addr != "" {
+		p.addr = ""
+		p.generation++
+	}
+}
+
+func (p *fakeProvider) Addr() string {
+	return p.addr
+}
+
+func (p *fakeProvider) Generation() uint64 {
+	return p.generation
+}
+
+func TestProviderSupervisorSupervise(t *testing.T) {
+	now := time.Now()
+	type args struct {
+		provider *fakeProvider
+		checker  HealthChecker
+		failures int
+	}
+	type want struct {
+		status   ProviderStatus
+		failures int
+		starts   int
+		stops    int
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Healthy": {
+			reason: "Nothing should be done if the native provider is healthy.",
+			args: args{
+				provider: &fakeProvider{addr: "old"},
+				checker:  HealthCheckerFn(func(_ context.Context, _ string) error { return nil }),
+				failures: 2,
+			},
+			want: want{
+				status: ProviderStatus{Running: true, Healthy: true, LastCheckTime: now},
+			},
+		},
+		"UnhealthyBelowThreshold": {
+			reason: "The native provider should not be restarted before the failure threshold is reached.",
+			args: args{
+				provider: &fakeProvider{addr: "old"},
+				checker:  HealthCheckerFn(func(_ context.Context, _ string) error { return errBoom }),
+			},
+			want: want{
+				status:   ProviderStatus{Running: true, LastCheckTime: now, LastError: errBoom},
+				failures: 1,
+			},
+		},
+		"UnhealthyRestarted": {
+			reason: "The native provider should be restarted once the failure threshold is reached.",
+			args: args{
+				provider: &fakeProvider{addr: "old"},
+				checker:  HealthCheckerFn(func(_ context.Context, _ string) error { return errBoom }),
+				failures: 1,
+			},
+			want: want{
+				status: ProviderStatus{Running: true, Restarts: 1, LastCheckTime: now, LastError: errBoom},
+				starts: 1,
+				stops:  1,
+			},
+		},
+		"NotRunningStarted": {
+			reason: "The native provider should be started right away if it's not running.",
+			args: args{
+				provider: &fakeProvider{},
+				checker:  HealthCheckerFn(func(_ context.Context, _ string) error { return nil }),
+			},
+			want: want{
+				status: ProviderStatus{Running: true, LastCheckTime: now, LastError: errors.New(errNotRunning)},
+				starts: 1,
+				stops:  1,
+			},
+		},
+		"CannotRestart": {
+			reason: "The error should be reported if the native provider cannot be started.",
+			args: args{
+				provider: &fakeProvider{startErr: errBoom},
+				checker:  HealthCheckerFn(func(_ context.Context, _ string) error { return nil }),
+			},
+			want: want{
+				status: ProviderStatus{LastCheckTime: now, LastError: errors.Wrap(errBoom, "cannot restart native provider process")},
+				starts: 1,
+				stops:  1,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := NewProviderSupervisor(tc.args.provider, WithHealthChecker(tc.args.checker), WithFailureThreshold(2))
+			s.clock = clock.NewFakeClock(now)
+			s.failures = tc.args.failures
+			s.supervise(context.Background())
+			if diff := cmp.Diff(tc.want.status, s.Status(), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nsupervise(...): -want status, +got status:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.failures, s.failures); diff != "" {
+				t.Errorf("\n%s\nsupervise(...): -want failures, +got failures:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.starts, tc.args.provider.starts); diff != "" {
+				t.Errorf("\n%s\nsupervise(...): -want starts, +got starts:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.stops, tc.args.provider.stops); diff != "" {
+				t.Errorf("\n%s\nsupervise(...): -want stops, +got stops:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
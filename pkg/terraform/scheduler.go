@@ -0,0 +1,202 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// SchedulerOption lets you configure the Scheduler.
+type SchedulerOption func(*Scheduler)
+
+// WithSyncLimit sets the maximum number of Terraform CLI processes that run
+// for the blocking operations at the same time. Zero means no limit.
+func WithSyncLimit(n int) SchedulerOption {
+	return func(s *Scheduler) {
+		s.sync = newSemaphore(n)
+	}
+}
+
+// WithAsyncLimit sets the maximum number of Terraform CLI processes that run
+// for the async operations at the same time. Zero means no limit.
+func WithAsyncLimit(n int) SchedulerOption {
+	return func(s *Scheduler) {
+		s.async = newSemaphore(n)
+	}
+}
+
+// WithProviderConfigLimit sets the maximum number of Terraform CLI processes
+// that run for the resources of a single ProviderConfig at the same time,
+// regardless of whether they're async. Zero means no limit.
+func WithProviderConfigLimit(n int) SchedulerOption {
+	return func(s *Scheduler) {
+		s.perKeyLimit = n
+	}
+}
+
+// NewScheduler returns a new Scheduler. Without any options, it doesn't
+// limit the number of processes.
+func NewScheduler(opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		perKey: map[string]*semaphore{},
+	}
+	for _, f := range opts {
+		f(s)
+	}
+	return s
+}
+
+// Scheduler bounds the number of Terraform CLI processes that run at the same
+// time. The operations that exceed the limits wait in first-in-first-out
+// queues. The operations of a single ProviderConfig first wait for their turn
+// in the queue of that ProviderConfig so that a ProviderConfig with many
+// resources cannot fill the shared queues.
+type Scheduler struct {
+	sync        *semaphore
+	async       *semaphore
+	perKeyLimit int
+
+	mu     sync.Mutex
+	perKey map[string]*semaphore
+
+	waitingSync  int64
+	waitingAsync int64
+}
+
+// Acquire blocks until a Terraform CLI process can be started for an
+// operation of a resource of the given ProviderConfig and returns the
+// function that has to be called once the process ends. It returns an error
+// if the given context is done before that.
+func (s *Scheduler) Acquire(ctx context.Context, providerConfig string, async bool) (func(), error) {
+	waiting, cs := &s.waitingSync, s.sync
+	if async {
+		waiting, cs = &s.waitingAsync, s.async
+	}
+	depth := schedulerQueueDepth.WithLabelValues(strconv.FormatBool(async))
+	depth.Inc()
+	atomic.AddInt64(waiting, 1)
+	defer func() {
+		atomic.AddInt64(waiting, -1)
+		depth.Dec()
+	}()
+	ks := s.semaphoreOf(providerConfig)
+	if err := ks.acquire(ctx); err != nil {
+		return nil, err
+	}
+	if err := cs.acquire(ctx); err != nil {
+		ks.release()
+		return nil, err
+	}
+	once := &sync.Once{}
+	return func() {
+		once.Do(func() {
+			cs.release()
+			ks.release()
+		})
+	}, nil
+}
+
+// QueueDepth returns the number of the operations of the given kind that are
+// waiting for their turn.
+func (s *Scheduler) QueueDepth(async bool) int {
+	if async {
+		return int(atomic.LoadInt64(&s.waitingAsync))
+	}
+	return int(atomic.LoadInt64(&s.waitingSync))
+}
+
+func (s *Scheduler) semaphoreOf(providerConfig string) *semaphore {
+	if s.perKeyLimit <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sem, ok := s.perKey[providerConfig]
+	if !ok {
+		sem = newSemaphore(s.perKeyLimit)
+		s.perKey[providerConfig] = sem
+	}
+	return sem
+}
+
+// semaphore is a counting semaphore that hands the released slots over to
+// the waiters in the order they started waiting. A nil semaphore doesn't
+// limit anything.
+type semaphore struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	waiters list.List
+}
+
+func newSemaphore(limit int) *semaphore {
+	if limit <= 0 {
+		return nil
+	}
+	return &semaphore{limit: limit}
+}
+
+func (s *semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if s.running < s.limit && s.waiters.Len() == 0 {
+		s.running++
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	e := s.waiters.PushBack(ready)
+	s.mu.Unlock()
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// The slot was handed over right when the context was done,
+			// so it has to be given back.
+			s.mu.Unlock()
+			s.release()
+		default:
+			s.waiters.Remove(e)
+			s.mu.Unlock()
+		}
+		return ctx.Err()
+	}
+}
+
+func (s *semaphore) release() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.waiters.Front(); e != nil {
+		// The slot is handed over without decrementing the running count.
+		s.waiters.Remove(e)
+		close(e.Value.(chan struct{}))
+		return
+	}
+	s.running--
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// acquireAsync starts acquiring a slot in the background and returns the
+// channel the release function is sent to once it's acquired.
+func acquireAsync(ctx context.Context, s *Scheduler, pc string, async bool) chan func() {
+	c := make(chan func(), 1)
+	go func() {
+		release, err := s.Acquire(ctx, pc, async)
+		if err != nil {
+			close(c)
+			return
+		}
+		c <- release
+	}()
+	return c
+}
+
+// waitForQueueDepth waits until the given number of operations are queued.
+func waitForQueueDepth(t *testing.T, s *Scheduler, async bool, depth int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		if s.QueueDepth(async) == depth {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("QueueDepth(%t): want %d, got %d", async, depth, s.QueueDepth(async))
+}
+
+func TestSchedulerFIFO(t *testing.T) {
+	s := NewScheduler(WithAsyncLimit(1))
+	release, err := s.Acquire(context.TODO(), "pc", true)
+	if err != nil {
+		t.Fatalf("Acquire(...): %v", err)
+	}
+	first := acquireAsync(context.TODO(), s, "pc", true)
+	waitForQueueDepth(t, s, true, 1)
+	second := acquireAsync(context.TODO(), s, "pc", true)
+	waitForQueueDepth(t, s, true, 2)
+	if diff := cmp.Diff(float64(2), testutil.ToFloat64(schedulerQueueDepth.WithLabelValues("true"))); diff != "" {
+		t.Errorf("schedulerQueueDepth: -want, +got:\n%s", diff)
+	}
+
+	// Sync operations have their own limit.
+	if _, err := s.Acquire(context.TODO(), "pc", false); err != nil {
+		t.Fatalf("Acquire(...): sync operation should not wait for async ones: %v", err)
+	}
+
+	release()
+	releaseFirst := <-first
+	select {
+	case <-second:
+		t.Fatal("Acquire(...): second waiter acquired a slot before the first one released it")
+	default:
+	}
+	releaseFirst()
+	releaseSecond := <-second
+	releaseSecond()
+	if diff := cmp.Diff(0, s.QueueDepth(true)); diff != "" {
+		t.Errorf("QueueDepth(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestSchedulerProviderConfigLimit(t *testing.T) {
+	s := NewScheduler(WithSyncLimit(2), WithProviderConfigLimit(1))
+	release, err := s.Acquire(context.TODO(), "busy", false)
+	if err != nil {
+		t.Fatalf("Acquire(...): %v", err)
+	}
+	busy := acquireAsync(context.TODO(), s, "busy", false)
+	waitForQueueDepth(t, s, false, 1)
+	// The queue of a ProviderConfig should not block the others.
+	if _, err := s.Acquire(context.TODO(), "other", false); err != nil {
+		t.Fatalf("Acquire(...): %v", err)
+	}
+	release()
+	(<-busy)()
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := NewScheduler(WithSyncLimit(1))
+	release, err := s.Acquire(context.TODO(), "", false)
+	if err != nil {
+		t.Fatalf("Acquire(...): %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(ctx, "", false)
+		waiter <- err
+	}()
+	waitForQueueDepth(t, s, false, 1)
+	cancel()
+	if diff := cmp.Diff(context.Canceled, <-waiter, test.EquateErrors()); diff != "" {
+		t.Errorf("Acquire(...): -want error, +got error:\n%s", diff)
+	}
+	waitForQueueDepth(t, s, false, 0)
+	// The slot should be available once released since the cancelled
+	// waiter left the queue.
+	release()
+	if _, err := s.Acquire(context.TODO(), "", false); err != nil {
+		t.Fatalf("Acquire(...): %v", err)
+	}
+}
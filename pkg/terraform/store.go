@@ -22,21 +22,33 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/exec"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crossplane/terrajet/pkg/config"
 	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+	"github.com/crossplane/terrajet/pkg/tracing"
 )
 
 const (
 	fmtEnv = "%s=%s"
+
+	errFmtDetachRunning = "cannot detach the workspace while its %s operation is running"
+
+	fileMainTF       = "main.tf.json"
+	fileState        = "terraform.tfstate"
+	fileErroredState = "errored.tfstate"
 )
 
 // SetupFn is a function that returns Terraform setup which contains
@@ -78,15 +90,80 @@ func WithProviderRunner(pr ProviderRunner) WorkspaceStoreOption {
 	}
 }
 
+// WithOutputSinkFn configures the function that returns the OutputSink of
+// the workspace for the given resource. The output of Terraform CLI is
+// streamed to that sink while the operations are running.
+func WithOutputSinkFn(fn NewOutputSinkFn) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.newOutputSink = fn
+	}
+}
+
+// WithWorkspaceBackend configures the WorkspaceBackend where the Terraform
+// state and the dependency lock file of the workspaces are stored. If the
+// workspace directory of a resource doesn't have these files on the local
+// disk, e.g. because the provider was moved to another node, they're restored
+// from the backend. Otherwise, they're only kept on the local disk and the
+// state is reproduced from the resource.
+func WithWorkspaceBackend(b WorkspaceBackend) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.backend = b
+	}
+}
+
+// WithCLIConfig configures the Terraform CLI configuration that's shared by
+// all workspaces, such as a plugin cache directory or a filesystem mirror of
+// the provider plugins, so that the plugins are not downloaded for every
+// workspace. The configuration file is generated in the root directory of
+// the workspaces.
+func WithCLIConfig(c CLIConfig) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.cliConfig = c
+	}
+}
+
+// WithGoldenWorkspace configures a workspace directory that has been
+// initialized with the same provider requirements as the workspaces of the
+// store, e.g. at image build time. The new workspaces are initialized by
+// copying its .terraform directory and dependency lock file instead of running
+// terraform init. If the golden workspace isn't initialized, terraform init
+// is run as usual.
+func WithGoldenWorkspace(dir string) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.goldenDir = dir
+	}
+}
+
+// WithScheduler configures the Scheduler that bounds the number of Terraform
+// CLI processes run by the workspaces of the store.
+func WithScheduler(s *Scheduler) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.scheduler = s
+	}
+}
+
+// WithTracerProvider configures the TracerProvider that records the spans of
+// the preparation of the workspaces and of their Terraform CLI calls. The
+// spans are tagged with the attributes of the managed resource carried by the
+// context, see tracing.WithResourceAttributes.
+func WithTracerProvider(tp trace.TracerProvider) WorkspaceStoreOption {
+	return func(ws *WorkspaceStore) {
+		ws.tracer = tracing.Tracer(tp)
+	}
+}
+
 // NewWorkspaceStore returns a new WorkspaceStore.
 func NewWorkspaceStore(l logging.Logger, opts ...WorkspaceStoreOption) *WorkspaceStore {
 	ws := &WorkspaceStore{
 		store:          map[types.UID]*Workspace{},
+		diskUsage:      map[types.UID]int64{},
 		logger:         l,
 		mu:             sync.Mutex{},
 		fs:             afero.Afero{Fs: afero.NewOsFs()},
 		executor:       exec.New(),
 		providerRunner: NewNoOpProviderRunner(),
+		planCacheStats: &PlanCacheStats{},
+		tracer:         tracing.Tracer(nil),
 	}
 	for _, f := range opts {
 		f(ws)
@@ -103,12 +180,38 @@ type WorkspaceStore struct {
 	store          map[types.UID]*Workspace
 	logger         logging.Logger
 	providerRunner ProviderRunner
+	newOutputSink  NewOutputSinkFn
+	backend        WorkspaceBackend
+	cliConfig      CLIConfig
+	goldenDir      string
+	scheduler      *Scheduler
+	planCacheStats *PlanCacheStats
+	tracer         trace.Tracer
 	mu             sync.Mutex
 
+	// diskUsage is the size of the files in the directory of each workspace
+	// when it was last measured and diskBytes is their total.
+	diskUsage map[types.UID]int64
+	diskBytes int64
+
+	// cliConfigOnce makes sure the CLI configuration file is written once.
+	cliConfigOnce sync.Once
+	cliConfigEnv  string
+	cliConfigErr  error
+	// initMu serializes terraform init calls if a plugin cache is used
+	// since Terraform CLI doesn't support concurrent writes to the cache.
+	initMu sync.Mutex
+
 	fs       afero.Afero
 	executor exec.Interface
 }
 
+// PlanCacheStats returns the counts of the comparisons that the workspaces of
+// the store reused and computed.
+func (ws *WorkspaceStore) PlanCacheStats() *PlanCacheStats {
+	return ws.planCacheStats
+}
+
 // Workspace makes sure the Terraform workspace for the given resource is ready
 // to be used and returns the Workspace object configured to work in that
 // workspace folder in the filesystem.
@@ -117,15 +220,38 @@ func (ws *WorkspaceStore) Workspace(ctx context.Context, c resource.SecretClient
 	if err := ws.fs.MkdirAll(dir, os.ModePerm); err != nil {
 		return nil, errors.Wrap(err, "cannot create directory for workspace")
 	}
-	fp, err := NewFileProducer(ctx, c, dir, tr, ts, cfg)
+	if err := ws.writeCLIConfig(); err != nil {
+		return nil, err
+	}
+	l := ws.logger.WithValues("workspace", dir)
+	ws.mu.Lock()
+	_, known := ws.store[tr.GetUID()]
+	ws.mu.Unlock()
+	// The operations recorded in the workspace directory before this process
+	// started cannot be running anymore.
+	var interrupted *OperationRecord
+	if !known {
+		r, err := ws.recoverWorkspace(dir, l)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot recover workspace")
+		}
+		interrupted = r
+		if err := ws.restoreWorkspace(ctx, tr, dir); err != nil {
+			return nil, errors.Wrap(err, "cannot restore workspace from backend")
+		}
+	}
+	fctx, span := ws.tracer.Start(ctx, "NewFileProducer", trace.WithAttributes(tracing.ResourceAttributesFrom(ctx)...))
+	fp, err := NewFileProducer(fctx, c, dir, tr, ts, cfg)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot create a new file producer")
 	}
-	_, err = ws.fs.Stat(filepath.Join(fp.Dir, "terraform.tfstate"))
+	_, err = ws.fs.Stat(filepath.Join(fp.Dir, fileState))
 	if xpresource.Ignore(os.IsNotExist, err) != nil {
 		return nil, errors.Wrap(err, "cannot stat terraform.tfstate file")
 	}
-	if os.IsNotExist(err) {
+	// The state of a data source is produced by reading it.
+	if os.IsNotExist(err) && !cfg.DataSource {
 		if err := fp.WriteTFState(ctx); err != nil {
 			return nil, errors.Wrap(err, "cannot reproduce tfstate file")
 		}
@@ -133,38 +259,148 @@ func (ws *WorkspaceStore) Workspace(ctx context.Context, c resource.SecretClient
 	if err := fp.WriteMainTF(); err != nil {
 		return nil, errors.Wrap(err, "cannot write main tf file")
 	}
-	l := ws.logger.WithValues("workspace", dir)
-	attachmentConfig, err := ws.providerRunner.Start()
+	// An invalid timeout annotation is reported by the controller, see
+	// controller.Connector, and the configured timeouts are used instead.
+	ato, _ := NewAsyncTimeouts(tr, cfg.OperationTimeouts)
+	attachmentConfig, err := ws.startProvider(tr)
 	if err != nil {
+		ws.releaseProvider(tr.GetUID())
 		return nil, err
 	}
 	ws.mu.Lock()
 	w, ok := ws.store[tr.GetUID()]
 	if !ok {
-		ws.store[tr.GetUID()] = NewWorkspace(dir, WithLogger(l), WithExecutor(ws.executor))
+		uid := tr.GetUID()
+		measure := func() {
+			if err := ws.measureDiskUsage(uid, dir); err != nil {
+				l.Debug("cannot measure the disk usage of the workspace", "error", err.Error())
+			}
+		}
+		opts := []WorkspaceOption{WithLogger(l), WithExecutor(ws.executor), WithAferoFs(ws.fs), WithSingleNestedAttributes(cfg.SingleNestedAttributes), WithDataSource(cfg.DataSource), WithResourceType(cfg.Name), WithTracer(ws.tracer, tracing.ResourceAttributesFrom(ctx)...), WithPlanCacheStats(ws.planCacheStats), WithDiskUsageMeasurer(measure)}
+		if ws.newOutputSink != nil {
+			opts = append(opts, WithOutputSink(ws.newOutputSink(tr, l)))
+		}
+		if ws.scheduler != nil {
+			opts = append(opts, WithSchedulerFor(ws.scheduler, providerConfigName(tr)))
+		}
+		if fn := ws.providerGeneration(tr.GetUID()); fn != nil {
+			opts = append(opts, WithProviderGeneration(fn))
+		}
+		if ws.backend != nil {
+			// Only the identity of the resource is needed by the backend.
+			owner := &metav1.ObjectMeta{Name: tr.GetName(), Namespace: tr.GetNamespace(), UID: tr.GetUID()}
+			opts = append(opts, WithBackend(ws.backend, owner))
+		}
+		ws.store[tr.GetUID()] = NewWorkspace(dir, opts...)
 		w = ws.store[tr.GetUID()]
+		workspaces.Set(float64(len(ws.store)))
+		if interrupted != nil {
+			w.setInterrupted(tferrors.NewOperationInterrupted(interrupted.Type, *interrupted.StartTime))
+		}
 	}
 	ws.mu.Unlock()
-	_, err = ws.fs.Stat(filepath.Join(dir, ".terraform.lock.hcl"))
-	if xpresource.Ignore(os.IsNotExist, err) != nil {
-		return nil, errors.Wrap(err, "cannot stat init lock file")
+	initialized, err := ws.isInitialized(dir)
+	if err != nil {
+		return nil, err
+	}
+	// A new slice is built since the async operations of the workspace
+	// could be reading the current one.
+	env := append(make([]string, 0, len(ts.Env)+2), ts.Env...)
+	env = append(env, fmt.Sprintf(fmtEnv, envReattachConfig, attachmentConfig))
+	if ws.cliConfigEnv != "" {
+		env = append(env, ws.cliConfigEnv)
 	}
-	w.env = ts.Env
-	w.env = append(w.env, fmt.Sprintf(fmtEnv, envReattachConfig, attachmentConfig))
+	w.configure(env, ato)
 	// We need to initialize only if the workspace hasn't been initialized yet.
-	if !os.IsNotExist(err) {
-		return w, nil
+	if !initialized {
+		if err := ws.initWorkspace(ctx, w, l); err != nil {
+			return nil, err
+		}
+	}
+	// The disk usage is measured once the workspace is initialized or, if it
+	// was initialized before this process started, added to the store. It's
+	// measured again after the operations that change its files, see
+	// WithDiskUsageMeasurer.
+	if !ok || !initialized {
+		w.diskUsageChanged()
+	}
+	return w, nil
+}
+
+// initWorkspace initializes the given workspace by copying the golden
+// workspace if it's configured and initialized, or by running terraform init.
+func (ws *WorkspaceStore) initWorkspace(ctx context.Context, w *Workspace, l logging.Logger) (err error) {
+	ctx, span := w.startSpan(ctx, "init")
+	defer func() { tracing.End(span, err) }()
+	if ws.goldenDir != "" {
+		copied, err := copyGoldenWorkspace(ws.fs, ws.goldenDir, w.dir)
+		if err != nil {
+			return errors.Wrap(err, "cannot initialize workspace from golden workspace")
+		}
+		if copied {
+			return nil
+		}
+		l.Debug("golden workspace is not initialized, running init", "golden", ws.goldenDir)
 	}
+	// The init call downloads the providers, so it's bounded by the
+	// Scheduler like the other Terraform CLI calls.
+	release, err := w.schedule(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer release()
 	cmd := w.executor.CommandContext(ctx, "terraform", "init", "-input=false")
 	cmd.SetDir(w.dir)
+	if ws.cliConfigEnv != "" {
+		cmd.SetEnv(append(os.Environ(), ws.cliConfigEnv))
+	}
+	if ws.cliConfig.PluginCacheDir != "" {
+		ws.initMu.Lock()
+		defer ws.initMu.Unlock()
+	}
+	start := time.Now()
 	out, err := cmd.CombinedOutput()
+	observeCLIDuration("init", w.resourceType, start, err)
 	l.Debug("init ended", "out", string(out))
-	return w, errors.Wrapf(err, "cannot init workspace: %s", string(out))
+	return errors.Wrapf(err, "cannot init workspace: %s", string(out))
+}
+
+// measureDiskUsage records the size of the files in the given workspace
+// directory and updates the total size of the workspaces.
+func (ws *WorkspaceStore) measureDiskUsage(uid types.UID, dir string) error {
+	var size int64
+	err := ws.fs.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "cannot walk workspace directory")
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	// The workspace could have been removed in the meantime.
+	if _, ok := ws.store[uid]; !ok {
+		return nil
+	}
+	ws.diskBytes += size - ws.diskUsage[uid]
+	ws.diskUsage[uid] = size
+	workspaceDiskBytes.Set(float64(ws.diskBytes))
+	return nil
 }
 
 // Remove deletes the workspace directory from the filesystem and erases its
-// record from the store.
+// record from the store and the WorkspaceBackend.
 func (ws *WorkspaceStore) Remove(obj xpresource.Object) error {
+	if ws.backend != nil {
+		if err := ws.backend.Delete(context.TODO(), obj); err != nil {
+			return errors.Wrap(err, "cannot delete workspace from backend")
+		}
+	}
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
 	w, ok := ws.store[obj.GetUID()]
@@ -174,6 +410,211 @@ func (ws *WorkspaceStore) Remove(obj xpresource.Object) error {
 	if err := ws.fs.RemoveAll(w.dir); err != nil {
 		return errors.Wrap(err, "cannot remove workspace folder")
 	}
-	delete(ws.store, obj.GetUID())
+	ws.release(obj.GetUID())
 	return nil
 }
+
+// Detach drops the workspace of the given resource without destroying its
+// external resource. It fails while an operation of the workspace is running
+// so that the operation isn't left half-done. The check and the removal from
+// the store are done under the lock so that no operation can start in
+// between, see WorkspaceCollector.release.
+func (ws *WorkspaceStore) Detach(ctx context.Context, obj xpresource.Object) error {
+	ws.mu.Lock()
+	w, ok := ws.store[obj.GetUID()]
+	if ok && w.LastOperation.IsRunning() {
+		ws.mu.Unlock()
+		return errors.Errorf(errFmtDetachRunning, w.LastOperation.Type)
+	}
+	if ok {
+		ws.release(obj.GetUID())
+	}
+	ws.mu.Unlock()
+	if ws.backend != nil {
+		if err := ws.backend.Delete(ctx, obj); err != nil {
+			return errors.Wrap(err, "cannot delete workspace from backend")
+		}
+	}
+	if !ok {
+		return nil
+	}
+	return errors.Wrap(ws.fs.RemoveAll(w.dir), "cannot remove workspace folder")
+}
+
+// startProvider starts the native provider process that the workspace of
+// the given resource uses, if it's not already running, and returns its
+// reattach configuration.
+func (ws *WorkspaceStore) startProvider(mg xpresource.Managed) (string, error) {
+	if ar, ok := ws.providerRunner.(AssigningProviderRunner); ok {
+		return ar.StartFor(mg.GetUID(), providerConfigName(mg))
+	}
+	return ws.providerRunner.Start()
+}
+
+// releaseProvider removes the assignment of the workspace with the given UID
+// to a native provider process if the workspace is not in the store, e.g.
+// because the process it was assigned to couldn't be started, since it's
+// released only when the workspace is removed from the store.
+func (ws *WorkspaceStore) releaseProvider(uid types.UID) {
+	ar, ok := ws.providerRunner.(AssigningProviderRunner)
+	if !ok {
+		return
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if _, ok := ws.store[uid]; !ok {
+		ar.Release(uid)
+	}
+}
+
+// providerGeneration returns the function that reports the generation of
+// the native provider process that the workspace with the given UID uses, or
+// nil if the ProviderRunner doesn't track it.
+func (ws *WorkspaceStore) providerGeneration(uid types.UID) func() uint64 {
+	switch pr := ws.providerRunner.(type) {
+	case AssigningProviderRunner:
+		return func() uint64 {
+			return pr.GenerationFor(uid)
+		}
+	case ProviderGenerationTracker:
+		return pr.Generation
+	}
+	return nil
+}
+
+// release erases the record of the workspace with the given UID from the
+// store and the ProviderRunner. It must be called with the lock held.
+func (ws *WorkspaceStore) release(uid types.UID) {
+	delete(ws.store, uid)
+	workspaces.Set(float64(len(ws.store)))
+	ws.diskBytes -= ws.diskUsage[uid]
+	delete(ws.diskUsage, uid)
+	workspaceDiskBytes.Set(float64(ws.diskBytes))
+	if ar, ok := ws.providerRunner.(AssigningProviderRunner); ok {
+		ar.Release(uid)
+	}
+}
+
+// recoverWorkspace checks whether the last async operation recorded in the
+// given workspace directory was interrupted, i.e. the provider process that
+// started it was terminated before the operation ended, and returns its
+// record if so. Terraform writes the state it cannot persist to
+// errored.tfstate, in which case it's restored. If the state file was left
+// half-written, it's removed so that it's reproduced from the resource.
+func (ws *WorkspaceStore) recoverWorkspace(dir string, l logging.Logger) (*OperationRecord, error) {
+	raw, err := ws.fs.ReadFile(filepath.Join(dir, operationRecordFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read operation record")
+	}
+	r := &OperationRecord{}
+	if err := json.JSParser.Unmarshal(raw, r); err != nil {
+		// The record itself could be left half-written, in which case
+		// there is nothing we can tell about the operation.
+		l.Info("removing the operation record that cannot be parsed", "error", err.Error())
+		return nil, errors.Wrap(ws.fs.Remove(filepath.Join(dir, operationRecordFile)), "cannot remove operation record")
+	}
+	if !r.IsInterrupted() {
+		return nil, nil
+	}
+	l.Info("recovering workspace after interrupted operation", "type", r.Type, "startTime", r.StartTime.String())
+	_, err = ws.fs.Stat(filepath.Join(dir, fileErroredState))
+	switch {
+	case err == nil:
+		if err := ws.fs.Rename(filepath.Join(dir, fileErroredState), filepath.Join(dir, fileState)); err != nil {
+			return nil, errors.Wrap(err, "cannot restore errored state")
+		}
+	case os.IsNotExist(err):
+		if err := ws.removeCorruptState(dir); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Wrap(err, "cannot stat errored state file")
+	}
+	now := time.Now()
+	ended := *r
+	ended.EndTime = &now
+	b, err := json.JSParser.Marshal(ended)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal operation record")
+	}
+	return r, errors.Wrap(ws.fs.WriteFile(filepath.Join(dir, operationRecordFile), b, 0600), "cannot write operation record")
+}
+
+// removeCorruptState removes the state file in the given directory if it
+// cannot be parsed.
+func (ws *WorkspaceStore) removeCorruptState(dir string) error {
+	raw, err := ws.fs.ReadFile(filepath.Join(dir, fileState))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "cannot read terraform state file")
+	}
+	if json.JSParser.Unmarshal(raw, &json.StateV4{}) == nil {
+		return nil
+	}
+	return errors.Wrap(ws.fs.Remove(filepath.Join(dir, fileState)), "cannot remove corrupt terraform state file")
+}
+
+// writeCLIConfig writes the CLI configuration file once if it's configured.
+func (ws *WorkspaceStore) writeCLIConfig() error {
+	if ws.cliConfig.IsEmpty() {
+		return nil
+	}
+	ws.cliConfigOnce.Do(func() {
+		ws.cliConfigEnv, ws.cliConfigErr = writeCLIConfig(ws.fs, ws.fs.GetTempDir(""), ws.cliConfig)
+	})
+	return ws.cliConfigErr
+}
+
+// restoreWorkspace writes the files stored in the WorkspaceBackend to the
+// given workspace directory unless they already exist on the local disk.
+func (ws *WorkspaceStore) restoreWorkspace(ctx context.Context, o metav1.Object, dir string) error {
+	if ws.backend == nil {
+		return nil
+	}
+	files, err := ws.backend.Load(ctx, o)
+	if err != nil {
+		return err
+	}
+	for f, b := range files {
+		p := filepath.Join(dir, f)
+		_, err := ws.fs.Stat(p)
+		if err == nil {
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return errors.Wrapf(err, "cannot stat %s", f)
+		}
+		if err := ws.fs.WriteFile(p, b, 0600); err != nil {
+			return errors.Wrapf(err, "cannot write %s", f)
+		}
+	}
+	return nil
+}
+
+// isInitialized returns whether terraform init has been run in the given
+// workspace directory. The dependency lock file alone is not enough since it
+// could be restored from the WorkspaceBackend without the providers.
+func (ws *WorkspaceStore) isInitialized(dir string) (bool, error) {
+	for _, f := range []string{fileLock, dirDotTerraform} {
+		_, err := ws.fs.Stat(filepath.Join(dir, f))
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrapf(err, "cannot stat %s", f)
+		}
+	}
+	return true, nil
+}
+
+func providerConfigName(mg xpresource.Managed) string {
+	if ref := mg.GetProviderConfigReference(); ref != nil {
+		return ref.Name
+	}
+	return ""
+}
@@ -0,0 +1,407 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	xpfake "github.com/crossplane/crossplane-runtime/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/afero"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	testingexec "k8s.io/utils/exec/testing"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	"github.com/crossplane/terrajet/pkg/terraform/fake"
+)
+
+func TestRecoverWorkspace(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(pid string, ended bool) string {
+		if ended {
+			return fmt.Sprintf(`{"type":"apply","startTime":"%s","endTime":"%s","processID":"%s"}`, start.Format(time.RFC3339), start.Format(time.RFC3339), pid)
+		}
+		return fmt.Sprintf(`{"type":"apply","startTime":"%s","processID":"%s"}`, start.Format(time.RFC3339), pid)
+	}
+	type args struct {
+		files map[string]string
+	}
+	type want struct {
+		r     *OperationRecord
+		state string
+		ended bool
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"NoRecord": {
+			reason: "Nothing should be done if there is no recorded operation.",
+			args: args{
+				files: map[string]string{fileState: tfstate},
+			},
+			want: want{
+				state: tfstate,
+			},
+		},
+		"CorruptRecord": {
+			reason: "A record that cannot be parsed should be removed.",
+			args: args{
+				files: map[string]string{operationRecordFile: `{"type":`, fileState: tfstate},
+			},
+			want: want{
+				state: tfstate,
+			},
+		},
+		"Ended": {
+			reason: "Nothing should be done if the recorded operation has ended.",
+			args: args{
+				files: map[string]string{operationRecordFile: record("other-process", true), fileState: tfstate},
+			},
+			want: want{
+				state: tfstate,
+				ended: true,
+			},
+		},
+		"CurrentProcess": {
+			reason: "An operation started by the current process should not be treated as interrupted.",
+			args: args{
+				files: map[string]string{operationRecordFile: record(processID, false), fileState: tfstate},
+			},
+			want: want{
+				state: tfstate,
+			},
+		},
+		"ErroredState": {
+			reason: "The errored state of an interrupted operation should be restored.",
+			args: args{
+				files: map[string]string{operationRecordFile: record("other-process", false), fileState: `{}`, fileErroredState: tfstate},
+			},
+			want: want{
+				r:     &OperationRecord{Type: "apply", StartTime: &start, ProcessID: "other-process"},
+				state: tfstate,
+				ended: true,
+			},
+		},
+		"CorruptState": {
+			reason: "A half-written state of an interrupted operation should be removed.",
+			args: args{
+				files: map[string]string{operationRecordFile: record("other-process", false), fileState: `{"version": 1,`},
+			},
+			want: want{
+				r:     &OperationRecord{Type: "apply", StartTime: &start, ProcessID: "other-process"},
+				ended: true,
+			},
+		},
+		"ValidState": {
+			reason: "A valid state of an interrupted operation should be kept.",
+			args: args{
+				files: map[string]string{operationRecordFile: record("other-process", false), fileState: tfstate},
+			},
+			want: want{
+				r:     &OperationRecord{Type: "apply", StartTime: &start, ProcessID: "other-process"},
+				state: tfstate,
+				ended: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for f, c := range tc.args.files {
+				if err := afero.WriteFile(fs, filepath.Join(directory, f), []byte(c), os.ModePerm); err != nil {
+					t.Fatalf("WriteFile(...): %v", err)
+				}
+			}
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs))
+			r, err := ws.recoverWorkspace(directory, logging.NewNopLogger())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nrecoverWorkspace(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.r, r); diff != "" {
+				t.Errorf("\n%s\nrecoverWorkspace(...): -want record, +got record:\n%s", tc.reason, diff)
+			}
+			state, _ := afero.ReadFile(fs, filepath.Join(directory, fileState))
+			if diff := cmp.Diff(tc.want.state, string(state)); diff != "" {
+				t.Errorf("\n%s\nrecoverWorkspace(...): -want state, +got state:\n%s", tc.reason, diff)
+			}
+			ended := false
+			if raw, err := afero.ReadFile(fs, filepath.Join(directory, operationRecordFile)); err == nil {
+				rec := OperationRecord{}
+				ended = json.JSParser.Unmarshal(raw, &rec) == nil && rec.EndTime != nil
+			}
+			if diff := cmp.Diff(tc.want.ended, ended); diff != "" {
+				t.Errorf("\n%s\nrecoverWorkspace(...): -want ended, +got ended:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRestoreWorkspace(t *testing.T) {
+	type args struct {
+		local   map[string]string
+		backend *fake.Backend
+	}
+	type want struct {
+		local map[string]string
+		err   error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Restored": {
+			reason: "The stored files should be restored if they don't exist on the local disk.",
+			args: args{
+				backend: &fake.Backend{Files: map[types.UID]map[string][]byte{
+					"some-uid": {fileState: []byte(tfstate), fileLock: []byte("lock")},
+				}},
+			},
+			want: want{
+				local: map[string]string{fileState: tfstate, fileLock: "lock"},
+			},
+		},
+		"LocalFilesKept": {
+			reason: "The files that exist on the local disk should not be overwritten.",
+			args: args{
+				local: map[string]string{fileState: `{"version": 4}`},
+				backend: &fake.Backend{Files: map[types.UID]map[string][]byte{
+					"some-uid": {fileState: []byte(tfstate), fileLock: []byte("lock")},
+				}},
+			},
+			want: want{
+				local: map[string]string{fileState: `{"version": 4}`, fileLock: "lock"},
+			},
+		},
+		"NothingStored": {
+			reason: "Nothing should be written if nothing is stored for the workspace.",
+			args: args{
+				backend: fake.NewBackend(),
+			},
+			want: want{
+				local: map[string]string{},
+			},
+		},
+		"LoadFailed": {
+			reason: "Errors from the backend should be returned.",
+			args: args{
+				backend: &fake.Backend{Err: errBoom},
+			},
+			want: want{
+				local: map[string]string{},
+				err:   errBoom,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for f, c := range tc.args.local {
+				if err := afero.WriteFile(fs, filepath.Join(directory, f), []byte(c), os.ModePerm); err != nil {
+					t.Fatalf("WriteFile(...): %v", err)
+				}
+			}
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs), WithWorkspaceBackend(tc.args.backend))
+			err := ws.restoreWorkspace(context.TODO(), &metav1.ObjectMeta{UID: "some-uid"}, directory)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nrestoreWorkspace(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			local := map[string]string{}
+			for _, f := range backendFiles {
+				if b, err := afero.ReadFile(fs, filepath.Join(directory, f)); err == nil {
+					local[f] = string(b)
+				}
+			}
+			if diff := cmp.Diff(tc.want.local, local); diff != "" {
+				t.Errorf("\n%s\nrestoreWorkspace(...): -want local files, +got local files:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestMeasureDiskUsage(t *testing.T) {
+	type args struct {
+		files    map[string]string
+		released bool
+	}
+	type want struct {
+		bytes float64
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Measured": {
+			reason: "The size of the files in the workspace directory should be reported.",
+			args: args{
+				files: map[string]string{fileMainTF: "main", fileState: "state", filepath.Join(dirDotTerraform, "provider"): "binary"},
+			},
+			want: want{
+				bytes: 15,
+			},
+		},
+		"Released": {
+			reason: "The size of a released workspace should not be reported.",
+			args: args{
+				files:    map[string]string{fileMainTF: "main"},
+				released: true,
+			},
+			want: want{
+				bytes: 0,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for f, c := range tc.args.files {
+				if err := afero.WriteFile(fs, filepath.Join(directory, f), []byte(c), os.ModePerm); err != nil {
+					t.Fatalf("WriteFile(...): %v", err)
+				}
+			}
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(fs))
+			ws.store["some-uid"] = NewWorkspace(directory)
+			if err := ws.measureDiskUsage("some-uid", directory); err != nil {
+				t.Fatalf("\n%s\nmeasureDiskUsage(...): unexpected error: %v", tc.reason, err)
+			}
+			if tc.args.released {
+				ws.release("some-uid")
+			}
+			if diff := cmp.Diff(tc.want.bytes, testutil.ToFloat64(workspaceDiskBytes)); diff != "" {
+				t.Errorf("\n%s\nmeasureDiskUsage(...): -want bytes, +got bytes:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReleaseProvider(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		inStore  bool
+		assigned bool
+	}{
+		"NotInStore": {
+			reason:   "The assignment of a workspace that is not in the store should be released.",
+			assigned: false,
+		},
+		"InStore": {
+			reason:   "The assignment of a workspace in the store should be kept until it's removed.",
+			inStore:  true,
+			assigned: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := NewSharedProviderPool(logging.NewNopLogger(), "path", "name", 2)
+			p.assign("uid", "pc")
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithProviderRunner(p))
+			if tc.inStore {
+				ws.store["uid"] = NewWorkspace("dir")
+			}
+			ws.releaseProvider("uid")
+			_, assigned := p.workspaces["uid"]
+			if diff := cmp.Diff(tc.assigned, assigned); diff != "" {
+				t.Errorf("\n%s\nreleaseProvider(...): -want assigned, +got assigned:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInitWorkspaceScheduled(t *testing.T) {
+	s := NewScheduler(WithSyncLimit(1))
+	release, err := s.Acquire(context.TODO(), "pc", false)
+	if err != nil {
+		t.Fatalf("Acquire(...): %v", err)
+	}
+	defer release()
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	// The init call should not run since there is no slot left.
+	w := NewWorkspace(directory, WithSchedulerFor(s, "pc"), WithExecutor(&testingexec.FakeExec{}))
+	ws := NewWorkspaceStore(logging.NewNopLogger())
+	err = ws.initWorkspace(ctx, w, logging.NewNopLogger())
+	if diff := cmp.Diff(context.Canceled, errors.Cause(err), test.EquateErrors()); diff != "" {
+		t.Errorf("initWorkspace(...): -want error, +got error:\n%s", diff)
+	}
+}
+
+func TestWorkspaceStoreDetach(t *testing.T) {
+	type args struct {
+		op *Operation
+	}
+	type want struct {
+		err      error
+		detached bool
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Running": {
+			reason: "The workspace should not be detached while its operation is running.",
+			args: args{
+				op: newCancellableOperation(applyType, false),
+			},
+			want: want{
+				err: errors.Errorf(errFmtDetachRunning, applyType),
+			},
+		},
+		"Idle": {
+			reason: "The workspace and its directory should be removed if no operation is running.",
+			args: args{
+				op: &Operation{},
+			},
+			want: want{
+				detached: true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mfs := afero.NewMemMapFs()
+			if err := afero.WriteFile(mfs, filepath.Join(directory, fileMainTF), []byte("{}"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			ws := NewWorkspaceStore(logging.NewNopLogger(), WithFs(mfs))
+			ws.store["some-uid"] = NewWorkspace(directory, WithLastOperation(tc.args.op))
+			err := ws.Detach(context.TODO(), &xpfake.Managed{ObjectMeta: metav1.ObjectMeta{UID: "some-uid"}})
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nDetach(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			_, inStore := ws.store["some-uid"]
+			exists, _ := afero.DirExists(mfs, directory)
+			if diff := cmp.Diff(tc.want.detached, !inStore && !exists); diff != "" {
+				t.Errorf("\n%s\nDetach(...): -want detached, +got detached:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
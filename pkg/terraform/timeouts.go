@@ -17,12 +17,23 @@
 package terraform
 
 import (
+	"time"
+
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/resource/json"
 )
 
+const (
+	// asyncTimeoutMargin is added to the configured operation timeouts while
+	// calculating the deadline of the async operations since the Terraform
+	// CLI does more than the operation itself, like refreshing the state.
+	asyncTimeoutMargin = 5 * time.Minute
+)
+
 // "e2bfb730-ecaa-11e6-8f88-34363bc7c4c0" is a hardcoded string for Terraform
 // timeout key in private raw, i.e. provider specific metadata:
 // https://github.com/hashicorp/terraform-plugin-sdk/blob/112e2164c381d80e8ada3170dac9a8a5db01079a/helper/schema/resource_timeout.go#L14
@@ -95,3 +106,47 @@ func insertTimeoutsMeta(existingMeta []byte, to timeouts) ([]byte, error) {
 	meta[tfMetaTimeoutKey] = customTimeouts
 	return json.JSParser.Marshal(meta)
 }
+
+// AsyncTimeouts are the durations after which the async operations are
+// stopped.
+type AsyncTimeouts struct {
+	Apply   time.Duration
+	Destroy time.Duration
+}
+
+// NewAsyncTimeouts returns the AsyncTimeouts of the given resource. The value
+// of the resource.AnnotationKeyAsyncTimeout annotation takes precedence for
+// all operations. Otherwise, the configured operation timeouts plus a margin
+// are used where apply is bound by the larger of create and update timeouts.
+// Operations without a configured timeout fall back to the default. If the
+// annotation is not a positive duration, the timeouts derived from the
+// configuration are returned along with an error to be reported.
+func NewAsyncTimeouts(o metav1.Object, to config.OperationTimeouts) (AsyncTimeouts, error) {
+	apply := to.Create
+	if to.Update > apply {
+		apply = to.Update
+	}
+	ato := AsyncTimeouts{
+		Apply:   withMargin(apply),
+		Destroy: withMargin(to.Delete),
+	}
+	v, ok := o.GetAnnotations()[resource.AnnotationKeyAsyncTimeout]
+	if !ok {
+		return ato, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return ato, errors.Wrapf(err, "cannot parse the value of annotation %s", resource.AnnotationKeyAsyncTimeout)
+	}
+	if d <= 0 {
+		return ato, errors.Errorf("the value of annotation %s must be a positive duration: %s", resource.AnnotationKeyAsyncTimeout, v)
+	}
+	return AsyncTimeouts{Apply: d, Destroy: d}, nil
+}
+
+func withMargin(d time.Duration) time.Duration {
+	if d == 0 {
+		return defaultAsyncTimeout
+	}
+	return d + asyncTimeoutMargin
+}
@@ -21,9 +21,13 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/crossplane/crossplane-runtime/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource"
 )
 
 func TestTimeoutsAsParameter(t *testing.T) {
@@ -227,3 +231,102 @@ func TestInsertTimeoutsMeta(t *testing.T) {
 		})
 	}
 }
+
+func TestNewAsyncTimeouts(t *testing.T) {
+	_, errParse := time.ParseDuration("forever")
+	type args struct {
+		annotations map[string]string
+		to          config.OperationTimeouts
+	}
+	type want struct {
+		out AsyncTimeouts
+		err error
+	}
+	cases := map[string]struct {
+		args
+		want
+	}{
+		"NoTimeouts": {
+			want: want{
+				out: AsyncTimeouts{
+					Apply:   defaultAsyncTimeout,
+					Destroy: defaultAsyncTimeout,
+				},
+			},
+		},
+		"ConfiguredTimeouts": {
+			args: args{
+				to: config.OperationTimeouts{
+					Create: 10 * time.Minute,
+					Update: 20 * time.Minute,
+					Delete: 30 * time.Minute,
+				},
+			},
+			want: want{
+				out: AsyncTimeouts{
+					Apply:   20*time.Minute + asyncTimeoutMargin,
+					Destroy: 30*time.Minute + asyncTimeoutMargin,
+				},
+			},
+		},
+		"AnnotationOverrides": {
+			args: args{
+				annotations: map[string]string{
+					resource.AnnotationKeyAsyncTimeout: "2h",
+				},
+				to: config.OperationTimeouts{
+					Create: 10 * time.Minute,
+				},
+			},
+			want: want{
+				out: AsyncTimeouts{
+					Apply:   2 * time.Hour,
+					Destroy: 2 * time.Hour,
+				},
+			},
+		},
+		"InvalidAnnotation": {
+			args: args{
+				annotations: map[string]string{
+					resource.AnnotationKeyAsyncTimeout: "forever",
+				},
+			},
+			want: want{
+				out: AsyncTimeouts{
+					Apply:   defaultAsyncTimeout,
+					Destroy: defaultAsyncTimeout,
+				},
+				err: errors.Wrapf(errParse, "cannot parse the value of annotation %s", resource.AnnotationKeyAsyncTimeout),
+			},
+		},
+		"NonPositiveAnnotation": {
+			args: args{
+				annotations: map[string]string{
+					resource.AnnotationKeyAsyncTimeout: "-1h",
+				},
+				to: config.OperationTimeouts{
+					Delete: 30 * time.Minute,
+				},
+			},
+			want: want{
+				out: AsyncTimeouts{
+					Apply:   defaultAsyncTimeout,
+					Destroy: 30*time.Minute + asyncTimeoutMargin,
+				},
+				err: errors.Errorf("the value of annotation %s must be a positive duration: -1h", resource.AnnotationKeyAsyncTimeout),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &metav1.ObjectMeta{Annotations: tc.args.annotations}
+			got, err := NewAsyncTimeouts(o, tc.args.to)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNewAsyncTimeouts(...): -want error, +got error:\n%s", name, diff)
+			}
+			if diff := cmp.Diff(tc.want.out, got); diff != "" {
+				t.Errorf("\n%s\nNewAsyncTimeouts(...): -want out, +got out:\n%s", name, diff)
+			}
+		})
+	}
+}
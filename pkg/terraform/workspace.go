@@ -17,26 +17,47 @@ limitations under the License.
 package terraform
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sExec "k8s.io/utils/exec"
 
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 
+	"github.com/crossplane/terrajet/pkg/resource"
 	"github.com/crossplane/terrajet/pkg/resource/json"
 	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+	"github.com/crossplane/terrajet/pkg/tracing"
+	"github.com/crossplane/terrajet/pkg/workspace"
 )
 
 const (
 	defaultAsyncTimeout = 1 * time.Hour
+	// defaultInterruptGracePeriod is how long an interrupted operation is
+	// waited for to stop before its process is killed.
+	defaultInterruptGracePeriod = 30 * time.Second
+	// asyncPersistTimeout is how long storing the workspace files in the
+	// backend after an async operation can take.
+	asyncPersistTimeout = 1 * time.Minute
 )
 
+// refreshArgs are the arguments of the terraform call that refreshes the
+// state of the resource.
+var refreshArgs = []string{"apply", "-refresh-only", "-auto-approve", "-input=false", "-lock=false", "-json"}
+
 // WorkspaceOption allows you to configure Workspace objects.
 type WorkspaceOption func(*Workspace)
 
@@ -61,6 +82,111 @@ func WithLastOperation(lo *Operation) WorkspaceOption {
 	}
 }
 
+// WithOutputSink sets the OutputSink that the output of Terraform CLI is
+// streamed to while the operations are running. If it's not set, the output
+// is logged once the operation is completed.
+func WithOutputSink(s OutputSink) WorkspaceOption {
+	return func(w *Workspace) {
+		w.sink = s
+	}
+}
+
+// WithInterruptGracePeriod sets how long an ongoing apply operation is
+// waited for to stop after it's interrupted by a destroy call.
+func WithInterruptGracePeriod(d time.Duration) WorkspaceOption {
+	return func(w *Workspace) {
+		w.interruptGracePeriod = d
+	}
+}
+
+// WithAsyncTimeouts sets the durations after which the async operations of
+// Workspace are stopped.
+func WithAsyncTimeouts(to AsyncTimeouts) WorkspaceOption {
+	return func(w *Workspace) {
+		w.asyncTimeouts = to
+	}
+}
+
+// WithBackend configures the WorkspaceBackend where the files of the
+// workspace of the given object are stored after every operation that could
+// change them.
+func WithBackend(b WorkspaceBackend, o metav1.Object) WorkspaceOption {
+	return func(w *Workspace) {
+		w.backend = b
+		w.owner = o
+	}
+}
+
+// WithSchedulerFor configures the Scheduler that bounds the number of
+// Terraform CLI processes and the ProviderConfig of the resource the workspace
+// belongs to.
+func WithSchedulerFor(s *Scheduler, providerConfig string) WorkspaceOption {
+	return func(w *Workspace) {
+		w.scheduler = s
+		w.providerConfig = providerConfig
+	}
+}
+
+// WithProviderGeneration configures the function that returns the generation
+// of the native provider process the Terraform CLI is attached to, see
+// SharedProvider.Generation.
+func WithProviderGeneration(fn func() uint64) WorkspaceOption {
+	return func(w *Workspace) {
+		w.providerGeneration = fn
+	}
+}
+
+// WithDiskUsageMeasurer sets the function that is called after the
+// operations that change the files in the workspace directory, i.e. apply
+// and destroy, to measure their size.
+func WithDiskUsageMeasurer(fn func()) WorkspaceOption {
+	return func(w *Workspace) {
+		w.measureDiskUsage = fn
+	}
+}
+
+// WithSingleNestedAttributes sets the paths of the single nested attributes
+// of the resource, see config.Resource.SingleNestedAttributes.
+func WithSingleNestedAttributes(paths []string) WorkspaceOption {
+	return func(w *Workspace) {
+		w.singleNested = paths
+	}
+}
+
+// WithDataSource configures the Workspace to read a data source instead of
+// managing a resource, see Workspace.Refresh.
+func WithDataSource(dataSource bool) WorkspaceOption {
+	return func(w *Workspace) {
+		w.dataSource = dataSource
+	}
+}
+
+// WithPlanCacheStats sets the PlanCacheStats that count the comparisons
+// reused and computed by the Workspace, see Workspace.Plan and
+// Workspace.Observe.
+func WithPlanCacheStats(s *PlanCacheStats) WorkspaceOption {
+	return func(w *Workspace) {
+		w.planCacheStats = s
+	}
+}
+
+// WithResourceType sets the Terraform resource type of the workspace, which
+// labels the metrics of its operations.
+func WithResourceType(t string) WorkspaceOption {
+	return func(w *Workspace) {
+		w.resourceType = t
+	}
+}
+
+// WithTracer sets the tracer that records the spans of the Terraform CLI
+// calls of the workspace, which are tagged with the given attributes.
+func WithTracer(t trace.Tracer, attrs ...attribute.KeyValue) WorkspaceOption {
+	return func(w *Workspace) {
+		w.tracer = t
+		w.spanAttributes = attrs
+	}
+}
+
 // WithAferoFs lets you set the fs of WorkspaceStore.
 func WithAferoFs(fs afero.Fs) WorkspaceOption {
 	return func(ws *Workspace) {
@@ -72,10 +198,16 @@ func WithAferoFs(fs afero.Fs) WorkspaceOption {
 // directory.
 func NewWorkspace(dir string, opts ...WorkspaceOption) *Workspace {
 	w := &Workspace{
-		LastOperation: &Operation{},
-		dir:           dir,
-		logger:        logging.NewNopLogger(),
-		fs:            afero.Afero{Fs: afero.NewOsFs()},
+		LastOperation:        &Operation{},
+		dir:                  dir,
+		interruptGracePeriod: defaultInterruptGracePeriod,
+		logger:               logging.NewNopLogger(),
+		tracer:               tracing.Tracer(nil),
+		fs:                   afero.Afero{Fs: afero.NewOsFs()},
+		asyncTimeouts: AsyncTimeouts{
+			Apply:   defaultAsyncTimeout,
+			Destroy: defaultAsyncTimeout,
+		},
 	}
 	for _, f := range opts {
 		f(w)
@@ -85,7 +217,7 @@ func NewWorkspace(dir string, opts ...WorkspaceOption) *Workspace {
 
 // CallbackFn is the type of accepted function that can be called after an async
 // operation is completed.
-type CallbackFn func(error, context.Context) error
+type CallbackFn = workspace.CallbackFn
 
 // Workspace runs Terraform operations in its directory and holds the information
 // about their statuses.
@@ -94,11 +226,227 @@ type Workspace struct {
 	LastOperation *Operation
 
 	dir string
-	env []string
+	// env and asyncTimeouts are updated by the WorkspaceStore every time
+	// the workspace is returned while an async operation could be reading
+	// them, so they're guarded by configMu.
+	env      []string
+	configMu sync.RWMutex
+	// resourceType is the Terraform resource type of the workspace.
+	resourceType string
+
+	interruptGracePeriod time.Duration
+	asyncTimeouts        AsyncTimeouts
+	// refreshBeforeDestroy is set when an ongoing operation is interrupted
+	// so that the state it leaves behind is refreshed before the resource is
+	// destroyed.
+	refreshBeforeDestroy bool
+
+	// interrupted is the error about the last async operation that was
+	// interrupted by a restart of the provider. It's reported by the next
+	// Refresh or Observe call. It's guarded by interruptedMu since it's set
+	// by the WorkspaceStore.
+	interrupted   error
+	interruptedMu sync.Mutex
 
 	logger   logging.Logger
 	executor k8sExec.Interface
+	sink     OutputSink
 	fs       afero.Afero
+
+	tracer         trace.Tracer
+	spanAttributes []attribute.KeyValue
+
+	scheduler      *Scheduler
+	providerConfig string
+
+	providerGeneration func() uint64
+	// measureDiskUsage is called after the files in the workspace directory
+	// are changed by an operation.
+	measureDiskUsage func()
+
+	// singleNested are the paths of the attributes that are objects in the
+	// state but lists in the CRD.
+	singleNested []string
+	// dataSource is true if the workspace reads a data source.
+	dataSource bool
+
+	// planned is the fingerprint of the workspace when its configuration and
+	// state were last compared and lastPlan is the result of that comparison.
+	// They're reset by the operations that change the resource.
+	planned        string
+	lastPlan       PlanResult
+	planCacheStats *PlanCacheStats
+
+	backend WorkspaceBackend
+	owner   metav1.Object
+	// persisted are the files that were last stored in the backend.
+	persisted map[string][]byte
+}
+
+// runTF runs Terraform CLI with the given arguments in the workspace
+// directory and returns its combined output. If an OutputSink is configured,
+// the output is sent to it line by line while the command is running.
+func (w *Workspace) runTF(ctx context.Context, op string, args ...string) (out []byte, err error) {
+	ctx, span := w.startSpan(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	release, err := w.schedule(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	gen := w.generation()
+	start := time.Now()
+	out, err = w.run(w.command(ctx, args...), op)
+	observeCLIDuration(op, w.resourceType, start, err)
+	return out, w.checkProvider(err, out, op, gen)
+}
+
+// runTFAsync is the same as runTF except that it lets the last operation
+// be cancelled. Once the Terraform CLI process has started, cancelling the
+// operation sends it a SIGINT, upon which Terraform stops the ongoing
+// provider calls gracefully and persists the state. While the operation is
+// waiting for its turn, cancelling it stops the waiting.
+func (w *Workspace) runTFAsync(ctx context.Context, op string, args ...string) (out []byte, err error) {
+	ctx, span := w.startSpan(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	wctx, stopWaiting := context.WithCancel(ctx)
+	defer stopWaiting()
+	w.LastOperation.SetCancel(stopWaiting)
+	w.LastOperation.SetWaiting(true)
+	release, err := w.schedule(wctx, true)
+	w.LastOperation.SetWaiting(false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if w.LastOperation.IsCancelled() {
+		return nil, errors.Errorf("%s operation was cancelled before it started", op)
+	}
+	cmd := w.command(ctx, args...)
+	gen := w.generation()
+	start := time.Now()
+	out, err = w.runStarted(cmd, op, func() {
+		// Terraform CLI handles SIGINT by stopping the ongoing provider
+		// calls gracefully and persisting the state.
+		interrupt, kill := processSignals(cmd, syscall.SIGINT)
+		w.LastOperation.SetKill(kill)
+		w.LastOperation.SetCancel(interrupt)
+	})
+	observeCLIDuration(op, w.resourceType, start, err)
+	return out, w.checkProvider(err, out, op, gen)
+}
+
+// startSpan starts the span of the Terraform CLI call of the given
+// operation.
+func (w *Workspace) startSpan(ctx context.Context, op string) (context.Context, trace.Span) {
+	attrs := append([]attribute.KeyValue{tracing.AttributeOperation.String(op)}, w.spanAttributes...)
+	return w.tracer.Start(ctx, "terraform "+op, trace.WithAttributes(attrs...))
+}
+
+// generation returns the current generation of the native provider process.
+func (w *Workspace) generation() uint64 {
+	if w.providerGeneration == nil {
+		return 0
+	}
+	return w.providerGeneration()
+}
+
+// checkProvider returns a clear error that wraps the given one if the native
+// provider process that the failed operation was attached to exited while it
+// was running, in which case the operation can be retried as is. The
+// diagnostics in the given output of the operation are kept.
+func (w *Workspace) checkProvider(err error, out []byte, op string, gen uint64) error {
+	if err == nil || w.generation() == gen {
+		return err
+	}
+	return tferrors.NewProviderRestarted(op, out, err)
+}
+
+// operationFailed returns the error that reports the failure of an operation
+// with the given output. The errors that are not caused by the operation
+// itself, e.g. the exit of the native provider process, are returned as is.
+func operationFailed(err error, out []byte, fn func([]byte) error) error {
+	if tferrors.IsProviderRestarted(err) {
+		return err
+	}
+	return fn(out)
+}
+
+// schedule waits for the turn of the workspace to start a Terraform CLI
+// process if a Scheduler is configured.
+func (w *Workspace) schedule(ctx context.Context, async bool) (func(), error) {
+	if w.scheduler == nil {
+		return func() {}, nil
+	}
+	release, err := w.scheduler.Acquire(ctx, w.providerConfig, async)
+	return release, errors.Wrap(err, "cannot schedule terraform process")
+}
+
+// configure sets the environment variables of the Terraform CLI processes and
+// the timeouts of the async operations of the workspace.
+func (w *Workspace) configure(env []string, to AsyncTimeouts) {
+	w.configMu.Lock()
+	defer w.configMu.Unlock()
+	w.env = env
+	w.asyncTimeouts = to
+}
+
+// environment returns the environment variables of the Terraform CLI
+// processes of the workspace.
+func (w *Workspace) environment() []string {
+	w.configMu.RLock()
+	defer w.configMu.RUnlock()
+	return w.env
+}
+
+// timeouts returns the timeouts of the async operations of the workspace.
+func (w *Workspace) timeouts() AsyncTimeouts {
+	w.configMu.RLock()
+	defer w.configMu.RUnlock()
+	return w.asyncTimeouts
+}
+
+func (w *Workspace) command(ctx context.Context, args ...string) k8sExec.Cmd {
+	cmd := w.executor.CommandContext(ctx, "terraform", args...)
+	cmd.SetEnv(append(os.Environ(), w.environment()...))
+	cmd.SetDir(w.dir)
+	return cmd
+}
+
+func (w *Workspace) run(cmd k8sExec.Cmd, op string) ([]byte, error) {
+	if w.sink == nil {
+		return cmd.CombinedOutput()
+	}
+	ow := newOutputWriter(op, w.sink)
+	cmd.SetStdout(ow)
+	cmd.SetStderr(ow)
+	err := cmd.Run()
+	ow.Flush()
+	return ow.Bytes(), err
+}
+
+// runStarted is the same as run except that it calls the given function once
+// the process of the command has started.
+func (w *Workspace) runStarted(cmd k8sExec.Cmd, op string, started func()) ([]byte, error) {
+	b := &bytes.Buffer{}
+	var out io.Writer = b
+	var ow *outputWriter
+	if w.sink != nil {
+		ow = newOutputWriter(op, w.sink)
+		out = ow
+	}
+	cmd.SetStdout(out)
+	cmd.SetStderr(out)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	started()
+	err := cmd.Wait()
+	if ow != nil {
+		ow.Flush()
+		return ow.Bytes(), err
+	}
+	return b.Bytes(), err
 }
 
 // ApplyAsync makes a terraform apply call without blocking and calls the given
@@ -108,52 +456,71 @@ func (w *Workspace) ApplyAsync(callback CallbackFn) error {
 		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
 	}
 	w.LastOperation.MarkStart("apply")
-	ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(defaultAsyncTimeout))
+	w.resetPlan()
+	if err := w.recordOperation(); err != nil {
+		w.LastOperation.MarkEnd()
+		w.LastOperation.Flush()
+		return err
+	}
+	ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(w.timeouts().Apply))
 	go func() {
 		defer cancel()
-		cmd := w.executor.CommandContext(ctx, "terraform", "apply", "-auto-approve", "-input=false", "-lock=false", "-json")
-		cmd.SetEnv(append(os.Environ(), w.env...))
-		cmd.SetDir(w.dir)
-		out, err := cmd.CombinedOutput()
+		asyncOperations.WithLabelValues("apply").Inc()
+		defer asyncOperations.WithLabelValues("apply").Dec()
+		out, err := w.runTFAsync(ctx, "apply", "apply", "-auto-approve", "-input=false", "-lock=false", "-json")
+		// The state is stored even if the operation fails since the
+		// resource could be created or deleted partially.
+		if pErr := w.persistAsync(); pErr != nil {
+			w.logger.Info("cannot persist workspace", "error", pErr.Error())
+		}
+		w.diskUsageChanged()
+		// The operation must be checked before it's marked as ended since
+		// the destroy operation that interrupted it starts right after.
+		interrupted := w.LastOperation.IsCancelled()
 		w.LastOperation.MarkEnd()
 		w.logger.Debug("apply async ended", "out", string(out))
+		if rErr := w.recordOperation(); rErr != nil {
+			w.logger.Info("cannot record the end of the operation", "error", rErr.Error())
+		}
+		// The result of an interrupted apply is stale since the resource
+		// is being destroyed.
+		if interrupted {
+			w.logger.Debug("apply async was interrupted, its callback is skipped")
+			return
+		}
 		defer func() {
 			if cErr := callback(err, ctx); cErr != nil {
 				w.logger.Info("callback failed", "error", cErr.Error())
 			}
 		}()
 		if err != nil {
-			err = tferrors.NewApplyFailed(out)
+			err = operationFailed(err, out, tferrors.NewApplyFailed)
 		}
 	}()
 	return nil
 }
 
 // ApplyResult contains the state after the apply operation.
-type ApplyResult struct {
-	State *json.StateV4
-}
+type ApplyResult = workspace.ApplyResult
 
 // Apply makes a blocking terraform apply call.
 func (w *Workspace) Apply(ctx context.Context) (ApplyResult, error) {
 	if w.LastOperation.IsRunning() {
 		return ApplyResult{}, errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "apply", "-auto-approve", "-input=false", "-lock=false", "-json")
-	cmd.SetEnv(append(os.Environ(), w.env...))
-	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
+	w.resetPlan()
+	defer w.diskUsageChanged()
+	out, err := w.runTF(ctx, "apply", "apply", "-auto-approve", "-input=false", "-lock=false", "-json")
 	w.logger.Debug("apply ended", "out", string(out))
 	if err != nil {
-		return ApplyResult{}, tferrors.NewApplyFailed(out)
+		return ApplyResult{}, operationFailed(err, out, tferrors.NewApplyFailed)
 	}
-	raw, err := w.fs.ReadFile(filepath.Join(w.dir, "terraform.tfstate"))
-	if err != nil {
-		return ApplyResult{}, errors.Wrap(err, "cannot read terraform state file")
+	if err := w.persist(ctx); err != nil {
+		return ApplyResult{}, err
 	}
-	s := &json.StateV4{}
-	if err := json.JSParser.Unmarshal(raw, s); err != nil {
-		return ApplyResult{}, errors.Wrap(err, "cannot unmarshal tfstate file")
+	s, err := w.readState()
+	if err != nil {
+		return ApplyResult{}, err
 	}
 	return ApplyResult{State: s}, nil
 }
@@ -161,118 +528,347 @@ func (w *Workspace) Apply(ctx context.Context) (ApplyResult, error) {
 // DestroyAsync makes a non-blocking terraform destroy call. It doesn't accept
 // a callback because destroy operations are not time sensitive as ApplyAsync
 // where you might need to store the server-side computed information as soon
-// as possible.
+// as possible. An ongoing apply operation is interrupted first and an error
+// is returned until it ends. The state it leaves behind is refreshed before
+// the resource is destroyed.
 func (w *Workspace) DestroyAsync(callback CallbackFn) error {
 	switch {
 	// Destroy call is idempotent and can be called repeatedly.
 	case w.LastOperation.Type == "destroy":
 		return nil
-	// We cannot run destroy until current non-destroy operation is completed.
-	// TODO(muvaf): Gracefully terminate the ongoing apply operation?
+	// We cannot run destroy until current non-destroy operation is completed,
+	// so we interrupt it.
 	case w.LastOperation.IsRunning():
-		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+		if err := w.interrupt(); err != nil {
+			return err
+		}
 	}
+	interrupted := w.refreshBeforeDestroy
 	w.LastOperation.MarkStart("destroy")
-	ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(defaultAsyncTimeout))
+	w.resetPlan()
+	if err := w.recordOperation(); err != nil {
+		w.LastOperation.MarkEnd()
+		w.LastOperation.Flush()
+		return err
+	}
+	ctx, cancel := context.WithDeadline(context.TODO(), w.LastOperation.StartTime().Add(w.timeouts().Destroy))
 	go func() {
 		defer cancel()
-		cmd := w.executor.CommandContext(ctx, "terraform", "destroy", "-auto-approve", "-input=false", "-lock=false", "-json")
-		cmd.SetEnv(append(os.Environ(), w.env...))
-		cmd.SetDir(w.dir)
-		out, err := cmd.CombinedOutput()
+		asyncOperations.WithLabelValues("destroy").Inc()
+		defer asyncOperations.WithLabelValues("destroy").Dec()
+		var out []byte
+		var err error
+		// The state of an interrupted operation is refreshed first so that
+		// the resources it created before it stopped are destroyed.
+		if interrupted {
+			out, err = w.runTFAsync(ctx, "refresh", refreshArgs...)
+			if err != nil {
+				err = operationFailed(err, out, tferrors.NewRefreshFailed)
+			} else {
+				// The flag is cleared before the operation is marked as
+				// ended, after which the next destroy call reads it.
+				w.refreshBeforeDestroy = false
+			}
+		}
+		if err == nil {
+			out, err = w.runTFAsync(ctx, "destroy", "destroy", "-auto-approve", "-input=false", "-lock=false", "-json")
+			if err != nil {
+				err = operationFailed(err, out, tferrors.NewDestroyFailed)
+			}
+		}
+		// The state is stored even if the operation fails since the
+		// resource could be created or deleted partially.
+		if pErr := w.persistAsync(); pErr != nil {
+			w.logger.Info("cannot persist workspace", "error", pErr.Error())
+		}
+		w.diskUsageChanged()
 		w.LastOperation.MarkEnd()
 		w.logger.Debug("destroy async ended", "out", string(out))
-		defer func() {
-			if cErr := callback(err, ctx); cErr != nil {
-				w.logger.Info("callback failed", "error", cErr.Error())
-			}
-		}()
-		if err != nil {
-			err = tferrors.NewDestroyFailed(out)
+		if rErr := w.recordOperation(); rErr != nil {
+			w.logger.Info("cannot record the end of the operation", "error", rErr.Error())
+		}
+		if cErr := callback(err, ctx); cErr != nil {
+			w.logger.Info("callback failed", "error", cErr.Error())
 		}
 	}()
 	return nil
 }
 
-// Destroy makes a blocking terraform destroy call.
+// Destroy makes a blocking terraform destroy call. If there is an ongoing
+// operation, it's interrupted first and an error is returned until it ends.
+// The state it leaves behind is refreshed before the resource is destroyed.
 func (w *Workspace) Destroy(ctx context.Context) error {
 	if w.LastOperation.IsRunning() {
-		return errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
+		if err := w.interrupt(); err != nil {
+			return err
+		}
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "destroy", "-auto-approve", "-input=false", "-lock=false", "-json")
-	cmd.SetEnv(append(os.Environ(), w.env...))
-	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
+	w.resetPlan()
+	defer w.diskUsageChanged()
+	if w.refreshBeforeDestroy {
+		out, err := w.runTF(ctx, "refresh", refreshArgs...)
+		w.logger.Debug("refresh ended", "out", string(out))
+		if err != nil {
+			return operationFailed(err, out, tferrors.NewRefreshFailed)
+		}
+		w.refreshBeforeDestroy = false
+	}
+	out, err := w.runTF(ctx, "destroy", "destroy", "-auto-approve", "-input=false", "-lock=false", "-json")
 	w.logger.Debug("destroy ended", "out", string(out))
 	if err != nil {
-		return tferrors.NewDestroyFailed(out)
+		return operationFailed(err, out, tferrors.NewDestroyFailed)
+	}
+	return w.persist(ctx)
+}
+
+// diskUsageChanged measures the size of the files in the workspace directory
+// if a measurer is configured.
+func (w *Workspace) diskUsageChanged() {
+	if w.measureDiskUsage != nil {
+		w.measureDiskUsage()
+	}
+}
+
+// readState reads the state file of the workspace. The single nested
+// attributes in the state are converted to the lists that the CRDs use.
+func (w *Workspace) readState() (*json.StateV4, error) {
+	raw, err := w.fs.ReadFile(filepath.Join(w.dir, fileState))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read terraform state file")
+	}
+	s := &json.StateV4{}
+	if err := json.JSParser.Unmarshal(raw, s); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal tfstate file")
+	}
+	if len(w.singleNested) == 0 || s.GetAttributes() == nil {
+		return s, nil
+	}
+	attr := map[string]interface{}{}
+	if err := json.JSParser.Unmarshal(s.GetAttributes(), &attr); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal state attributes")
+	}
+	resource.WrapSingleNested(attr, w.singleNested)
+	raw, err = json.JSParser.Marshal(attr)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal state attributes")
+	}
+	s.Resources[0].Instances[0].AttributesRaw = raw
+	return s, nil
+}
+
+// persist stores the files of the workspace in its WorkspaceBackend if they
+// have changed since they were last stored.
+func (w *Workspace) persist(ctx context.Context) error {
+	if w.backend == nil {
+		return nil
+	}
+	files, err := readBackendFiles(w.fs, w.dir)
+	if err != nil {
+		return errors.Wrap(err, "cannot read workspace files")
+	}
+	if equalFiles(files, w.persisted) {
+		return nil
+	}
+	if err := w.backend.Store(ctx, w.owner, files); err != nil {
+		return errors.Wrap(err, "cannot store workspace files")
 	}
+	w.persisted = files
 	return nil
 }
 
-// RefreshResult contains information about the current state of the resource.
-type RefreshResult struct {
-	Exists       bool
-	IsApplying   bool
-	IsDestroying bool
-	State        *json.StateV4
+// persistAsync is the same as persist except that it's bound by a timeout of
+// its own instead of the context of the async operation, whose deadline could
+// have passed. The partial state of an operation that timed out matters the
+// most.
+func (w *Workspace) persistAsync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), asyncPersistTimeout)
+	defer cancel()
+	return w.persist(ctx)
+}
+
+// recordOperation persists the last operation in the workspace directory so
+// that an operation interrupted by a restart of the provider can be detected
+// by the next process. See WorkspaceStore.Workspace.
+func (w *Workspace) recordOperation() error {
+	b, err := json.JSParser.Marshal(w.LastOperation.Record())
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal operation record")
+	}
+	return errors.Wrap(w.fs.WriteFile(filepath.Join(w.dir, operationRecordFile), b, 0600), "cannot write operation record")
+}
+
+// setInterrupted sets the error about the last async operation that was
+// interrupted by a restart of the provider.
+func (w *Workspace) setInterrupted(err error) {
+	w.interruptedMu.Lock()
+	defer w.interruptedMu.Unlock()
+	w.interrupted = err
+}
+
+// takeInterrupted returns the error about the last async operation that was
+// interrupted by a restart of the provider, if any, and clears it so that
+// it's reported only once.
+func (w *Workspace) takeInterrupted() error {
+	w.interruptedMu.Lock()
+	defer w.interruptedMu.Unlock()
+	err := w.interrupted
+	w.interrupted = nil
+	return err
+}
+
+// interrupt stops the ongoing operation gracefully and kills its process if
+// it's still running a grace period after it was interrupted. It doesn't wait
+// for the operation to end but returns an error until it ends, so that the
+// caller tries again on the next reconcile. Terraform persists the state of
+// the resources that are created before it's interrupted, which is refreshed
+// before the resource is destroyed.
+func (w *Workspace) interrupt() error {
+	switch t := w.LastOperation.CancelTime(); {
+	case t.IsZero():
+		if !w.LastOperation.Cancel() {
+			// The operation ended in the meantime.
+			return nil
+		}
+		w.refreshBeforeDestroy = true
+		w.logger.Debug("interrupted the ongoing operation", "type", w.LastOperation.Type)
+	case time.Since(t) >= w.interruptGracePeriod:
+		if w.LastOperation.Kill() {
+			w.logger.Debug("killed the ongoing operation", "type", w.LastOperation.Type)
+		}
+	}
+	select {
+	case <-w.LastOperation.Done():
+		return nil
+	default:
+		return errors.Errorf("waiting for the interrupted %s operation that started at %s to end", w.LastOperation.Type, w.LastOperation.StartTime().String())
+	}
 }
 
+// RefreshResult contains information about the current state of the resource.
+type RefreshResult = workspace.RefreshResult
+
 // Refresh makes a blocking terraform apply -refresh-only call where only the state file
-// is changed with the current state of the resource.
+// is changed with the current state of the resource. The workspace of a data
+// source makes a terraform apply call instead so that the data source is read
+// and stored in the state file even if it's not there yet. Since there is no
+// resource in the configuration of such a workspace, nothing can be changed
+// by that call.
 func (w *Workspace) Refresh(ctx context.Context) (RefreshResult, error) {
 	switch {
 	case w.LastOperation.IsRunning():
 		return RefreshResult{
 			IsApplying:   w.LastOperation.Type == "apply",
 			IsDestroying: w.LastOperation.Type == "destroy",
+			IsWaiting:    w.LastOperation.IsWaiting(),
 		}, nil
 	case w.LastOperation.IsEnded():
 		defer w.LastOperation.Flush()
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "apply", "-refresh-only", "-auto-approve", "-input=false", "-lock=false", "-json")
-	cmd.SetEnv(append(os.Environ(), w.env...))
-	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
+	args := refreshArgs
+	if w.dataSource {
+		args = []string{"apply", "-auto-approve", "-input=false", "-lock=false", "-json"}
+	}
+	out, err := w.runTF(ctx, "refresh", args...)
 	w.logger.Debug("refresh ended", "out", string(out))
 	if err != nil {
-		return RefreshResult{}, tferrors.NewRefreshFailed(out)
+		return RefreshResult{}, operationFailed(err, out, tferrors.NewRefreshFailed)
+	}
+	if err := w.persist(ctx); err != nil {
+		return RefreshResult{}, err
 	}
-	raw, err := w.fs.ReadFile(filepath.Join(w.dir, "terraform.tfstate"))
+	s, err := w.readState()
 	if err != nil {
-		return RefreshResult{}, errors.Wrap(err, "cannot read terraform state file")
+		return RefreshResult{}, err
 	}
-	s := &json.StateV4{}
-	if err := json.JSParser.Unmarshal(raw, s); err != nil {
-		return RefreshResult{}, errors.Wrap(err, "cannot unmarshal tfstate file")
+	res := RefreshResult{
+		Exists:               s.GetAttributes() != nil,
+		State:                s,
+		InterruptedOperation: w.takeInterrupted(),
 	}
-	return RefreshResult{
-		Exists: s.GetAttributes() != nil,
-		State:  s,
-	}, nil
+	return res, nil
 }
 
 // PlanResult returns a summary of comparison between desired and current state
 // of the resource.
-type PlanResult struct {
-	Exists   bool
-	UpToDate bool
-}
+type PlanResult = workspace.PlanResult
 
-// Plan makes a blocking terraform plan call.
+// Plan makes a blocking terraform plan call. The call is skipped and the
+// result of the last plan is returned if the main.tf.json file and the state
+// of the workspace are the same as they were when that plan ran.
 func (w *Workspace) Plan(ctx context.Context) (PlanResult, error) {
 	// The last operation is still ongoing.
 	if w.LastOperation.IsRunning() {
 		return PlanResult{}, errors.Errorf("%s operation that started at %s is still running", w.LastOperation.Type, w.LastOperation.StartTime().String())
 	}
-	cmd := w.executor.CommandContext(ctx, "terraform", "plan", "-refresh=false", "-input=false", "-lock=false", "-json")
-	cmd.SetEnv(append(os.Environ(), w.env...))
-	cmd.SetDir(w.dir)
-	out, err := cmd.CombinedOutput()
+	fp, err := w.fingerprint()
+	if err != nil {
+		// The plan is run as usual if the workspace cannot be fingerprinted.
+		w.logger.Debug("cannot fingerprint workspace", "error", err.Error())
+	}
+	if fp != "" && fp == w.planned {
+		w.planCacheStats.hit()
+		return w.lastPlan, nil
+	}
+	w.planCacheStats.miss()
+	w.planned = ""
+	res, err := w.plan(ctx)
+	if err == nil {
+		w.planned, w.lastPlan = fp, res
+	}
+	return res, err
+}
+
+// resetPlan forgets the result of the last plan, see Workspace.Plan.
+func (w *Workspace) resetPlan() {
+	w.planned = ""
+	w.lastPlan = PlanResult{}
+}
+
+func (w *Workspace) plan(ctx context.Context) (PlanResult, error) {
+	out, err := w.runTF(ctx, "plan", "plan", "-refresh=false", "-input=false", "-lock=false", "-json", "-out="+planFile)
 	w.logger.Debug("plan ended", "out", string(out))
+	defer w.removePlan()
 	if err != nil {
-		return PlanResult{}, tferrors.NewPlanFailed(out)
+		// The resource is protected against destruction unless its
+		// replacement is allowed, in which case the plan fails.
+		if isDestroyPrevented(out) {
+			return PlanResult{Exists: true, RequiresReplace: true}, nil
+		}
+		return PlanResult{}, operationFailed(err, out, tferrors.NewPlanFailed)
 	}
+	p, err := parseChangeSummary(out)
+	if err != nil {
+		return PlanResult{}, err
+	}
+	// Since there is only a single resource in the workspace, a plan that
+	// both adds and removes a resource is a replacement of it.
+	res := PlanResult{
+		Exists:   p.Add == 0 || p.Remove != 0,
+		UpToDate: p.Change == 0 && p.Remove == 0,
+	}
+	if !res.Exists || res.UpToDate {
+		return res, nil
+	}
+	c, err := w.showPlan(ctx)
+	if err != nil {
+		return PlanResult{}, err
+	}
+	res.RequiresReplace = c.Actions.Replace()
+	res.Diff = NewPlanDiff(c)
+	return res, nil
+}
+
+// changeSummary is the number of the resources that a plan adds, changes and
+// removes.
+type changeSummary struct {
+	Add    float64 `json:"add,omitempty"`
+	Change float64 `json:"change,omitempty"`
+	Remove float64 `json:"remove,omitempty"`
+}
+
+// parseChangeSummary returns the change summary in the JSON log of a plan
+// operation.
+func parseChangeSummary(out []byte) (changeSummary, error) {
 	line := ""
 	for _, l := range strings.Split(string(out), "\n") {
 		if strings.Contains(l, `"type":"change_summary"`) {
@@ -281,20 +877,140 @@ func (w *Workspace) Plan(ctx context.Context) (PlanResult, error) {
 		}
 	}
 	if line == "" {
-		return PlanResult{}, errors.Errorf("cannot find the change summary line in plan log: %s", string(out))
-	}
-	type plan struct {
-		Changes struct {
-			Add    float64 `json:"add,omitempty"`
-			Change float64 `json:"change,omitempty"`
-		} `json:"changes,omitempty"`
+		return changeSummary{}, errors.Errorf("cannot find the change summary line in plan log: %s", string(out))
 	}
-	p := &plan{}
+	p := &struct {
+		Changes changeSummary `json:"changes,omitempty"`
+	}{}
 	if err := json.JSParser.Unmarshal([]byte(line), p); err != nil {
-		return PlanResult{}, errors.Wrap(err, "cannot unmarshal change summary json")
+		return changeSummary{}, errors.Wrap(err, "cannot unmarshal change summary json")
+	}
+	return p.Changes, nil
+}
+
+// ObserveResult contains the refreshed state of the resource and the
+// comparison of its desired and current states.
+type ObserveResult = workspace.ObserveResult
+
+// Observe refreshes the state of the resource and compares it with the
+// desired configuration in a single terraform plan call, instead of the
+// separate Refresh and Plan calls. The refreshed state is read from the plan
+// file and stored in the state file the way Refresh does. The plan file is
+// shown only if the resource is not up-to-date and the refreshed state or the
+// configuration changed since the last comparison. The workspace of a data
+// source is only refreshed since there is nothing to compare.
+func (w *Workspace) Observe(ctx context.Context) (ObserveResult, error) {
+	if w.dataSource || w.LastOperation.IsRunning() {
+		r, err := w.Refresh(ctx)
+		return ObserveResult{RefreshResult: r, UpToDate: r.Exists}, err
+	}
+	if w.LastOperation.IsEnded() {
+		defer w.LastOperation.Flush()
+	}
+	out, err := w.runTF(ctx, "plan", "plan", "-refresh=true", "-input=false", "-lock=false", "-json", "-out="+planFile)
+	w.logger.Debug("plan ended", "out", string(out))
+	defer w.removePlan()
+	if err != nil {
+		// The plan file is not written if the replacement of the resource is
+		// prevented, so the state is refreshed separately.
+		if isDestroyPrevented(out) {
+			r, rErr := w.Refresh(ctx)
+			return ObserveResult{RefreshResult: r, RequiresReplace: true}, rErr
+		}
+		return ObserveResult{}, operationFailed(err, out, tferrors.NewPlanFailed)
+	}
+	if err := w.writePlannedState(); err != nil {
+		return ObserveResult{}, err
+	}
+	if err := w.persist(ctx); err != nil {
+		return ObserveResult{}, err
+	}
+	s, err := w.readState()
+	if err != nil {
+		return ObserveResult{}, err
+	}
+	res := ObserveResult{
+		RefreshResult: RefreshResult{
+			Exists:               s.GetAttributes() != nil,
+			State:                s,
+			InterruptedOperation: w.takeInterrupted(),
+		},
+	}
+	if !res.Exists {
+		return res, nil
+	}
+	// The plan file is not shown if the refreshed state and the
+	// configuration are the same as they were in the last comparison.
+	fp, err := w.fingerprint()
+	if err != nil {
+		w.logger.Debug("cannot fingerprint workspace", "error", err.Error())
+	}
+	if fp != "" && fp == w.planned {
+		w.planCacheStats.hit()
+		res.UpToDate, res.RequiresReplace, res.Diff = w.lastPlan.UpToDate, w.lastPlan.RequiresReplace, w.lastPlan.Diff
+		return res, nil
+	}
+	w.planCacheStats.miss()
+	w.planned = ""
+	p, err := parseChangeSummary(out)
+	if err != nil {
+		return ObserveResult{}, err
+	}
+	res.UpToDate = p.Change == 0 && p.Remove == 0
+	if !res.UpToDate {
+		c, err := w.showPlan(ctx)
+		if err != nil {
+			return ObserveResult{}, err
+		}
+		res.RequiresReplace = c.Actions.Replace()
+		res.Diff = NewPlanDiff(c)
+	}
+	w.planned = fp
+	w.lastPlan = PlanResult{Exists: true, UpToDate: res.UpToDate, RequiresReplace: res.RequiresReplace, Diff: res.Diff}
+	return res, nil
+}
+
+// writePlannedState writes the refreshed state in the plan file produced by
+// the last plan operation to the state file of the workspace.
+func (w *Workspace) writePlannedState() error {
+	raw, err := w.fs.ReadFile(filepath.Join(w.dir, planFile))
+	if err != nil {
+		return errors.Wrap(err, "cannot read plan file")
+	}
+	s, err := planFileState(raw)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(w.fs.WriteFile(filepath.Join(w.dir, fileState), s, 0600), "cannot write terraform state file")
+}
+
+// removePlan removes the plan file produced by the last plan operation since
+// it could contain the sensitive values of the resource.
+func (w *Workspace) removePlan() {
+	if err := w.fs.Remove(filepath.Join(w.dir, planFile)); err != nil && !os.IsNotExist(err) {
+		w.logger.Info("cannot remove plan file", "error", err.Error())
+	}
+}
+
+// showPlan returns the change of the resource in the plan file produced by
+// the last plan operation.
+func (w *Workspace) showPlan(ctx context.Context) (_ *tfjson.Change, err error) {
+	ctx, span := w.startSpan(ctx, "show")
+	defer func() { tracing.End(span, err) }()
+	release, err := w.schedule(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	cmd := w.executor.CommandContext(ctx, "terraform", "show", "-json", planFile)
+	cmd.SetEnv(append(os.Environ(), w.environment()...))
+	cmd.SetDir(w.dir)
+	start := time.Now()
+	out, err := cmd.Output()
+	observeCLIDuration("show", w.resourceType, start, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot show plan: %s", string(out))
 	}
-	return PlanResult{
-		Exists:   p.Changes.Add == 0,
-		UpToDate: p.Changes.Change == 0,
-	}, nil
+	c, err := ResourceChange(out)
+	return c, errors.Wrap(err, "cannot parse plan json")
 }
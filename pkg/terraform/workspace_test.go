@@ -17,13 +17,20 @@ limitations under the License.
 package terraform
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	k8sExec "k8s.io/utils/exec"
 	testingexec "k8s.io/utils/exec/testing"
 
@@ -31,6 +38,8 @@ import (
 
 	"github.com/crossplane/terrajet/pkg/resource/json"
 	tferrors "github.com/crossplane/terrajet/pkg/terraform/errors"
+	"github.com/crossplane/terrajet/pkg/terraform/fake"
+	"github.com/crossplane/terrajet/pkg/tracing"
 )
 
 var (
@@ -43,6 +52,10 @@ var (
 	directory             = "random-dir/"
 	changeSummaryAdd      = `{"@level":"info","@message":"Plan: 1 to add, 0 to change, 0 to destroy.","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","changes":{"add":1,"change":0,"remove":0,"operation":"plan"},"type":"change_summary"}`
 	changeSummaryUpdate   = `{"@level":"info","@message":"Plan: 0 to add, 1 to change, 0 to destroy.","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","changes":{"add":0,"change":1,"remove":0,"operation":"plan"},"type":"change_summary"}`
+	planUpdate            = `{"format_version":"1.0","resource_changes":[{"address":"very-cool-type.name","mode":"managed","type":"very-cool-type","name":"name","change":{"actions":["update"],"before":{"id":"some-id","size":1},"after":{"id":"some-id","size":2},"after_unknown":{},"before_sensitive":{},"after_sensitive":{}}}]}`
+	changeSummaryReplace  = `{"@level":"info","@message":"Plan: 1 to add, 0 to change, 1 to destroy.","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","changes":{"add":1,"change":0,"remove":1,"operation":"plan"},"type":"change_summary"}`
+	planReplace           = `{"format_version":"1.0","resource_changes":[{"address":"very-cool-type.name","mode":"managed","type":"very-cool-type","name":"name","change":{"actions":["delete","create"],"before":{"id":"some-id","zone":"a"},"after":{"zone":"b"},"after_unknown":{"id":true},"before_sensitive":{},"after_sensitive":{}}}]}`
+	destroyPrevented      = `{"@level":"error","@message":"Error: Instance cannot be destroyed","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","diagnostic":{"severity":"error","summary":"Instance cannot be destroyed","detail":"Resource very-cool-type.name has lifecycle.prevent_destroy set, but the plan calls for this resource to be destroyed."},"type":"diagnostic"}`
 	changeSummaryNoAction = `{"@level":"info","@message":"Plan: 0 to add, 0 to change, 0 to destroy.","@module":"terraform.ui","@timestamp":"0000-00-00T00:00:00.000000+03:00","changes":{"add":0,"change":0,"remove":0,"operation":"plan"},"type":"change_summary"}`
 
 	state = &json.StateV4{
@@ -79,6 +92,75 @@ func newFakeExec(stdOut string, err error) *testingexec.FakeExec {
 	}
 }
 
+func newFakeStreamingExec(stdOut string, err error) *testingexec.FakeExec {
+	return &testingexec.FakeExec{
+		CommandScript: []testingexec.FakeCommandAction{
+			func(_ string, _ ...string) k8sExec.Cmd {
+				return &testingexec.FakeCmd{
+					RunScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							return []byte(stdOut), nil, err
+						},
+					},
+				}
+			},
+		},
+	}
+}
+
+func newFakePlanExec(planOut, showOut string, more ...string) *testingexec.FakeExec {
+	e := &testingexec.FakeExec{}
+	// The outputs alternate between the plan and the show calls.
+	for i, out := range append([]string{planOut, showOut}, more...) {
+		out := out
+		if i%2 == 0 {
+			e.CommandScript = append(e.CommandScript, func(_ string, _ ...string) k8sExec.Cmd {
+				return &testingexec.FakeCmd{
+					CombinedOutputScript: []testingexec.FakeAction{
+						func() ([]byte, []byte, error) {
+							return []byte(out), nil, nil
+						},
+					},
+				}
+			})
+			continue
+		}
+		e.CommandScript = append(e.CommandScript, func(_ string, _ ...string) k8sExec.Cmd {
+			return &testingexec.FakeCmd{
+				OutputScript: []testingexec.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte(out), nil, nil
+					},
+				},
+			}
+		})
+	}
+	return e
+}
+
+// newCancellableOperation returns a running operation that ends when it's
+// cancelled if stops is true.
+func newCancellableOperation(t string, stops bool) *Operation {
+	o := &Operation{}
+	o.MarkStart(t)
+	o.SetCancel(func() {
+		if stops {
+			o.MarkEnd()
+		}
+	})
+	return o
+}
+
+// newGenerationFn returns a function that reports a different native provider
+// generation every time it's called.
+func newGenerationFn() func() uint64 {
+	var gen uint64
+	return func() uint64 {
+		gen++
+		return gen
+	}
+}
+
 func TestWorkspaceApply(t *testing.T) {
 	type args struct {
 		w *Workspace
@@ -119,6 +201,15 @@ func TestWorkspaceApply(t *testing.T) {
 				err: tferrors.NewApplyFailed([]byte(errBoom.Error())),
 			},
 		},
+		"ProviderRestarted": {
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeExec(errBoom.Error(), errBoom)), WithAferoFs(fs),
+					WithProviderGeneration(newGenerationFn())),
+			},
+			want: want{
+				err: tferrors.NewProviderRestarted("apply", []byte(errBoom.Error()), errBoom),
+			},
+		},
 	}
 
 	for name, tc := range cases {
@@ -138,6 +229,8 @@ func TestWorkspaceApply(t *testing.T) {
 }
 
 func TestWorkspaceDestroy(t *testing.T) {
+	stuckOp := newCancellableOperation(applyType, false)
+
 	type args struct {
 		w *Workspace
 	}
@@ -149,12 +242,12 @@ func TestWorkspaceDestroy(t *testing.T) {
 		args
 		want
 	}{
-		"Running": {
+		"Interrupting": {
 			args: args{
-				w: NewWorkspace(directory, WithLastOperation(&Operation{Type: testType, startTime: &now, endTime: nil})),
+				w: NewWorkspace(directory, WithLastOperation(stuckOp)),
 			},
 			want: want{
-				err: errors.Errorf("%s operation that started at %s is still running", testType, now.String()),
+				err: errors.Errorf("waiting for the interrupted %s operation that started at %s to end", applyType, stuckOp.StartTime().String()),
 			},
 		},
 		"Success": {
@@ -184,6 +277,129 @@ func TestWorkspaceDestroy(t *testing.T) {
 	}
 }
 
+func TestWorkspaceDestroyInterrupted(t *testing.T) {
+	var calls [][]string
+	record := func(_ string, args ...string) k8sExec.Cmd {
+		calls = append(calls, args)
+		return &testingexec.FakeCmd{
+			CombinedOutputScript: []testingexec.FakeAction{
+				func() ([]byte, []byte, error) { return nil, nil, nil },
+			},
+		}
+	}
+	w := NewWorkspace(directory, WithLastOperation(newCancellableOperation(applyType, true)), WithAferoFs(fs),
+		WithExecutor(&testingexec.FakeExec{CommandScript: []testingexec.FakeCommandAction{record, record, record}}))
+	if err := w.Destroy(context.TODO()); err != nil {
+		t.Fatalf("Destroy(...): unexpected error: %v", err)
+	}
+	// The state is refreshed only once after the interruption.
+	if err := w.Destroy(context.TODO()); err != nil {
+		t.Fatalf("Destroy(...): unexpected error: %v", err)
+	}
+	want := [][]string{refreshArgs, {"destroy", "-auto-approve", "-input=false", "-lock=false", "-json"}, {"destroy", "-auto-approve", "-input=false", "-lock=false", "-json"}}
+	if diff := cmp.Diff(want, calls); diff != "" {
+		t.Errorf("Destroy(...): the state of the interrupted operation should be refreshed before it's destroyed: -want calls, +got calls:\n%s", diff)
+	}
+}
+
+func TestWorkspaceInterrupt(t *testing.T) {
+	type want struct {
+		cancelled bool
+		killed    bool
+		err       bool
+	}
+	cases := map[string]struct {
+		reason      string
+		gracePeriod time.Duration
+		calls       int
+		want
+	}{
+		"Interrupted": {
+			reason:      "The ongoing operation should be cancelled without waiting for it to end.",
+			gracePeriod: time.Hour,
+			calls:       2,
+			want: want{
+				cancelled: true,
+				err:       true,
+			},
+		},
+		"Killed": {
+			reason: "The ongoing operation should be killed if it's still running after the grace period.",
+			calls:  2,
+			want: want{
+				cancelled: true,
+				killed:    true,
+				err:       true,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			op := &Operation{}
+			op.MarkStart(applyType)
+			op.SetCancel(func() { got.cancelled = true })
+			op.SetKill(func() { got.killed = true })
+			w := NewWorkspace(directory, WithLastOperation(op), WithInterruptGracePeriod(tc.gracePeriod))
+			var err error
+			for i := 0; i < tc.calls; i++ {
+				err = w.interrupt()
+			}
+			got.err = err != nil
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ninterrupt(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceMeasureDiskUsage(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		op     func(context.Context, *Workspace) error
+		want   int
+	}{
+		"Apply": {
+			reason: "The disk usage should be measured after an apply call",
+			op: func(ctx context.Context, w *Workspace) error {
+				_, err := w.Apply(ctx)
+				return err
+			},
+			want: 1,
+		},
+		"Destroy": {
+			reason: "The disk usage should be measured after a destroy call",
+			op: func(ctx context.Context, w *Workspace) error {
+				return w.Destroy(ctx)
+			},
+			want: 1,
+		},
+		"Refresh": {
+			reason: "The disk usage should not be measured after a refresh call",
+			op: func(ctx context.Context, w *Workspace) error {
+				_, err := w.Refresh(ctx)
+				return err
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			measured := 0
+			w := NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}), WithAferoFs(fs),
+				WithDiskUsageMeasurer(func() { measured++ }))
+			if err := w.fs.WriteFile(directory+"terraform.tfstate", []byte(tfstate), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.op(context.TODO(), w); err != nil {
+				t.Fatalf("\n%s\nunexpected error: %s", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, measured); diff != "" {
+				t.Errorf("\n%s\n-want measurements, +got measurements:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestWorkspaceRefresh(t *testing.T) {
 	type args struct {
 		w *Workspace
@@ -208,6 +424,18 @@ func TestWorkspaceRefresh(t *testing.T) {
 				},
 			},
 		},
+		"Waiting": {
+			args: args{
+				w: NewWorkspace(directory, WithLastOperation(&Operation{Type: applyType, startTime: &now, endTime: nil, waiting: true}),
+					WithAferoFs(fs)),
+			},
+			want: want{
+				r: RefreshResult{
+					IsApplying: true,
+					IsWaiting:  true,
+				},
+			},
+		},
 		"Success": {
 			args: args{
 				w: NewWorkspace(
@@ -219,6 +447,33 @@ func TestWorkspaceRefresh(t *testing.T) {
 				},
 			},
 		},
+		"DataSource": {
+			args: args{
+				w: NewWorkspace(directory, WithDataSource(true), WithAferoFs(fs), WithExecutor(&testingexec.FakeExec{
+					CommandScript: []testingexec.FakeCommandAction{
+						func(_ string, args ...string) k8sExec.Cmd {
+							var err error
+							// The data source should be read by a plain apply.
+							if diff := cmp.Diff([]string{"apply", "-auto-approve", "-input=false", "-lock=false", "-json"}, args); diff != "" {
+								err = errors.New(diff)
+							}
+							return &testingexec.FakeCmd{
+								CombinedOutputScript: []testingexec.FakeAction{
+									func() ([]byte, []byte, error) {
+										return nil, nil, err
+									},
+								},
+							}
+						},
+					},
+				})),
+			},
+			want: want{
+				r: RefreshResult{
+					State: state,
+				},
+			},
+		},
 		"Failure": {
 			args: args{
 				w: NewWorkspace(directory, WithExecutor(newFakeExec(errBoom.Error(), errBoom)), WithAferoFs(fs)),
@@ -287,12 +542,43 @@ func TestWorkspacePlan(t *testing.T) {
 		},
 		"ChangeSummaryUpdate": {
 			args: args{
-				w: NewWorkspace(directory, WithExecutor(newFakeExec(changeSummaryUpdate, nil))),
+				w: NewWorkspace(directory, WithExecutor(newFakePlanExec(changeSummaryUpdate, planUpdate))),
 			},
 			want: want{
 				r: PlanResult{
 					Exists:   true,
 					UpToDate: false,
+					Diff: PlanDiff{
+						{Path: "size", Before: float64(1), After: float64(2)},
+					},
+				},
+			},
+		},
+		"ChangeSummaryReplace": {
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakePlanExec(changeSummaryReplace, planReplace))),
+			},
+			want: want{
+				r: PlanResult{
+					Exists:          true,
+					UpToDate:        false,
+					RequiresReplace: true,
+					Diff: PlanDiff{
+						{Path: "id", Before: "some-id", Unknown: true},
+						{Path: "zone", Before: "a", After: "b"},
+					},
+				},
+			},
+		},
+		"ReplacementPrevented": {
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeExec(destroyPrevented, errBoom))),
+			},
+			want: want{
+				r: PlanResult{
+					Exists:          true,
+					UpToDate:        false,
+					RequiresReplace: true,
 				},
 			},
 		},
@@ -307,6 +593,18 @@ func TestWorkspacePlan(t *testing.T) {
 				},
 			},
 		},
+		"Streaming": {
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeStreamingExec(changeSummaryNoAction, nil)),
+					WithOutputSink(OutputSinkFn(func(_ string, _ UIMessage) {}))),
+			},
+			want: want{
+				r: PlanResult{
+					Exists:   true,
+					UpToDate: true,
+				},
+			},
+		},
 		"Failure": {
 			args: args{
 				w: NewWorkspace(directory, WithExecutor(newFakeExec(errBoom.Error(), errBoom))),
@@ -330,6 +628,320 @@ func TestWorkspacePlan(t *testing.T) {
 	}
 }
 
+func TestWorkspacePlanCache(t *testing.T) {
+	stateWithResource := `{"version": 4,"terraform_version": "1.0.10","serial": 3,"lineage": "very-cool-lineage","outputs": {},"resources": [{"mode": "managed","type": "very-cool-type","name": "name","provider": "provider.very-cool","instances": [{"schema_version": 0,"attributes": {"id": "some-id","size": 1}}]}]}`
+	noAction := func(_ string, _ ...string) k8sExec.Cmd {
+		return &testingexec.FakeCmd{
+			CombinedOutputScript: []testingexec.FakeAction{
+				func() ([]byte, []byte, error) {
+					return []byte(changeSummaryNoAction), nil, nil
+				},
+			},
+		}
+	}
+	type step struct {
+		// mainTF is the content of main.tf.json before the plan.
+		mainTF string
+		r      PlanResult
+	}
+	type want struct {
+		hits   uint64
+		misses uint64
+	}
+	cases := map[string]struct {
+		reason string
+		steps  []step
+		want
+	}{
+		"Unchanged": {
+			reason: "The plan should be skipped if nothing changed since the last plan that found the resource up-to-date",
+			steps: []step{
+				{mainTF: `{"resource":{"very-cool-type":{"name":{"size":1}}}}`, r: PlanResult{Exists: true, UpToDate: true}},
+				{mainTF: `{"resource":{"very-cool-type":{"name":{"size":1}}}}`, r: PlanResult{Exists: true, UpToDate: true}},
+			},
+			want: want{
+				hits:   1,
+				misses: 1,
+			},
+		},
+		"ConfigurationChanged": {
+			reason: "The plan should be run if the configuration changed since the last plan",
+			steps: []step{
+				{mainTF: `{"resource":{"very-cool-type":{"name":{"size":1}}}}`, r: PlanResult{Exists: true, UpToDate: true}},
+				{mainTF: `{"resource":{"very-cool-type":{"name":{"size":2}}}}`, r: PlanResult{Exists: true, UpToDate: true}},
+			},
+			want: want{
+				misses: 2,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			stats := &PlanCacheStats{}
+			mfs := afero.Afero{Fs: afero.NewMemMapFs()}
+			if err := mfs.WriteFile(directory+"terraform.tfstate", []byte(stateWithResource), 0600); err != nil {
+				t.Fatal(err)
+			}
+			w := NewWorkspace(directory, WithAferoFs(mfs), WithPlanCacheStats(stats),
+				// Every plan that is not skipped runs a command.
+				WithExecutor(&testingexec.FakeExec{CommandScript: []testingexec.FakeCommandAction{noAction, noAction}}))
+			for _, s := range tc.steps {
+				if err := mfs.WriteFile(directory+fileMainTF, []byte(s.mainTF), 0600); err != nil {
+					t.Fatal(err)
+				}
+				r, err := w.Plan(context.TODO())
+				if err != nil {
+					t.Fatalf("\n%s\nPlan(...): unexpected error: %s", tc.reason, err)
+				}
+				if diff := cmp.Diff(s.r, r); diff != "" {
+					t.Errorf("\n%s\nPlan(...): -want result, +got result:\n%s", tc.reason, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.want, want{hits: stats.Hits(), misses: stats.Misses()}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nPlan(...): -want stats, +got stats:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceObserve(t *testing.T) {
+	stateWithResource := `{"version": 4,"terraform_version": "1.0.10","serial": 3,"lineage": "very-cool-lineage","outputs": {},"resources": [{"mode": "managed","type": "very-cool-type","name": "name","provider": "provider.very-cool","instances": [{"schema_version": 0,"attributes": {"id": "some-id","size": 1}}]}]}`
+	refreshed := &json.StateV4{}
+	if err := json.JSParser.Unmarshal([]byte(stateWithResource), refreshed); err != nil {
+		t.Fatal(err)
+	}
+	type args struct {
+		w *Workspace
+	}
+	type want struct {
+		r   ObserveResult
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Running": {
+			reason: "The workspace should not be planned while an operation is running",
+			args: args{
+				w: NewWorkspace(directory, WithLastOperation(&Operation{Type: applyType, startTime: &now, endTime: nil})),
+			},
+			want: want{
+				r: ObserveResult{
+					RefreshResult: RefreshResult{
+						IsApplying: true,
+					},
+				},
+			},
+		},
+		"Failure": {
+			reason: "The failure of the plan should be reported",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeExec(errBoom.Error(), errBoom))),
+			},
+			want: want{
+				err: tferrors.NewPlanFailed([]byte(errBoom.Error())),
+			},
+		},
+		"UpToDate": {
+			reason: "The refreshed state in the plan file should be returned without showing the plan",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakeExec(changeSummaryNoAction, nil))),
+			},
+			want: want{
+				r: ObserveResult{
+					RefreshResult: RefreshResult{
+						Exists: true,
+						State:  refreshed,
+					},
+					UpToDate: true,
+				},
+			},
+		},
+		"Drifted": {
+			reason: "The diff of the plan should be returned if the resource is not up-to-date",
+			args: args{
+				w: NewWorkspace(directory, WithExecutor(newFakePlanExec(changeSummaryUpdate, planUpdate))),
+			},
+			want: want{
+				r: ObserveResult{
+					RefreshResult: RefreshResult{
+						Exists: true,
+						State:  refreshed,
+					},
+					Diff: PlanDiff{
+						{Path: "size", Before: float64(1), After: float64(2)},
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mfs := afero.Afero{Fs: afero.NewMemMapFs()}
+			writePlanFile(t, mfs, stateWithResource)
+			tc.w.fs = mfs
+			r, err := tc.w.Observe(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nObserve(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.r, r, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nObserve(...): -want result, +got result:\n%s", tc.reason, diff)
+			}
+			if exists, _ := mfs.Exists(directory + planFile); exists && !r.IsApplying && !r.IsDestroying {
+				t.Errorf("\n%s\nObserve(...): the plan file should be removed", tc.reason)
+			}
+		})
+	}
+}
+
+func TestWorkspaceObserveCache(t *testing.T) {
+	stateWithResource := `{"version": 4,"terraform_version": "1.0.10","serial": 3,"lineage": "very-cool-lineage","outputs": {},"resources": [{"mode": "managed","type": "very-cool-type","name": "name","provider": "provider.very-cool","instances": [{"schema_version": 0,"attributes": {"id": "some-id","size": 1}}]}]}`
+	type step struct {
+		mainTF string
+		diff   PlanDiff
+	}
+	type want struct {
+		hits   uint64
+		misses uint64
+	}
+	diff := PlanDiff{{Path: "size", Before: float64(1), After: float64(2)}}
+	cases := map[string]struct {
+		reason string
+		exec   *testingexec.FakeExec
+		steps  []step
+		want
+	}{
+		"Unchanged": {
+			reason: "The plan should not be shown again if the refreshed state and the configuration are unchanged",
+			exec:   newFakePlanExec(changeSummaryUpdate, planUpdate, changeSummaryUpdate),
+			steps: []step{
+				{mainTF: `{"resource":{"very-cool-type":{"name":{"size":2}}}}`, diff: diff},
+				{mainTF: `{"resource":{"very-cool-type":{"name":{"size":2}}}}`, diff: diff},
+			},
+			want: want{
+				hits:   1,
+				misses: 1,
+			},
+		},
+		"ConfigurationChanged": {
+			reason: "The plan should be shown again if the configuration changed since the last comparison",
+			exec:   newFakePlanExec(changeSummaryUpdate, planUpdate, changeSummaryUpdate, planUpdate),
+			steps: []step{
+				{mainTF: `{"resource":{"very-cool-type":{"name":{"size":2}}}}`, diff: diff},
+				{mainTF: `{"resource":{"very-cool-type":{"name":{"size":3}}}}`, diff: diff},
+			},
+			want: want{
+				misses: 2,
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			stats := &PlanCacheStats{}
+			mfs := afero.Afero{Fs: afero.NewMemMapFs()}
+			w := NewWorkspace(directory, WithAferoFs(mfs), WithPlanCacheStats(stats), WithExecutor(tc.exec))
+			for _, s := range tc.steps {
+				if err := mfs.WriteFile(directory+fileMainTF, []byte(s.mainTF), 0600); err != nil {
+					t.Fatal(err)
+				}
+				writePlanFile(t, mfs, stateWithResource)
+				r, err := w.Observe(context.TODO())
+				if err != nil {
+					t.Fatalf("\n%s\nObserve(...): unexpected error: %s", tc.reason, err)
+				}
+				if diff := cmp.Diff(s.diff, r.Diff); diff != "" {
+					t.Errorf("\n%s\nObserve(...): -want diff, +got diff:\n%s", tc.reason, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.want, want{hits: stats.Hits(), misses: stats.Misses()}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nObserve(...): -want stats, +got stats:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// writePlanFile writes a plan file with the given state since the plan file is
+// written by the terraform plan call, which is faked.
+func writePlanFile(t *testing.T, fs afero.Afero, state string) {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	f, err := zw.Create(planFileStateEntry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(state)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile(directory+planFile, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkspaceTracing(t *testing.T) {
+	attrs := tracing.ResourceAttributes(schema.GroupVersionKind{Group: "cool.group", Version: "v1alpha1", Kind: "CoolResource"}, "cool-resource")
+	type span struct {
+		Name       string
+		Status     codes.Code
+		Attributes []attribute.KeyValue
+	}
+	type args struct {
+		exec *testingexec.FakeExec
+	}
+	type want struct {
+		spans []span
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Success": {
+			reason: "The span of the CLI call should be tagged with the operation and the resource.",
+			args: args{
+				exec: newFakeExec(changeSummaryNoAction, nil),
+			},
+			want: want{
+				spans: []span{
+					{Name: "terraform plan", Attributes: append([]attribute.KeyValue{tracing.AttributeOperation.String("plan")}, attrs...)},
+				},
+			},
+		},
+		"Failure": {
+			reason: "The failure of the CLI call should be recorded.",
+			args: args{
+				exec: newFakeExec(errBoom.Error(), errBoom),
+			},
+			want: want{
+				spans: []span{
+					{Name: "terraform plan", Status: codes.Error, Attributes: append([]attribute.KeyValue{tracing.AttributeOperation.String("plan")}, attrs...)},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tp, exp := tracing.NewInMemoryTracerProvider()
+			w := NewWorkspace(directory, WithExecutor(tc.args.exec), WithTracer(tracing.Tracer(tp), attrs...))
+			_, _ = w.Plan(context.TODO())
+			var got []span
+			for _, s := range exp.GetSpans() {
+				got = append(got, span{Name: s.Name, Status: s.Status.Code, Attributes: s.Attributes})
+			}
+			if diff := cmp.Diff(tc.want.spans, got, cmp.AllowUnexported(attribute.Value{})); diff != "" {
+				t.Errorf("\n%s\nPlan(...): -want spans, +got spans:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
 func TestWorkspaceApplyAsync(t *testing.T) {
 	calls := make(chan bool)
 
@@ -356,7 +968,7 @@ func TestWorkspaceApplyAsync(t *testing.T) {
 		},
 		"Callback": {
 			args: args{
-				w: NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true})),
+				w: NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}), WithAferoFs(fs)),
 				c: func(err error, ctx context.Context) error {
 					calls <- true
 					return nil
@@ -366,6 +978,14 @@ func TestWorkspaceApplyAsync(t *testing.T) {
 				called: true,
 			},
 		},
+		"CannotRecord": {
+			args: args{
+				w: NewWorkspace(directory, WithAferoFs(afero.NewReadOnlyFs(afero.NewMemMapFs()))),
+			},
+			want: want{
+				err: errors.Wrap(syscall.EPERM, "cannot write operation record"),
+			},
+		},
 	}
 
 	for name, tc := range cases {
@@ -385,8 +1005,72 @@ func TestWorkspaceApplyAsync(t *testing.T) {
 	}
 }
 
+func TestWorkspaceApplyAsyncInterrupted(t *testing.T) {
+	called := make(chan struct{}, 1)
+	w := NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}), WithAferoFs(fs))
+	if err := w.ApplyAsync(func(error, context.Context) error {
+		called <- struct{}{}
+		return nil
+	}); err != nil {
+		t.Fatalf("ApplyAsync(...): %v", err)
+	}
+	if !w.LastOperation.Cancel() {
+		t.Fatal("Cancel(): the ongoing apply should be cancellable")
+	}
+	<-w.LastOperation.Done()
+	select {
+	case <-called:
+		t.Error("ApplyAsync(...): the callback of an interrupted apply should not be called")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWorkspaceConfigureDuringApplyAsync(t *testing.T) {
+	w := NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}), WithAferoFs(fs))
+	if err := w.ApplyAsync(func(error, context.Context) error { return nil }); err != nil {
+		t.Fatalf("ApplyAsync(...): %v", err)
+	}
+	// The WorkspaceStore reconfigures the workspace every time it's
+	// returned, which should be safe while the apply is running. This is
+	// checked by the race detector.
+	w.configure([]string{"KEY=value"}, AsyncTimeouts{Apply: time.Hour, Destroy: time.Hour})
+	<-w.LastOperation.Done()
+	if diff := cmp.Diff([]string{"KEY=value"}, w.environment()); diff != "" {
+		t.Errorf("configure(...): -want env, +got env:\n%s", diff)
+	}
+}
+
+// contextBackend is a WorkspaceBackend that sends the error of the context
+// of every Store call.
+type contextBackend struct {
+	WorkspaceBackend
+	errs chan error
+}
+
+func (b *contextBackend) Store(ctx context.Context, _ metav1.Object, _ map[string][]byte) error {
+	b.errs <- ctx.Err()
+	return nil
+}
+
+func TestWorkspaceApplyAsyncPersistAfterTimeout(t *testing.T) {
+	mfs := afero.NewMemMapFs()
+	if err := afero.WriteFile(mfs, directory+fileState, []byte(tfstate), 0600); err != nil {
+		t.Fatal(err)
+	}
+	b := &contextBackend{errs: make(chan error, 1)}
+	w := NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}), WithAferoFs(mfs),
+		WithAsyncTimeouts(AsyncTimeouts{Apply: time.Nanosecond}), WithBackend(b, &metav1.ObjectMeta{Name: "cool-resource"}))
+	if err := w.ApplyAsync(func(error, context.Context) error { return nil }); err != nil {
+		t.Fatalf("ApplyAsync(...): %v", err)
+	}
+	if err := <-b.errs; err != nil {
+		t.Errorf("ApplyAsync(...): the workspace should be persisted with a live context even if the operation timed out, got: %v", err)
+	}
+}
+
 func TestWorkspaceDestroyAsync(t *testing.T) {
 	calls := make(chan bool)
+	stuckOp := newCancellableOperation(applyType, false)
 
 	type args struct {
 		w *Workspace
@@ -401,17 +1085,30 @@ func TestWorkspaceDestroyAsync(t *testing.T) {
 		args
 		want
 	}{
-		"Running": {
+		"InterruptApply": {
 			args: args{
-				w: NewWorkspace(directory, WithLastOperation(&Operation{Type: testType, startTime: &now, endTime: nil})),
+				w: NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}),
+					WithLastOperation(newCancellableOperation(applyType, true)), WithAferoFs(fs)),
+				c: func(err error, ctx context.Context) error {
+					calls <- true
+					return nil
+				},
 			},
 			want: want{
-				err: errors.Errorf("%s operation that started at %s is still running", testType, now.String()),
+				called: true,
+			},
+		},
+		"Interrupting": {
+			args: args{
+				w: NewWorkspace(directory, WithLastOperation(stuckOp)),
+			},
+			want: want{
+				err: errors.Errorf("waiting for the interrupted %s operation that started at %s to end", applyType, stuckOp.StartTime().String()),
 			},
 		},
 		"Callback": {
 			args: args{
-				w: NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true})),
+				w: NewWorkspace(directory, WithExecutor(&testingexec.FakeExec{DisableScripts: true}), WithAferoFs(fs)),
 				c: func(err error, ctx context.Context) error {
 					calls <- true
 					return nil
@@ -426,7 +1123,7 @@ func TestWorkspaceDestroyAsync(t *testing.T) {
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
 			err := tc.w.DestroyAsync(tc.c)
-			if t.Name() == "TestWorkspaceDestroyAsync/Callback" {
+			if tc.args.c != nil {
 				called := <-calls
 
 				if diff := cmp.Diff(tc.want.called, called, test.EquateErrors()); diff != "" {
@@ -439,3 +1136,62 @@ func TestWorkspaceDestroyAsync(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkspacePersist(t *testing.T) {
+	type args struct {
+		backend   *fake.Backend
+		persisted map[string][]byte
+	}
+	type want struct {
+		stored map[string][]byte
+		err    error
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Stored": {
+			reason: "The files of the workspace should be stored in the backend.",
+			args: args{
+				backend: fake.NewBackend(),
+			},
+			want: want{
+				stored: map[string][]byte{fileState: []byte(tfstate)},
+			},
+		},
+		"Unchanged": {
+			reason: "The files should not be stored again if they haven't changed.",
+			args: args{
+				backend:   &fake.Backend{Err: errBoom},
+				persisted: map[string][]byte{fileState: []byte(tfstate)},
+			},
+		},
+		"StoreFailed": {
+			reason: "Errors from the backend should be returned.",
+			args: args{
+				backend: &fake.Backend{Err: errBoom},
+			},
+			want: want{
+				err: errors.Wrap(errBoom, "cannot store workspace files"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fs := afero.Afero{Fs: afero.NewMemMapFs()}
+			if err := fs.WriteFile(directory+fileState, []byte(tfstate), 0600); err != nil {
+				t.Fatalf("WriteFile(...): %v", err)
+			}
+			w := NewWorkspace(directory, WithAferoFs(fs), WithBackend(tc.args.backend, &metav1.ObjectMeta{UID: "some-uid"}))
+			w.persisted = tc.args.persisted
+			err := w.persist(context.TODO())
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\npersist(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.stored, tc.args.backend.Files["some-uid"]); diff != "" {
+				t.Errorf("\n%s\npersist(...): -want stored, +got stored:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing contains the helpers to trace the reconciliation of the
+// managed resources and the Terraform operations with OpenTelemetry.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// InstrumentationName is the name of the tracers of Terrajet.
+const InstrumentationName = "github.com/crossplane/terrajet"
+
+const (
+	// AttributeGVK is the GroupVersionKind of the managed resource.
+	AttributeGVK = attribute.Key("terrajet.resource.gvk")
+	// AttributeName is the name of the managed resource.
+	AttributeName = attribute.Key("terrajet.resource.name")
+	// AttributeOperation is the Terraform operation, e.g. plan or apply.
+	AttributeOperation = attribute.Key("terrajet.terraform.operation")
+)
+
+// Tracer returns the tracer of Terrajet from the given TracerProvider. If
+// it's nil, the spans are not recorded.
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = trace.NewNoopTracerProvider()
+	}
+	return tp.Tracer(InstrumentationName)
+}
+
+// NewTracerProvider returns a TracerProvider that exports the spans of the
+// service with the given name to the given exporter in batches. The exporter
+// could be any OpenTelemetry exporter, e.g. OTLP or Jaeger.
+func NewTracerProvider(exp sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+}
+
+// NewInMemoryTracerProvider returns a TracerProvider that exports the spans
+// to the returned in-memory exporter as soon as they end. It's meant to be
+// used in tests.
+func NewInMemoryTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exp := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)), exp
+}
+
+// ResourceAttributes returns the attributes that identify the managed
+// resource with the given GroupVersionKind and name.
+func ResourceAttributes(gvk schema.GroupVersionKind, name string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttributeGVK.String(gvk.String()),
+		AttributeName.String(name),
+	}
+}
+
+type resourceAttributesKey struct{}
+
+// WithResourceAttributes returns a copy of the given context that carries
+// the given attributes of a managed resource so that the spans started by
+// the callees that don't know the resource, e.g. for the async Terraform
+// operations, can be tagged with them.
+func WithResourceAttributes(ctx context.Context, attrs []attribute.KeyValue) context.Context {
+	return context.WithValue(ctx, resourceAttributesKey{}, attrs)
+}
+
+// ResourceAttributesFrom returns the attributes of the managed resource
+// carried by the given context, if any.
+func ResourceAttributesFrom(ctx context.Context) []attribute.KeyValue {
+	attrs, _ := ctx.Value(resourceAttributesKey{}).([]attribute.KeyValue)
+	return attrs
+}
+
+// End records the given error, if any, on the given span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestResourceAttributesFrom(t *testing.T) {
+	attrs := ResourceAttributes(schema.GroupVersionKind{Group: "cool.group", Version: "v1alpha1", Kind: "CoolResource"}, "cool-resource")
+	type args struct {
+		ctx context.Context
+	}
+	type want struct {
+		attrs []attribute.KeyValue
+	}
+	cases := map[string]struct {
+		reason string
+		args
+		want
+	}{
+		"Carried": {
+			reason: "The attributes carried by the context should be returned.",
+			args: args{
+				ctx: WithResourceAttributes(context.TODO(), attrs),
+			},
+			want: want{
+				attrs: attrs,
+			},
+		},
+		"NotCarried": {
+			reason: "No attributes should be returned if the context doesn't carry them.",
+			args: args{
+				ctx: context.TODO(),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ResourceAttributesFrom(tc.args.ctx)
+			if diff := cmp.Diff(tc.want.attrs, got, cmp.AllowUnexported(attribute.Value{})); diff != "" {
+				t.Errorf("\n%s\nResourceAttributesFrom(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
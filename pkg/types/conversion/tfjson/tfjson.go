@@ -17,6 +17,8 @@
 package tfjson
 
 import (
+	"sort"
+
 	tfjson "github.com/hashicorp/terraform-json"
 	schemav2 "github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
@@ -62,7 +64,7 @@ func v2ResourceFromTFJSONSchema(s *tfjson.Schema) *schemav2.Resource {
 		// schema map but set as a separate field. So, we just need to ignore
 		// here.
 		// https://github.com/hashicorp/terraform-plugin-sdk/blob/6461ac6e9044a44157c4e2c8aec0f1ab7efc2055/helper/schema/core_schema.go#L315
-		if v.NestingMode == tfjson.SchemaNestingModeSingle {
+		if isTimeoutsBlock(k, v) {
 			continue
 		}
 		toSchemaMap[k] = tfJSONBlockTypeToV2Schema(v)
@@ -83,12 +85,53 @@ func tfJSONAttributeToV2Schema(attr *tfjson.SchemaAttribute) *schemav2.Schema {
 		Deprecated:  deprecatedMessage(attr.Deprecated),
 		Sensitive:   attr.Sensitive,
 	}
+	if attr.AttributeNestedType != nil {
+		tfJSONNestedTypeToV2Schema(attr.AttributeNestedType, v2sch)
+		return v2sch
+	}
 	if err := schemaV2TypeFromCtyType(attr.AttributeType, v2sch); err != nil {
 		panic(err)
 	}
 	return v2sch
 }
 
+// tfJSONNestedTypeToV2Schema converts the nested attribute type of a protocol
+// version 6 schema to the plugin SDK representation. Nested attributes are
+// represented the same way as the attributes with object element types in
+// SDKv2, i.e. collections of resources in attribute config mode. A nested
+// attribute with the single nesting mode is represented as a list with at
+// most one item, see GetSingleNestedAttributes.
+func tfJSONNestedTypeToV2Schema(nt *tfjson.SchemaNestedAttributeType, v2sch *schemav2.Schema) {
+	v2sch.ConfigMode = schemav2.SchemaConfigModeAttr
+	v2sch.MinItems = int(nt.MinItems)
+	v2sch.MaxItems = int(nt.MaxItems)
+	switch nt.NestingMode {
+	case tfjson.SchemaNestingModeSingle:
+		v2sch.Type = schemav2.TypeList
+		v2sch.MinItems = 0
+		v2sch.MaxItems = 1
+		if v2sch.Required {
+			v2sch.MinItems = 1
+		}
+	case tfjson.SchemaNestingModeList:
+		v2sch.Type = schemav2.TypeList
+	case tfjson.SchemaNestingModeSet:
+		v2sch.Type = schemav2.TypeSet
+	case tfjson.SchemaNestingModeMap:
+		v2sch.Type = schemav2.TypeMap
+	case tfjson.SchemaNestingModeGroup:
+		panic("unexpected nesting mode: " + nt.NestingMode)
+	default:
+		panic("unknown nesting mode: " + nt.NestingMode)
+	}
+	res := &schemav2.Resource{}
+	res.Schema = make(map[string]*schemav2.Schema, len(nt.Attributes))
+	for key, attr := range nt.Attributes {
+		res.Schema[key] = tfJSONAttributeToV2Schema(attr)
+	}
+	v2sch.Elem = res
+}
+
 func tfJSONBlockTypeToV2Schema(nb *tfjson.SchemaBlockType) *schemav2.Schema { //nolint:gocyclo
 	v2sch := &schemav2.Schema{
 		MinItems: int(nb.MinItems),
@@ -114,7 +157,14 @@ func tfJSONBlockTypeToV2Schema(nb *tfjson.SchemaBlockType) *schemav2.Schema { //
 		v2sch.Type = schemav2.TypeList
 	case tfjson.SchemaNestingModeMap:
 		v2sch.Type = schemav2.TypeMap
-	case tfjson.SchemaNestingModeSingle, tfjson.SchemaNestingModeGroup:
+	// Blocks with the single nesting mode are used by protocol version 6
+	// providers and they are represented as lists with at most one item,
+	// see GetSingleNestedAttributes.
+	case tfjson.SchemaNestingModeSingle:
+		v2sch.Type = schemav2.TypeList
+		v2sch.MaxItems = 1
+		v2sch.Computed = false
+	case tfjson.SchemaNestingModeGroup:
 		panic("unexpected nesting mode: " + nb.NestingMode)
 	default:
 		panic("unknown nesting mode: " + nb.NestingMode)
@@ -138,7 +188,7 @@ func tfJSONBlockTypeToV2Schema(nb *tfjson.SchemaBlockType) *schemav2.Schema { //
 		// schema map but set as a separate field. So, we just need to ignore
 		// here.
 		// https://github.com/hashicorp/terraform-plugin-sdk/blob/6461ac6e9044a44157c4e2c8aec0f1ab7efc2055/helper/schema/core_schema.go#L315
-		if block.NestingMode == tfjson.SchemaNestingModeSingle {
+		if isTimeoutsBlock(key, block) {
 			continue
 		}
 		res.Schema[key] = tfJSONBlockTypeToV2Schema(block)
@@ -147,6 +197,70 @@ func tfJSONBlockTypeToV2Schema(nb *tfjson.SchemaBlockType) *schemav2.Schema { //
 	return v2sch
 }
 
+// isTimeoutsBlock returns whether the given block is the resource timeouts
+// block that the plugin SDK adds to the schema.
+func isTimeoutsBlock(name string, nb *tfjson.SchemaBlockType) bool {
+	return name == "timeouts" && nb.NestingMode == tfjson.SchemaNestingModeSingle
+}
+
+// GetSingleNestedAttributes returns the paths of the attributes and blocks of
+// the given resource schema with the single nesting mode. Their values are
+// objects in Terraform but they are represented as lists with at most one
+// item in the plugin SDK representation. The paths are dot-separated
+// attribute names where "*" matches any key of a map, sorted so that a parent is listed before its children.
+func GetSingleNestedAttributes(s *tfjson.Schema) []string {
+	if s == nil || s.Block == nil {
+		return nil
+	}
+	paths := singleNestedInBlock(s.Block, "")
+	// A path sorts before the paths that it's a prefix of.
+	sort.Strings(paths)
+	return paths
+}
+
+func singleNestedInBlock(b *tfjson.SchemaBlock, prefix string) []string {
+	var paths []string
+	for k, attr := range b.Attributes {
+		paths = append(paths, singleNestedInAttribute(attr, prefix+k)...)
+	}
+	for k, nb := range b.NestedBlocks {
+		if isTimeoutsBlock(k, nb) {
+			continue
+		}
+		if nb.NestingMode == tfjson.SchemaNestingModeSingle {
+			paths = append(paths, prefix+k)
+		}
+		if nb.Block != nil {
+			paths = append(paths, singleNestedInBlock(nb.Block, childPrefix(prefix+k, nb.NestingMode))...)
+		}
+	}
+	return paths
+}
+
+func singleNestedInAttribute(attr *tfjson.SchemaAttribute, path string) []string {
+	nt := attr.AttributeNestedType
+	if nt == nil {
+		return nil
+	}
+	var paths []string
+	if nt.NestingMode == tfjson.SchemaNestingModeSingle {
+		paths = append(paths, path)
+	}
+	for k, a := range nt.Attributes {
+		paths = append(paths, singleNestedInAttribute(a, childPrefix(path, nt.NestingMode)+k)...)
+	}
+	return paths
+}
+
+// childPrefix returns the prefix of the paths of the children of the given
+// path. The values of the maps are matched with a wildcard.
+func childPrefix(path string, nm tfjson.SchemaNestingMode) string {
+	if nm == tfjson.SchemaNestingModeMap {
+		return path + ".*."
+	}
+	return path + "."
+}
+
 func schemaV2TypeFromCtyType(typ cty.Type, schema *schemav2.Schema) error { //nolint:gocyclo
 	configMode := schemav2.SchemaConfigModeAuto
 
@@ -0,0 +1,198 @@
+/*
+ Copyright 2022 The Crossplane Authors.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package tfjson
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	tfjson "github.com/hashicorp/terraform-json"
+	schemav2 "github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestTFJSONAttributeToV2Schema(t *testing.T) {
+	nested := map[string]*tfjson.SchemaAttribute{
+		"name": {AttributeType: cty.String, Required: true},
+		"id":   {AttributeType: cty.String, Computed: true},
+	}
+	nestedResource := &schemav2.Resource{Schema: map[string]*schemav2.Schema{
+		"name": {Type: schemav2.TypeString, Required: true},
+		"id":   {Type: schemav2.TypeString, Computed: true},
+	}}
+	type args struct {
+		attr *tfjson.SchemaAttribute
+	}
+	type want struct {
+		schema *schemav2.Schema
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Primitive": {
+			reason: "Attributes with primitive types should be converted to their SDK types.",
+			args: args{
+				attr: &tfjson.SchemaAttribute{AttributeType: cty.String, Optional: true},
+			},
+			want: want{
+				schema: &schemav2.Schema{Type: schemav2.TypeString, Optional: true},
+			},
+		},
+		"NestedSingle": {
+			reason: "Nested attributes with single nesting mode should be converted to lists with at most one item.",
+			args: args{
+				attr: &tfjson.SchemaAttribute{
+					Required: true,
+					AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+						NestingMode: tfjson.SchemaNestingModeSingle,
+						Attributes:  nested,
+					},
+				},
+			},
+			want: want{
+				schema: &schemav2.Schema{
+					Type:       schemav2.TypeList,
+					Required:   true,
+					MinItems:   1,
+					MaxItems:   1,
+					ConfigMode: schemav2.SchemaConfigModeAttr,
+					Elem:       nestedResource,
+				},
+			},
+		},
+		"NestedSet": {
+			reason: "Nested attributes with set nesting mode should be converted to sets of resources.",
+			args: args{
+				attr: &tfjson.SchemaAttribute{
+					Optional: true,
+					AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+						NestingMode: tfjson.SchemaNestingModeSet,
+						MaxItems:    3,
+						Attributes:  nested,
+					},
+				},
+			},
+			want: want{
+				schema: &schemav2.Schema{
+					Type:       schemav2.TypeSet,
+					Optional:   true,
+					MaxItems:   3,
+					ConfigMode: schemav2.SchemaConfigModeAttr,
+					Elem:       nestedResource,
+				},
+			},
+		},
+		"NestedMap": {
+			reason: "Nested attributes with map nesting mode should be converted to maps of resources.",
+			args: args{
+				attr: &tfjson.SchemaAttribute{
+					Computed: true,
+					AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+						NestingMode: tfjson.SchemaNestingModeMap,
+						Attributes:  nested,
+					},
+				},
+			},
+			want: want{
+				schema: &schemav2.Schema{
+					Type:       schemav2.TypeMap,
+					Computed:   true,
+					ConfigMode: schemav2.SchemaConfigModeAttr,
+					Elem:       nestedResource,
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tfJSONAttributeToV2Schema(tc.args.attr)
+			if diff := cmp.Diff(tc.want.schema, got, cmpopts.IgnoreUnexported(schemav2.Resource{})); diff != "" {
+				t.Errorf("\n%s\ntfJSONAttributeToV2Schema(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetSingleNestedAttributes(t *testing.T) {
+	single := func(attrs map[string]*tfjson.SchemaAttribute) *tfjson.SchemaAttribute {
+		return &tfjson.SchemaAttribute{AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+			NestingMode: tfjson.SchemaNestingModeSingle,
+			Attributes:  attrs,
+		}}
+	}
+	type args struct {
+		schema *tfjson.Schema
+	}
+	type want struct {
+		paths []string
+	}
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NoBlock": {
+			reason: "There should be no paths if the schema has no block.",
+			args: args{
+				schema: &tfjson.Schema{},
+			},
+		},
+		"AttributesAndBlocks": {
+			reason: "Paths of all single nested attributes and blocks should be returned except the timeouts block.",
+			args: args{
+				schema: &tfjson.Schema{Block: &tfjson.SchemaBlock{
+					Attributes: map[string]*tfjson.SchemaAttribute{
+						"name": {AttributeType: cty.String},
+						"settings": single(map[string]*tfjson.SchemaAttribute{
+							"network": single(map[string]*tfjson.SchemaAttribute{
+								"cidr": {AttributeType: cty.String},
+							}),
+						}),
+						"rules": {AttributeNestedType: &tfjson.SchemaNestedAttributeType{
+							NestingMode: tfjson.SchemaNestingModeMap,
+							Attributes: map[string]*tfjson.SchemaAttribute{
+								"target": single(nil),
+							},
+						}},
+					},
+					NestedBlocks: map[string]*tfjson.SchemaBlockType{
+						"timeouts": {NestingMode: tfjson.SchemaNestingModeSingle, Block: &tfjson.SchemaBlock{}},
+						"logging": {NestingMode: tfjson.SchemaNestingModeSingle, Block: &tfjson.SchemaBlock{
+							Attributes: map[string]*tfjson.SchemaAttribute{
+								"destination": single(nil),
+							},
+						}},
+					},
+				}},
+			},
+			want: want{
+				paths: []string{"logging", "logging.destination", "rules.*.target", "settings", "settings.network"},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := GetSingleNestedAttributes(tc.args.schema)
+			if diff := cmp.Diff(tc.want.paths, got); diff != "" {
+				t.Errorf("\n%s\nGetSingleNestedAttributes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
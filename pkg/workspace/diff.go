@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+const (
+	// maxSummaryAttributes is the maximum number of attributes listed in
+	// the summary of a diff.
+	maxSummaryAttributes = 5
+
+	valSensitive = "(sensitive value)"
+	valUnknown   = "(known after apply)"
+)
+
+// AttributeDiff is the difference of a single attribute between the current
+// state and the desired configuration of a resource.
+type AttributeDiff struct {
+	// Path is the Terraform path of the attribute, i.e. attribute names and
+	// list indexes joined with dots, e.g. "tags.env" or "rule.0.port".
+	Path string
+	// Before is the current value of the attribute. It's nil if the value
+	// is sensitive.
+	Before interface{}
+	// After is the desired value of the attribute. It's nil if the value is
+	// sensitive or not known until the apply is completed.
+	After interface{}
+	// Sensitive is true if either of the values is sensitive.
+	Sensitive bool
+	// Unknown is true if the desired value will be known after apply.
+	Unknown bool
+}
+
+// String returns a human-readable representation of the AttributeDiff.
+func (ad AttributeDiff) String() string {
+	before, after := FormatValue(ad.Before), FormatValue(ad.After)
+	if ad.Sensitive {
+		before, after = valSensitive, valSensitive
+	}
+	if ad.Unknown {
+		after = valUnknown
+	}
+	return fmt.Sprintf("%s: %s => %s", ad.Path, before, after)
+}
+
+// PlanDiff is the list of attributes whose desired values differ from their
+// current values.
+type PlanDiff []AttributeDiff
+
+// String returns a summary of the diff listing a limited number of the
+// attributes.
+func (pd PlanDiff) String() string {
+	if len(pd) == 0 {
+		return ""
+	}
+	l := make([]string, 0, maxSummaryAttributes)
+	for i, ad := range pd {
+		if i == maxSummaryAttributes {
+			break
+		}
+		l = append(l, ad.String())
+	}
+	s := strings.Join(l, "; ")
+	if len(pd) > maxSummaryAttributes {
+		s = fmt.Sprintf("%s; and %d more", s, len(pd)-maxSummaryAttributes)
+	}
+	return s
+}
+
+// FormatValue returns the JSON representation of the given attribute value,
+// which is used to show and compare the values in a diff.
+func FormatValue(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	b, err := json.JSParser.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
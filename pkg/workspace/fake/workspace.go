@@ -0,0 +1,292 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake contains an in-memory reference implementation of the
+// workspace.Workspace interface to be used in tests.
+package fake
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	xpresource "github.com/crossplane/crossplane-runtime/pkg/resource"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/terrajet/pkg/config"
+	"github.com/crossplane/terrajet/pkg/resource"
+	"github.com/crossplane/terrajet/pkg/resource/json"
+	"github.com/crossplane/terrajet/pkg/terraform"
+	"github.com/crossplane/terrajet/pkg/workspace"
+)
+
+// Workspace is an in-memory Workspace of a single resource whose external
+// resource is a map of attributes. Apply copies the desired parameters to the
+// external resource and Destroy removes it. The async operations run in a
+// goroutine that waits for Continue to be called if Block is set.
+type Workspace struct {
+	// Type is the Terraform resource type reported in the state.
+	Type string
+	// Parameters are the desired parameters of the resource.
+	Parameters map[string]interface{}
+	// Attributes are the attributes of the external resource, nil if it
+	// doesn't exist.
+	Attributes map[string]interface{}
+	// Err is returned by all operations if set.
+	Err error
+	// Calls are the names of the called methods in order.
+	Calls []string
+
+	mu      sync.Mutex
+	running string
+	block   chan struct{}
+}
+
+// NewWorkspace returns a new Workspace of a resource of the given type with
+// the given desired parameters whose external resource doesn't exist.
+func NewWorkspace(typ string, params map[string]interface{}) *Workspace {
+	return &Workspace{Type: typ, Parameters: params}
+}
+
+// Block makes the async operations wait until Continue is called.
+func (w *Workspace) Block() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.block = make(chan struct{})
+}
+
+// Continue lets the blocked async operations complete.
+func (w *Workspace) Continue() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.block != nil {
+		close(w.block)
+		w.block = nil
+	}
+}
+
+// ApplyAsync applies in a goroutine and calls the given function once it's
+// completed.
+func (w *Workspace) ApplyAsync(callback workspace.CallbackFn) error {
+	return w.async("ApplyAsync", "apply", w.apply, callback)
+}
+
+// Apply copies the desired parameters to the external resource.
+func (w *Workspace) Apply(_ context.Context) (workspace.ApplyResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Calls = append(w.Calls, "Apply")
+	if err := w.check(); err != nil {
+		return workspace.ApplyResult{}, err
+	}
+	w.apply()
+	return workspace.ApplyResult{State: w.state()}, nil
+}
+
+// DestroyAsync destroys in a goroutine and calls the given function once it's
+// completed.
+func (w *Workspace) DestroyAsync(callback workspace.CallbackFn) error {
+	return w.async("DestroyAsync", "destroy", w.destroy, callback)
+}
+
+// Destroy removes the external resource.
+func (w *Workspace) Destroy(_ context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Calls = append(w.Calls, "Destroy")
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.destroy()
+	return nil
+}
+
+// Refresh reports whether the external resource exists and the ongoing
+// async operation.
+func (w *Workspace) Refresh(_ context.Context) (workspace.RefreshResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Calls = append(w.Calls, "Refresh")
+	return w.refresh()
+}
+
+// refresh must be called with the lock held.
+func (w *Workspace) refresh() (workspace.RefreshResult, error) {
+	if w.running != "" {
+		return workspace.RefreshResult{
+			IsApplying:   w.running == "apply",
+			IsDestroying: w.running == "destroy",
+		}, nil
+	}
+	if w.Err != nil {
+		return workspace.RefreshResult{}, w.Err
+	}
+	if w.Attributes == nil {
+		return workspace.RefreshResult{}, nil
+	}
+	return workspace.RefreshResult{Exists: true, State: w.state()}, nil
+}
+
+// Plan compares the desired parameters with the attributes of the external
+// resource.
+func (w *Workspace) Plan(_ context.Context) (workspace.PlanResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Calls = append(w.Calls, "Plan")
+	if err := w.check(); err != nil {
+		return workspace.PlanResult{}, err
+	}
+	return w.plan(), nil
+}
+
+// Observe is Refresh and Plan in a single call.
+func (w *Workspace) Observe(_ context.Context) (workspace.ObserveResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Calls = append(w.Calls, "Observe")
+	r, err := w.refresh()
+	if err != nil || !r.Exists {
+		return workspace.ObserveResult{RefreshResult: r}, err
+	}
+	p := w.plan()
+	return workspace.ObserveResult{RefreshResult: r, UpToDate: p.UpToDate, Diff: p.Diff}, nil
+}
+
+// plan must be called with the lock held.
+func (w *Workspace) plan() workspace.PlanResult {
+	if w.Attributes == nil {
+		return workspace.PlanResult{}
+	}
+	var diff workspace.PlanDiff
+	for k, v := range w.Parameters {
+		if workspace.FormatValue(v) != workspace.FormatValue(w.Attributes[k]) {
+			diff = append(diff, workspace.AttributeDiff{Path: k, Before: w.Attributes[k], After: v})
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Path < diff[j].Path
+	})
+	return workspace.PlanResult{Exists: true, UpToDate: len(diff) == 0, Diff: diff}
+}
+
+func (w *Workspace) async(call, op string, fn func(), callback workspace.CallbackFn) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Calls = append(w.Calls, call)
+	if err := w.check(); err != nil {
+		return err
+	}
+	w.running = op
+	block := w.block
+	go func() {
+		if block != nil {
+			<-block
+		}
+		w.mu.Lock()
+		fn()
+		w.running = ""
+		w.mu.Unlock()
+		_ = callback(nil, context.TODO())
+	}()
+	return nil
+}
+
+// check returns an error if an async operation is running or Err is set. It
+// must be called with the lock held.
+func (w *Workspace) check() error {
+	if w.running != "" {
+		return errors.Errorf("%s operation is still running", w.running)
+	}
+	return w.Err
+}
+
+func (w *Workspace) apply() {
+	if w.Attributes == nil {
+		w.Attributes = map[string]interface{}{}
+	}
+	for k, v := range w.Parameters {
+		w.Attributes[k] = v
+	}
+}
+
+func (w *Workspace) destroy() {
+	w.Attributes = nil
+}
+
+// state returns the attributes of the external resource as a Terraform
+// state. It must be called with the lock held.
+func (w *Workspace) state() *json.StateV4 {
+	s := json.NewStateV4()
+	if w.Attributes == nil {
+		return s
+	}
+	attr, err := json.JSParser.Marshal(w.Attributes)
+	if err != nil {
+		return s
+	}
+	s.Resources = []json.ResourceStateV4{
+		{
+			Mode:      "managed",
+			Type:      w.Type,
+			Instances: []json.InstanceObjectStateV4{{AttributesRaw: attr}},
+		},
+	}
+	return s
+}
+
+// Store is an in-memory store of Workspaces keyed by the UIDs of their
+// resources. The desired parameters of a workspace are updated from its
+// resource every time it's returned.
+type Store struct {
+	// Workspaces are the workspaces of the resources keyed by their UIDs.
+	Workspaces map[types.UID]*Workspace
+
+	mu sync.Mutex
+}
+
+// NewStore returns a new empty Store.
+func NewStore() *Store {
+	return &Store{Workspaces: map[types.UID]*Workspace{}}
+}
+
+// Workspace returns the workspace of the given resource, creating it if
+// needed.
+func (s *Store) Workspace(_ context.Context, _ resource.SecretClient, tr resource.Terraformed, _ terraform.Setup, _ *config.Resource) (workspace.Workspace, error) {
+	params, err := tr.GetParameters()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get parameters")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.Workspaces[tr.GetUID()]
+	if !ok {
+		w = NewWorkspace(tr.GetTerraformResourceType(), params)
+		s.Workspaces[tr.GetUID()] = w
+	}
+	w.mu.Lock()
+	w.Parameters = params
+	w.mu.Unlock()
+	return w, nil
+}
+
+// Remove removes the workspace of the given resource.
+func (s *Store) Remove(obj xpresource.Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Workspaces, obj.GetUID())
+	return nil
+}
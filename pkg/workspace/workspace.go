@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workspace contains the interface of the workspaces that run the
+// operations of the managed resources and the types of their results. It's
+// shared by the controller and the execution engines implementing it, such as
+// the Terraform CLI workspaces.
+package workspace
+
+import (
+	"context"
+
+	"github.com/crossplane/terrajet/pkg/resource/json"
+)
+
+// Workspace is the set of methods that are needed for the controller to work.
+type Workspace interface {
+	ApplyAsync(CallbackFn) error
+	Apply(context.Context) (ApplyResult, error)
+	DestroyAsync(CallbackFn) error
+	Destroy(context.Context) error
+	Refresh(context.Context) (RefreshResult, error)
+	Plan(context.Context) (PlanResult, error)
+	Observe(context.Context) (ObserveResult, error)
+}
+
+// CallbackFn is the type of accepted function that can be called after an async
+// operation is completed.
+type CallbackFn func(error, context.Context) error
+
+// ApplyResult contains the state after the apply operation.
+type ApplyResult struct {
+	State *json.StateV4
+}
+
+// RefreshResult contains information about the current state of the resource.
+type RefreshResult struct {
+	Exists       bool
+	IsApplying   bool
+	IsDestroying bool
+	// IsWaiting is true if the ongoing operation is waiting for its turn to
+	// start.
+	IsWaiting bool
+	State     *json.StateV4
+	// InterruptedOperation is the error about the last async operation if it
+	// was interrupted by a restart of the provider before it could complete.
+	// It's reported only once.
+	InterruptedOperation error
+}
+
+// PlanResult returns a summary of comparison between desired and current state
+// of the resource.
+type PlanResult struct {
+	Exists   bool
+	UpToDate bool
+	// RequiresReplace is true if the resource has to be destroyed and
+	// created again to be up-to-date.
+	RequiresReplace bool
+	// Diff is the list of attributes that will be changed by the next apply.
+	// It's populated only if the resource exists and is not up-to-date.
+	Diff PlanDiff
+}
+
+// ObserveResult contains the refreshed state of the resource and the
+// comparison of its desired and current states, which are produced by a
+// single operation. The comparison is populated only if the resource exists
+// and there is no ongoing operation.
+type ObserveResult struct {
+	RefreshResult
+	UpToDate bool
+	// RequiresReplace is true if the resource has to be destroyed and
+	// created again to be up-to-date.
+	RequiresReplace bool
+	// Diff is the list of attributes that will be changed by the next apply.
+	Diff PlanDiff
+}